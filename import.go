@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	property2 "github.com/chn555/property-service/pkg/property"
+	"github.com/chn555/property-service/pkg/statement"
+)
+
+// errImportIncomplete is returned when some lines of a statement could not be imported.
+var errImportIncomplete = errors.New("some lines were not imported")
+
+// runImport imports the bank statement named by args into the ledger of a property, and writes
+// the result of every line to out:
+//
+//	property-service import -property <property ID> [-format csv|ofx|qif] [flags] <statement>
+func runImport(ctx context.Context, handler *property2.Handler, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	propertyID := fs.String("property", "", "ID of the property to import the statement into")
+	format := fs.String("format", "", "csv, ofx or qif, guessed from the file extension by default")
+	dateFormat := fs.String("date-format", "", "Go layout of the dates of CSV and QIF statements")
+	timezone := fs.String("timezone", "", "time zone of dates without one, the property time zone by default")
+	currency := fs.String("currency", "", "currency of lines without one, the property currency by default")
+	delimiter := fs.String("delimiter", ",", "column delimiter of CSV statements")
+	mapping := statement.CSVMapping{}
+	fs.StringVar(&mapping.Date, "date-column", "", "CSV column of the dates")
+	fs.StringVar(&mapping.Amount, "amount-column", "", "CSV column of the signed amounts")
+	fs.StringVar(&mapping.Debit, "debit-column", "", "CSV column of the debits, in place of -amount-column")
+	fs.StringVar(&mapping.Credit, "credit-column", "", "CSV column of the credits, in place of -amount-column")
+	fs.StringVar(&mapping.Description, "description-column", "", "CSV column of the descriptions")
+	fs.StringVar(&mapping.Reference, "reference-column", "", "CSV column of the bank references")
+	fs.StringVar(&mapping.Category, "category-column", "", "CSV column of the categories")
+	fs.StringVar(&mapping.Currency, "currency-column", "", "CSV column of the currencies")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *propertyID == "" || fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("a property ID and a statement are required")
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	if utf8.RuneCountInString(*delimiter) != 1 {
+		return fmt.Errorf("invalid delimiter %q", *delimiter)
+	}
+	mapping.Delimiter, _ = utf8.DecodeRuneInString(*delimiter)
+
+	calendar, err := handler.GetCalendar(ctx, *propertyID, *timezone)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	lines, err := statement.Parse(f, statement.Options{
+		Format:     statement.Format(*format),
+		CSV:        mapping,
+		DateFormat: *dateFormat,
+		Location:   calendar.Location,
+		Currency:   *currency,
+	})
+	if err != nil {
+		return fmt.Errorf("parse statement: %w", err)
+	}
+
+	report, err := handler.ImportEvents(ctx, *propertyID, lines)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ROW\tSTATUS\tEVENT\tREASON")
+	for _, line := range report.Lines {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", line.Row, line.Status, line.EventID, line.Reason)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d imported, %d skipped, %d errored\n", report.Imported, report.Skipped, report.Errored)
+
+	if report.Errored > 0 {
+		return errImportIncomplete
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chn555/property-service/internal/config"
+	property2 "github.com/chn555/property-service/pkg/property"
+)
+
+// TestRunImport_Args runs the command line of an import through the config loader and the flags
+// of the import, up to opening the statement.
+func TestRunImport_Args(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("{}\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() unexpected error = %v", err)
+	}
+	args := []string{"-c", path, "import", "-property", "elm", "-currency", "USD", "-timezone", "UTC", "missing.qif"}
+
+	_, command, err := config.NewLoader[struct{}]("TEST_", path).LoadConfig(args)
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error = %v", err)
+	}
+	if len(command) == 0 || command[0] != "import" {
+		t.Fatalf("LoadConfig() command got = %v, want import", command)
+	}
+	err = runImport(context.TODO(), property2.NewHandler(nil, nil, nil, "USD"), command[1:], io.Discard)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("runImport() error = %v, want the statement to be missing", err)
+	}
+}
@@ -3,7 +3,11 @@ package config
 import (
 	"context"
 	"fmt"
+	"github.com/chn555/property-service/pkg/blob"
 	"github.com/chn555/property-service/pkg/db/mongo"
+	"github.com/chn555/property-service/pkg/fx"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/chn555/property-service/pkg/webhook"
 	"log"
 
 	"github.com/go-playground/validator"
@@ -14,24 +18,39 @@ var (
 )
 
 type MainConfig struct {
-	MongoConfig           mongo.Config
-	MongoEventStateConfig mongo.EventStateConfig
+	MongoConfig                    mongo.Config
+	MongoEventStateConfig          mongo.EventStateConfig
+	MongoScheduleStateConfig       mongo.ScheduleStateConfig
+	MongoPropertyStateConfig       mongo.PropertyStateConfig
+	MongoPortfolioStateConfig      mongo.PortfolioStateConfig
+	MongoBudgetStateConfig         mongo.BudgetStateConfig
+	MongoAlertStateConfig          mongo.AlertStateConfig
+	MongoReconciliationStateConfig mongo.ReconciliationStateConfig
+	MongoAttachmentStateConfig     mongo.AttachmentStateConfig
+	FXConfig                       fx.Config
+	SchedulerConfig                property.SchedulerConfig
+	AlertsConfig                   property.AlertsConfig
+	WebhookConfig                  webhook.Config
+	AttachmentsConfig              property.AttachmentsConfig
+	BlobConfig                     blob.Config
 }
 
-func LoadConfig(ctx context.Context) (*MainConfig, error) {
+// LoadConfig loads the configuration as Loader.LoadConfig does from the command line arguments
+// args, and returns it along with the subcommand they name and its arguments, if any.
+func LoadConfig(ctx context.Context, args []string) (*MainConfig, []string, error) {
 	log.Println("beginning loading configurations")
-	c, err := NewDefaultLoader[MainConfig]().LoadConfig()
+	c, command, err := NewDefaultLoader[MainConfig]().LoadConfig(args)
 	if err != nil {
-		return nil, fmt.Errorf("error: failed to load configurations: %w", err)
+		return nil, nil, fmt.Errorf("error: failed to load configurations: %w", err)
 	}
 
 	err = validateConfig(c)
 	if err != nil {
-		return nil, fmt.Errorf("error: failed to validate configurations: %w", err)
+		return nil, nil, fmt.Errorf("error: failed to validate configurations: %w", err)
 	}
 
 	log.Println("successfully loaded configurations")
-	return c, nil
+	return c, command, nil
 }
 
 func validateConfig(config any) error {
@@ -6,7 +6,6 @@ import (
 	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/v2"
 	flag "github.com/spf13/pflag"
-	"os"
 	"strings"
 )
 
@@ -28,39 +27,49 @@ func NewDefaultLoader[C any]() *Loader[C] {
 	return &Loader[C]{envVarsPrefix: "PROP_", defaultConfigFilePath: "config.yaml"}
 }
 
-func (l Loader[C]) LoadConfig() (*C, error) {
-	confPath, err := l.loadConfPathFromFlag()
+// LoadConfig loads the configuration from the config file named by the command line arguments
+// args, and from the environment. The flags of args are read up to the first argument that is not
+// a flag, which names a subcommand: it is returned along with the arguments following it, which
+// are the flags and arguments of the subcommand.
+func (l Loader[C]) LoadConfig(args []string) (*C, []string, error) {
+	confPath, command, err := l.loadConfPathFromFlag(args)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := k.Load(file.Provider(confPath), ymlParser); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := k.Load(env.Provider(l.envVarsPrefix, ".", func(s string) string {
 		return strings.Replace(strings.ToLower(
 			strings.TrimPrefix(s, l.envVarsPrefix)), "_", ".", -1)
 	}), nil); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	conf := new(C)
 	err = k.Unmarshal("", conf)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return conf, nil
+	return conf, command, nil
 }
 
-func (l Loader[C]) loadConfPathFromFlag() (string, error) {
+func (l Loader[C]) loadConfPathFromFlag(args []string) (string, []string, error) {
 	f := flag.NewFlagSet("config", flag.ContinueOnError)
 	f.StringP("config", "c", l.defaultConfigFilePath, "the file path for the config file")
-	err := f.Parse(os.Args[1:])
+	// the flags following a subcommand are its own
+	f.SetInterspersed(false)
+	err := f.Parse(args)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return f.GetString("config")
+	confPath, err := f.GetString("config")
+	if err != nil {
+		return "", nil, err
+	}
+	return confPath, f.Args(), nil
 }
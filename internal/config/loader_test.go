@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type testConfig struct {
+	Name string
+}
+
+func TestLoader_LoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.yaml")
+	if err := os.WriteFile(path, []byte("name: elm\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() unexpected error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantCommand []string
+		wantErr     bool
+	}{
+		{
+			name: "server",
+		},
+		{
+			name: "config flag",
+			args: []string{"-c", path},
+		},
+		{
+			name:        "import",
+			args:        []string{"import", "-property", "elm", "-currency", "USD", "statement.qif"},
+			wantCommand: []string{"import", "-property", "elm", "-currency", "USD", "statement.qif"},
+		},
+		{
+			name:        "import with a config flag",
+			args:        []string{"--config", path, "import", "-c", "other.yaml", "statement.qif"},
+			wantCommand: []string{"import", "-c", "other.yaml", "statement.qif"},
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"-property", "elm"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, command, err := NewLoader[testConfig]("TEST_", path).LoadConfig(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if conf.Name != "elm" {
+				t.Errorf("LoadConfig() got = %+v, want the config file", conf)
+			}
+			if !slices.Equal(command, tt.wantCommand) {
+				t.Errorf("LoadConfig() command got = %v, want %v", command, tt.wantCommand)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package property
+
+import (
+	"context"
+	"github.com/chn555/property-service/internal/rest"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type AlertRuleReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	Kind       string `json:"kind" validate:"required,oneof=negative_balance balance_below expense_above"`
+	// Threshold is a decimal string: the balance floor of a balance_below rule, or the expense
+	// limit of an expense_above rule, such as "500".
+	Threshold property.Money `json:"threshold"`
+	// Currency is an ISO-4217 code, and defaults to the currency of the property.
+	Currency string `json:"currency" validate:"omitempty,len=3,alpha"`
+}
+
+type GetAlertRulesRes struct {
+	Rules []*property.AlertRule `json:"rules"`
+}
+
+type AlertRuleIDReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	RuleID     string `param:"ruleID" validate:"required"`
+}
+
+type WebhookReq struct {
+	URL string `json:"url" validate:"required,url,max=2048"`
+	// Secret is the key payloads are signed with, sent back in the X-Signature-256 header.
+	Secret string `json:"secret" validate:"required,min=16,max=255"`
+}
+
+type GetWebhooksRes struct {
+	Webhooks []*property.Webhook `json:"webhooks"`
+}
+
+type GetDeliveriesReq struct {
+	WebhookID  string `query:"webhook_id"`
+	PropertyID string `query:"property_id"`
+	Status     string `query:"status" validate:"omitempty,oneof=pending delivered failed"`
+	Offset     int    `query:"offset" validate:"omitempty,gt=0"`
+	Limit      int    `query:"limit" validate:"omitempty,gt=0"`
+	NextToken  string `query:"next_token"`
+}
+
+type GetDeliveriesRes struct {
+	Deliveries []*property.Delivery `json:"deliveries"`
+	NextToken  string               `json:"next_token"`
+}
+
+func (h *RestHandler) CreateAlertRule(c echo.Context) error {
+	req := &AlertRuleReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	rule := &property.AlertRule{
+		PropertyID: req.PropertyID,
+		Kind:       property.AlertKind(req.Kind),
+		Threshold:  req.Threshold,
+		Currency:   req.Currency,
+	}
+	if err := h.Alerts.CreateAlertRule(context.Background(), rule); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, rule)
+}
+
+func (h *RestHandler) GetAlertRules(c echo.Context) error {
+	propertyID := c.Param("propertyID")
+	if propertyID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "property ID is required")
+	}
+
+	rules, err := h.Alerts.GetAlertRules(context.Background(), propertyID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, &GetAlertRulesRes{Rules: rules})
+}
+
+func (h *RestHandler) GetAlertRule(c echo.Context) error {
+	req := &AlertRuleIDReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	rule, err := h.Alerts.GetAlertRule(context.Background(), req.PropertyID, req.RuleID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, rule)
+}
+
+func (h *RestHandler) DeleteAlertRule(c echo.Context) error {
+	req := &AlertRuleIDReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.Alerts.DeleteAlertRule(context.Background(), req.PropertyID, req.RuleID); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *RestHandler) CreateWebhook(c echo.Context) error {
+	req := &WebhookReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	webhook := &property.Webhook{
+		URL:    req.URL,
+		Secret: req.Secret,
+	}
+	if err := h.Alerts.CreateWebhook(context.Background(), webhook); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, webhook)
+}
+
+func (h *RestHandler) GetWebhooks(c echo.Context) error {
+	webhooks, err := h.Alerts.GetWebhooks(context.Background())
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, &GetWebhooksRes{Webhooks: webhooks})
+}
+
+func (h *RestHandler) DeleteWebhook(c echo.Context) error {
+	webhookID := c.Param("webhookID")
+	if webhookID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "webhook ID is required")
+	}
+
+	if err := h.Alerts.DeleteWebhook(context.Background(), webhookID); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *RestHandler) GetDeliveries(c echo.Context) error {
+	req := &GetDeliveriesReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.NextToken != "" {
+		token, err := rest.DecodeNextToken(req.NextToken)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		req.Limit = token.Limit
+		req.Offset = token.Offset
+	}
+
+	filter := &property.DeliveryFilter{
+		WebhookID:  req.WebhookID,
+		PropertyID: req.PropertyID,
+		Status:     property.DeliveryStatus(req.Status),
+	}
+	deliveries, err := h.Alerts.GetDeliveries(context.Background(), filter, req.Offset, req.Limit)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	res := &GetDeliveriesRes{Deliveries: deliveries}
+	if req.Limit > 0 && len(deliveries) >= req.Limit {
+		nextToken, err := rest.CreateNextToken(req.Limit, req.Offset+req.Limit)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		res.NextToken = nextToken
+	}
+
+	return c.JSON(200, res)
+}
@@ -0,0 +1,106 @@
+package property
+
+import (
+	"context"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"net/http"
+	"time"
+)
+
+// AmendEventReq changes only the fields it sets.
+type AmendEventReq struct {
+	PropertyID  string          `param:"propertyID" validate:"required"`
+	EventID     string          `param:"eventID" validate:"required"`
+	Amount      *property.Money `json:"amount"`
+	Date        *time.Time      `json:"date"`
+	Description *string         `json:"description" validate:"omitempty,max=500"`
+	AmendedBy   string          `json:"amended_by" validate:"required,max=255"`
+	Reason      string          `json:"reason" validate:"required,max=500"`
+}
+
+type AmendEventRes struct {
+	Event *Event `json:"event"`
+}
+
+func (h *RestHandler) AmendEvent(c echo.Context) error {
+	req := &AmendEventReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.Amount == nil && req.Date == nil && req.Description == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "one of amount, date or description is required")
+	}
+	if req.Amount != nil && req.Amount.IsZero() {
+		return echo.NewHTTPError(http.StatusBadRequest, "amount must not be zero, reverse the event instead")
+	}
+
+	amendment := &property.Amendment{
+		EventAmount: req.Amount,
+		Date:        req.Date,
+		Description: req.Description,
+		AmendedBy:   req.AmendedBy,
+		Reason:      req.Reason,
+	}
+	amended, err := h.PropertyHandler.AmendEvent(context.Background(), req.PropertyID, req.EventID, amendment)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, &AmendEventRes{Event: newEvent(amended)})
+}
+
+type GetEventHistoryReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	EventID    string `param:"eventID" validate:"required"`
+}
+
+// EventRevision is a prior version of an event, and the amendment that replaced it.
+type EventRevision struct {
+	Event     *Event    `json:"event"`
+	AmendedBy string    `json:"amended_by"`
+	Reason    string    `json:"reason"`
+	AmendedAt time.Time `json:"amended_at"`
+}
+
+type GetEventHistoryRes struct {
+	// Event is the current version of the event.
+	Event *Event `json:"event"`
+	// Revisions are the prior versions of the event, oldest first.
+	Revisions []*EventRevision `json:"revisions"`
+}
+
+func (h *RestHandler) GetEventHistory(c echo.Context) error {
+	req := &GetEventHistoryReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	event, revisions, err := h.PropertyHandler.GetEventHistory(context.Background(), req.PropertyID, req.EventID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	res := &GetEventHistoryRes{
+		Event: newEvent(event),
+		Revisions: lo.Map(revisions, func(r *property.EventRevision, _ int) *EventRevision {
+			return &EventRevision{
+				Event:     newEvent(r.Event),
+				AmendedBy: r.AmendedBy,
+				Reason:    r.Reason,
+				AmendedAt: r.AmendedAt,
+			}
+		}),
+	}
+	return c.JSON(200, res)
+}
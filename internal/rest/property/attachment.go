@@ -0,0 +1,172 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// multipartOverhead is how much larger than the largest attachment a multipart upload may be,
+// for its headers and boundaries.
+const multipartOverhead = 1 << 20
+
+type UploadAttachmentReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	EventID    string `param:"eventID" validate:"required"`
+	// Filename names an attachment sent as the request body. The file name of a multipart form
+	// field is used instead.
+	Filename string `query:"filename"`
+}
+
+type EventIDReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	EventID    string `param:"eventID" validate:"required"`
+}
+
+type AttachmentIDReq struct {
+	PropertyID   string `param:"propertyID" validate:"required"`
+	EventID      string `param:"eventID" validate:"required"`
+	AttachmentID string `param:"attachmentID" validate:"required"`
+}
+
+type GetAttachmentsRes struct {
+	Attachments []*property.Attachment `json:"attachments"`
+}
+
+// UploadAttachment attaches a PDF document or an image to an event, sent as the request body or
+// as the file field of a multipart form.
+func (h *RestHandler) UploadAttachment(c echo.Context) error {
+	req := &UploadAttachmentReq{}
+	binder := &echo.DefaultBinder{}
+	if err := binder.BindPathParams(c, req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := binder.BindQueryParams(c, req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, h.Attachments.MaxSize()+multipartOverhead)
+	var body io.Reader = c.Request().Body
+	filename := req.Filename
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge, property.ErrAttachmentTooLarge.Error())
+			}
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		f, err := file.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		defer f.Close()
+		body = f
+		filename = file.Filename
+	}
+
+	attachment := &property.Attachment{
+		PropertyID: req.PropertyID,
+		EventID:    req.EventID,
+		Filename:   filename,
+	}
+	if err := h.Attachments.UploadAttachment(context.Background(), attachment, body); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, attachment)
+}
+
+func (h *RestHandler) GetAttachments(c echo.Context) error {
+	req := &EventIDReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	attachments, err := h.Attachments.GetAttachments(context.Background(), req.PropertyID, req.EventID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+	if attachments == nil {
+		attachments = []*property.Attachment{}
+	}
+
+	return c.JSON(200, &GetAttachmentsRes{Attachments: attachments})
+}
+
+// DownloadAttachment sends the content of an attachment, as the content type it was sniffed as.
+func (h *RestHandler) DownloadAttachment(c echo.Context) error {
+	req, err := bindAttachmentID(c)
+	if err != nil {
+		return err
+	}
+
+	attachment, content, err := h.Attachments.OpenAttachment(context.Background(), req.PropertyID, req.EventID, req.AttachmentID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+	defer content.Close()
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(attachment.Size, 10))
+	header.Set("ETag", strconv.Quote(attachment.SHA256))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	return c.Stream(http.StatusOK, attachment.ContentType, content)
+}
+
+func (h *RestHandler) DeleteAttachment(c echo.Context) error {
+	req, err := bindAttachmentID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Attachments.DeleteAttachment(context.Background(), req.PropertyID, req.EventID, req.AttachmentID); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// eventAttachments sets the attachments of each of events.
+func (h *RestHandler) eventAttachments(ctx context.Context, propertyID string, events []*Event) error {
+	eventIDs := make([]string, len(events))
+	for i, e := range events {
+		eventIDs[i] = e.ID
+	}
+	byEvent, err := h.Attachments.GetEventAttachments(ctx, propertyID, eventIDs)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		e.Attachments = byEvent[e.ID]
+	}
+	return nil
+}
+
+func bindAttachmentID(c echo.Context) (*AttachmentIDReq, error) {
+	req := &AttachmentIDReq{}
+	if err := c.Bind(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return req, nil
+}
@@ -2,18 +2,69 @@ package property
 
 import (
 	"context"
+	"github.com/chn555/property-service/pkg/property"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"time"
 )
 
+type GetBalanceReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	// AsOf is an RFC 3339 timestamp. The balance after every event dated at or before it is
+	// returned, and the current balance if it is not given.
+	AsOf time.Time `query:"as_of"`
+}
+
+type GetBalanceRes struct {
+	// Balance is the total of every currency, converted to BaseCurrency.
+	Balance      property.Money            `json:"balance"`
+	BaseCurrency string                    `json:"base_currency"`
+	ByCurrency   map[string]property.Money `json:"by_currency"`
+	AsOf         time.Time                 `json:"as_of"`
+	// Sources maps each currency of ByCurrency to the event its balance was taken from.
+	Sources map[string]*BalanceSource `json:"sources,omitempty"`
+}
+
+type BalanceSource struct {
+	EventID string    `json:"event_id"`
+	Date    time.Time `json:"date"`
+}
+
 func (h *RestHandler) getBalance(c echo.Context) error {
-	propertyID := c.Param("propertyID")
-	if propertyID == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "property ID is required")
+	req := &GetBalanceReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var balance *property.Balance
+	var err error
+	if req.AsOf.IsZero() {
+		balance, err = h.PropertyHandler.GetBalance(context.Background(), req.PropertyID)
+	} else {
+		balance, err = h.PropertyHandler.GetBalanceAsOf(context.Background(), req.PropertyID, req.AsOf)
 	}
-	balance, err := h.PropertyHandler.GetBalance(context.Background(), propertyID)
 	if err != nil {
-		return err
+		return toHTTPError(err)
+	}
+	return c.JSON(200, newGetBalanceRes(balance))
+}
+
+func newGetBalanceRes(balance *property.Balance) *GetBalanceRes {
+	res := &GetBalanceRes{
+		Balance:      balance.Total,
+		BaseCurrency: balance.BaseCurrency,
+		ByCurrency:   balance.ByCurrency,
+		AsOf:         balance.AsOf,
+	}
+	if len(balance.Sources) > 0 {
+		res.Sources = make(map[string]*BalanceSource, len(balance.Sources))
+		for currency, source := range balance.Sources {
+			res.Sources[currency] = &BalanceSource{EventID: source.EventID, Date: source.Date}
+		}
 	}
-	return c.JSON(200, map[string]interface{}{"balance": balance})
+	return res
 }
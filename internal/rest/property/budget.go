@@ -0,0 +1,212 @@
+package property
+
+import (
+	"context"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"net/http"
+	"time"
+)
+
+type BudgetReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	// BudgetID is only set when updating a budget.
+	BudgetID string `param:"budgetID"`
+	// Year is the fiscal year of the property the budget covers. Only Amount can be updated.
+	Year     int    `json:"year" validate:"omitempty,gte=1970,lte=2100"`
+	Category string `json:"category" validate:"omitempty,category,ne=transfer"`
+	// Amount is a decimal string, positive for income and negative for expense, such as "-3000".
+	Amount property.Money `json:"amount"`
+	// Currency is an ISO-4217 code, and defaults to the currency of the property.
+	Currency string `json:"currency" validate:"omitempty,len=3,alpha"`
+}
+
+type GetBudgetsReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	// Year is optional, and lists the budgets of every year when not given.
+	Year int `query:"year" validate:"omitempty,gte=1970,lte=2100"`
+}
+
+type GetBudgetsRes struct {
+	Budgets []*property.Budget `json:"budgets"`
+}
+
+type BudgetIDReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	BudgetID   string `param:"budgetID" validate:"required"`
+}
+
+type GetVarianceReportReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	Year       int    `query:"year" validate:"required,gte=1970,lte=2100"`
+}
+
+type BudgetVariance struct {
+	Category string         `json:"category"`
+	Currency string         `json:"currency"`
+	Budgeted property.Money `json:"budgeted"`
+	Actual   property.Money `json:"actual"`
+	// Variance is Actual less Budgeted: positive when income beats its budget or expense stays
+	// under it, and negative otherwise.
+	Variance        property.Money `json:"variance"`
+	VariancePercent float64        `json:"variance_percent"`
+}
+
+type MonthVariance struct {
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Variances []*BudgetVariance `json:"variances"`
+}
+
+type GetVarianceReportRes struct {
+	Year       int               `json:"year"`
+	Months     []*MonthVariance  `json:"months"`
+	YearToDate []*BudgetVariance `json:"year_to_date"`
+}
+
+func newBudgetVariances(variances []*property.BudgetVariance) []*BudgetVariance {
+	return lo.Map(variances, func(v *property.BudgetVariance, _ int) *BudgetVariance {
+		return &BudgetVariance{
+			Category:        string(v.Category),
+			Currency:        v.Currency,
+			Budgeted:        v.Budgeted,
+			Actual:          v.Actual,
+			Variance:        v.Variance,
+			VariancePercent: v.VariancePercent,
+		}
+	})
+}
+
+func bindBudget(c echo.Context) (*property.Budget, error) {
+	req := &BudgetReq{}
+	if err := c.Bind(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return &property.Budget{
+		ID:         req.BudgetID,
+		PropertyID: req.PropertyID,
+		Year:       req.Year,
+		Category:   property.Category(req.Category),
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+	}, nil
+}
+
+func (h *RestHandler) CreateBudget(c echo.Context) error {
+	budget, err := bindBudget(c)
+	if err != nil {
+		return err
+	}
+	if budget.Year == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "year is required")
+	}
+	if budget.Category == property.Uncategorized {
+		return echo.NewHTTPError(http.StatusBadRequest, "category is required")
+	}
+
+	if err := h.Budgets.CreateBudget(context.Background(), budget); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, budget)
+}
+
+func (h *RestHandler) UpdateBudget(c echo.Context) error {
+	budget, err := bindBudget(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Budgets.UpdateBudget(context.Background(), budget); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, budget)
+}
+
+func (h *RestHandler) GetBudgets(c echo.Context) error {
+	req := &GetBudgetsReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	budgets, err := h.Budgets.GetBudgets(context.Background(), req.PropertyID, req.Year)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, &GetBudgetsRes{Budgets: budgets})
+}
+
+func (h *RestHandler) GetBudget(c echo.Context) error {
+	req := &BudgetIDReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	budget, err := h.Budgets.GetBudget(context.Background(), req.PropertyID, req.BudgetID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, budget)
+}
+
+func (h *RestHandler) DeleteBudget(c echo.Context) error {
+	req := &BudgetIDReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.Budgets.DeleteBudget(context.Background(), req.PropertyID, req.BudgetID); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *RestHandler) GetVarianceReport(c echo.Context) error {
+	req := &GetVarianceReportReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	report, err := h.Budgets.GetVarianceReport(context.Background(), req.PropertyID, req.Year)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, &GetVarianceReportRes{
+		Year: report.Year,
+		Months: lo.Map(report.Months, func(m *property.MonthVariance, _ int) *MonthVariance {
+			return &MonthVariance{
+				From:      m.From,
+				To:        m.To,
+				Variances: newBudgetVariances(m.Variances),
+			}
+		}),
+		YearToDate: newBudgetVariances(report.YearToDate),
+	})
+}
@@ -0,0 +1,37 @@
+package property
+
+import (
+	"context"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+type GetCategoryReportReq struct {
+	PropertyID string    `param:"propertyID" validate:"required"`
+	DateFrom   time.Time `query:"date_from" validate:"required"`
+	DateTo     time.Time `query:"date_to" validate:"required"`
+}
+
+type GetCategoryReportRes struct {
+	Categories []*property.CategoryTotal `json:"categories"`
+}
+
+func (h *RestHandler) GetCategoryReport(c echo.Context) error {
+	req := &GetCategoryReportReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	totals, err := h.PropertyHandler.GetCategoryReport(context.Background(), req.PropertyID, req.DateFrom, req.DateTo)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, &GetCategoryReportRes{Categories: totals})
+}
@@ -0,0 +1,39 @@
+package property
+
+import (
+	"context"
+	"fmt"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+// getCalendar returns the calendar of the property, in timezone if it is set.
+func (h *RestHandler) getCalendar(propertyID string, timezone string) (*property.Calendar, error) {
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid timezone %q", timezone))
+		}
+	}
+	calendar, err := h.PropertyHandler.GetCalendar(context.Background(), propertyID, timezone)
+	if err != nil {
+		return nil, toHTTPError(err)
+	}
+	return calendar, nil
+}
+
+// parseDate parses a date query parameter, either an RFC 3339 timestamp or a YYYY-MM-DD day of
+// calendar. A day is returned as its bounds, and a timestamp as itself along with a zero end.
+func parseDate(value string, calendar *property.Calendar) (time.Time, time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, time.Time{}, nil
+	}
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("invalid date %q, want an RFC 3339 timestamp or YYYY-MM-DD", value))
+	}
+	from, to := calendar.Day(day.Day(), day.Month(), day.Year())
+	return from, to, nil
+}
@@ -0,0 +1,71 @@
+package property
+
+import (
+	"errors"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// OverdraftErrorRes is the body of the error returned for an event rejected by the overdraft
+// policy of its property.
+type OverdraftErrorRes struct {
+	Message          string         `json:"message"`
+	Currency         string         `json:"currency"`
+	ProjectedBalance property.Money `json:"projected_balance"`
+	Minimum          property.Money `json:"minimum"`
+}
+
+// BalanceMismatchErrorRes is the body of the error returned for a reconciliation whose closing
+// balance does not agree with the ledger.
+type BalanceMismatchErrorRes struct {
+	Message        string         `json:"message"`
+	Currency       string         `json:"currency"`
+	ClosingBalance property.Money `json:"closing_balance"`
+	LedgerBalance  property.Money `json:"ledger_balance"`
+}
+
+// toHTTPError maps the typed errors of the property package to their HTTP status,
+// leaving any other error to echo, which reports it as an internal error.
+func toHTTPError(err error) error {
+	var overdraft *property.OverdraftError
+	var mismatch *property.BalanceMismatchError
+	switch {
+	case errors.As(err, &overdraft):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, &OverdraftErrorRes{
+			Message:          err.Error(),
+			Currency:         overdraft.Currency,
+			ProjectedBalance: overdraft.ProjectedBalance,
+			Minimum:          overdraft.Minimum,
+		})
+	case errors.As(err, &mismatch):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, &BalanceMismatchErrorRes{
+			Message:        err.Error(),
+			Currency:       mismatch.Currency,
+			ClosingBalance: mismatch.ClosingBalance,
+			LedgerBalance:  mismatch.LedgerBalance,
+		})
+	case errors.Is(err, property.ErrEventNotFound), errors.Is(err, property.ErrScheduleNotFound),
+		errors.Is(err, property.ErrPropertyNotFound), errors.Is(err, property.ErrPortfolioNotFound),
+		errors.Is(err, property.ErrBudgetNotFound), errors.Is(err, property.ErrAlertRuleNotFound),
+		errors.Is(err, property.ErrWebhookNotFound), errors.Is(err, property.ErrReconciliationNotFound),
+		errors.Is(err, property.ErrStatementLineNotFound), errors.Is(err, property.ErrAttachmentNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case errors.Is(err, property.ErrConflict), errors.Is(err, property.ErrNotReversible),
+		errors.Is(err, property.ErrNotAmendable), errors.Is(err, property.ErrPropertyExists),
+		errors.Is(err, property.ErrPropertyHasEvents), errors.Is(err, property.ErrBudgetExists),
+		errors.Is(err, property.ErrReconciled), errors.Is(err, property.ErrEventMatched):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, property.ErrOverflow), errors.Is(err, property.ErrNoRate),
+		errors.Is(err, property.ErrIdempotencyKeyReused), errors.Is(err, property.ErrNotMatchable),
+		errors.Is(err, property.ErrAttachmentEmpty):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, property.ErrInvalid):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, property.ErrAttachmentTooLarge):
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, property.ErrUnsupportedContentType):
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, err.Error())
+	}
+	return err
+}
@@ -11,14 +11,27 @@ import (
 )
 
 type GetEventsReq struct {
-	PropertyID string    `param:"propertyID" validate:"required"`
-	DateFrom   time.Time `query:"date_from"`
-	DateTo     time.Time `query:"date_to"`
-	SortOrder  string    `query:"sort_order" validate:"omitempty,oneof=asc desc"`
-	AmountType string    `query:"amount_type" validate:"omitempty,oneof=expense income"`
-	Offset     int       `query:"offset" validate:"omitempty,gt=0"`
-	Limit      int       `query:"limit" validate:"omitempty,gt=0"`
-	NextToken  string    `query:"next_token"`
+	PropertyID string `param:"propertyID" validate:"required"`
+	// DateFrom and DateTo are RFC 3339 timestamps, or YYYY-MM-DD days of Timezone taken whole.
+	DateFrom string `query:"date_from"`
+	DateTo   string `query:"date_to"`
+	// Timezone is an IANA time zone name, and defaults to the time zone of the property.
+	Timezone   string `query:"timezone"`
+	SortOrder  string `query:"sort_order" validate:"omitempty,oneof=asc desc"`
+	AmountType string `query:"amount_type" validate:"omitempty,oneof=expense income"`
+	Category   string `query:"category" validate:"omitempty,category"`
+	// Tags matches events carrying every one of the tags, given as repeated tag parameters.
+	Tags []string `query:"tag"`
+	// ExcludeReversed leaves out reversed events and the events compensating them.
+	ExcludeReversed bool `query:"exclude_reversed"`
+	// ExcludeTransfers leaves out the legs of transfers between properties.
+	ExcludeTransfers bool   `query:"exclude_transfers"`
+	Offset           int    `query:"offset" validate:"omitempty,gt=0"`
+	Limit            int    `query:"limit" validate:"omitempty,gt=0"`
+	NextToken        string `query:"next_token"`
+	// Format is json or csv, and defaults to the Accept header. The CSV file holds every
+	// matching event, leaving out Offset, Limit and NextToken.
+	Format string `query:"format" validate:"omitempty,oneof=json csv"`
 }
 
 type GetEventsRes struct {
@@ -27,9 +40,42 @@ type GetEventsRes struct {
 }
 
 type Event struct {
-	PropertyID  string    `json:"property_id,omitempty" bson:"property_id"`
-	EventAmount float64   `json:"event_amount" bson:"event_amount"`
-	Date        time.Time `json:"date" bson:"date"`
+	ID          string         `json:"id" bson:"_id"`
+	PropertyID  string         `json:"property_id,omitempty" bson:"property_id"`
+	EventAmount property.Money `json:"event_amount" bson:"event_amount"`
+	Currency    string         `json:"currency" bson:"currency"`
+	Date        time.Time      `json:"date" bson:"date"`
+	Description string         `json:"description,omitempty" bson:"description"`
+	Category    string         `json:"category,omitempty" bson:"category"`
+	Tags        []string       `json:"tags,omitempty" bson:"tags"`
+	Reversed    bool           `json:"reversed,omitempty" bson:"reversed"`
+	ReversalOf  string         `json:"reversal_of,omitempty" bson:"reversal_of"`
+	// ReversalReason is set on the compensating event.
+	ReversalReason string `json:"reversal_reason,omitempty" bson:"reversal_reason"`
+	Revision       int    `json:"revision,omitempty" bson:"revision"`
+	TransferID     string `json:"transfer_id,omitempty" bson:"transfer_id"`
+	Overdrawn      bool   `json:"overdrawn,omitempty" bson:"overdrawn"`
+	// Attachments are only set on the events listed by GetEvents.
+	Attachments []*property.Attachment `json:"attachments,omitempty" bson:"attachments"`
+}
+
+func newEvent(e *property.Event) *Event {
+	return &Event{
+		ID:             e.ID,
+		PropertyID:     e.PropertyID,
+		EventAmount:    e.EventAmount,
+		Currency:       e.Currency,
+		Date:           e.Date,
+		Description:    e.Description,
+		Category:       string(e.Category),
+		Tags:           e.Tags,
+		Reversed:       e.Reversed,
+		ReversalOf:     e.ReversalOf,
+		ReversalReason: e.ReversalReason,
+		Revision:       e.Revision,
+		TransferID:     e.TransferID,
+		Overdrawn:      e.Overdrawn,
+	}
 }
 
 func (h *RestHandler) GetEvents(c echo.Context) error {
@@ -62,18 +108,52 @@ func (h *RestHandler) GetEvents(c echo.Context) error {
 		amountType = property.Expense
 	}
 
-	events, err := h.PropertyHandler.GetPropertyEvents(context.Background(), req.PropertyID, req.DateFrom, req.DateTo, sortOrder, amountType, req.Offset, req.Limit)
+	filter := &property.EventFilter{
+		PropertyID:       req.PropertyID,
+		AmountType:       amountType,
+		Category:         property.Category(req.Category),
+		Tags:             req.Tags,
+		ExcludeReversed:  req.ExcludeReversed,
+		ExcludeTransfers: req.ExcludeTransfers,
+	}
+	if req.DateFrom != "" || req.DateTo != "" {
+		calendar, err := h.getCalendar(req.PropertyID, req.Timezone)
+		if err != nil {
+			return err
+		}
+		if req.DateFrom != "" {
+			if filter.AfterTime, _, err = parseDate(req.DateFrom, calendar); err != nil {
+				return err
+			}
+		}
+		if req.DateTo != "" {
+			// a day is taken whole, up to the start of the following day
+			if filter.BeforeTime, filter.EndTime, err = parseDate(req.DateTo, calendar); err != nil {
+				return err
+			}
+			if !filter.EndTime.IsZero() {
+				filter.BeforeTime = time.Time{}
+			}
+		}
+	}
+	if exportFormat(c, req.Format) == exportCSV {
+		calendar, err := h.getCalendar(req.PropertyID, req.Timezone)
+		if err != nil {
+			return err
+		}
+		return h.exportEvents(c, filter, sortOrder, calendar)
+	}
+	events, err := h.PropertyHandler.GetPropertyEvents(context.Background(), filter, sortOrder, req.Offset, req.Limit)
 	if err != nil {
-		return err
+		return toHTTPError(err)
 	}
 
 	mappedEvents := lo.Map(events, func(e *property.Event, _ int) *Event {
-		return &Event{
-			PropertyID:  e.PropertyID,
-			EventAmount: e.EventAmount,
-			Date:        e.Date,
-		}
+		return newEvent(e)
 	})
+	if err := h.eventAttachments(context.Background(), req.PropertyID, mappedEvents); err != nil {
+		return toHTTPError(err)
+	}
 	res := &GetEventsRes{
 		Events: mappedEvents,
 	}
@@ -89,9 +169,20 @@ func (h *RestHandler) GetEvents(c echo.Context) error {
 	return c.JSON(200, res)
 }
 
+// IdempotencyKeyHeader lets a client retry a save without risking saving the event twice.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
 type SaveEventReq struct {
-	PropertyID string  `param:"propertyID" validate:"required"`
-	Amount     float64 `json:"amount" validate:"required"`
+	PropertyID string `param:"propertyID" validate:"required"`
+	// Amount is a decimal string, such as "-120.50".
+	Amount property.Money `json:"amount"`
+	// Currency is an ISO-4217 code, and defaults to the base currency.
+	Currency string `json:"currency" validate:"omitempty,len=3,alpha"`
+	// Date defaults to now, an earlier date backdates the event.
+	Date        time.Time `json:"date"`
+	Description string    `json:"description" validate:"max=500"`
+	Category    string    `json:"category" validate:"omitempty,category,ne=transfer"`
+	Tags        []string  `json:"tags"`
 }
 
 func (h *RestHandler) SaveEvent(c echo.Context) error {
@@ -104,10 +195,27 @@ func (h *RestHandler) SaveEvent(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	balance, err := h.PropertyHandler.SaveEvent(context.Background(), req.PropertyID, req.Amount, time.Now())
+	if req.Amount.IsZero() {
+		return echo.NewHTTPError(http.StatusBadRequest, "amount is required")
+	}
+	if req.Date.IsZero() {
+		req.Date = time.Now()
+	}
+
+	event := &property.Event{
+		PropertyID:     req.PropertyID,
+		EventAmount:    req.Amount,
+		Currency:       req.Currency,
+		Date:           req.Date,
+		Description:    req.Description,
+		Category:       property.Category(req.Category),
+		Tags:           req.Tags,
+		IdempotencyKey: c.Request().Header.Get(IdempotencyKeyHeader),
+	}
+	balance, err := h.PropertyHandler.SaveEvent(context.Background(), event)
 	if err != nil {
-		return err
+		return toHTTPError(err)
 	}
 
-	return c.JSON(200, map[string]interface{}{"balance": balance})
+	return c.JSON(200, map[string]interface{}{"balance": balance, "id": event.ID, "overdrawn": event.Overdrawn})
 }
@@ -0,0 +1,142 @@
+package property
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/chn555/property-service/pkg/export"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The formats reports and event listings can be exported to, besides JSON.
+const (
+	exportCSV = "csv"
+	exportPDF = "pdf"
+)
+
+const (
+	mimeTextCSV        = "text/csv"
+	mimeApplicationPDF = "application/pdf"
+)
+
+// exportWriter writes the events of an export file, and ends it on Close.
+type exportWriter interface {
+	WriteEvent(event *property.Event) error
+	Close() error
+}
+
+// exportFormat returns the format requested by the format query parameter, or by the Accept
+// header if there is none, and "" for JSON.
+func exportFormat(c echo.Context, format string) string {
+	if format == "json" {
+		return ""
+	} else if format != "" {
+		return format
+	}
+	accept := c.Request().Header.Get(echo.HeaderAccept)
+	switch {
+	case strings.Contains(accept, mimeTextCSV):
+		return exportCSV
+	case strings.Contains(accept, mimeApplicationPDF):
+		return exportPDF
+	}
+	return ""
+}
+
+// exportPeriodReport streams the report of the property over [from, to) with every one of its
+// events as a CSV file, or sends it as a PDF document. A PDF document is only valid once whole,
+// so it is built before any of it is sent, and a failure is returned in its place.
+func (h *RestHandler) exportPeriodReport(c echo.Context, format string, propertyID string, from time.Time, to time.Time, excludeTransfers bool, calendar *property.Calendar) error {
+	ctx := context.Background()
+	report, err := h.PropertyHandler.GetPeriodTotals(ctx, propertyID, from, to, excludeTransfers)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	filename := fmt.Sprintf("%s_%s_%s.%s", propertyID, from.In(calendar.Location).Format(time.DateOnly),
+		to.Add(-time.Nanosecond).In(calendar.Location).Format(time.DateOnly), format)
+	var w exportWriter
+	switch format {
+	case exportCSV:
+		setExportHeaders(c, mimeTextCSV+"; charset=utf-8", filename)
+		w, err = export.NewCSVReport(c.Response(), report, calendar.Location)
+	case exportPDF:
+		var title string
+		if title, err = h.reportTitle(ctx, propertyID); err != nil {
+			return err
+		}
+		var document bytes.Buffer
+		if w, err = export.NewPDFReport(&document, report, title, calendar.Location); err != nil {
+			return err
+		}
+		if err := h.PropertyHandler.EachEvent(ctx, report.Filter(), property.Ascending, w.WriteEvent); err != nil {
+			return toHTTPError(err)
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		setExportHeaders(c, mimeApplicationPDF, filename)
+		return c.Blob(http.StatusOK, mimeApplicationPDF, document.Bytes())
+	}
+	if err != nil {
+		return err
+	}
+
+	return h.streamEvents(c, w, report.Filter(), property.Ascending)
+}
+
+// reportTitle returns the name of the property, or its ID if it has none.
+func (h *RestHandler) reportTitle(ctx context.Context, propertyID string) (string, error) {
+	p, err := h.PropertyHandler.GetProperty(ctx, propertyID)
+	if err != nil {
+		return "", toHTTPError(err)
+	}
+	if p.Name == "" {
+		return p.ID, nil
+	}
+	return p.Name, nil
+}
+
+// exportEvents streams the events matching filter as a CSV file.
+func (h *RestHandler) exportEvents(c echo.Context, filter *property.EventFilter, sortOrder property.SortOrder, calendar *property.Calendar) error {
+	setExportHeaders(c, mimeTextCSV+"; charset=utf-8", fmt.Sprintf("%s_events.csv", filter.PropertyID))
+	w, err := export.NewCSVEvents(c.Response(), calendar.Location)
+	if err != nil {
+		return err
+	}
+
+	return h.streamEvents(c, w, filter, sortOrder)
+}
+
+// streamEvents writes the events matching filter with w and ends the file. An error raised
+// before any of the file was sent is returned in its place. Once the file has started, its status
+// was sent with it, so the error is logged and the transfer aborted, leaving the client with
+// an incomplete response rather than a truncated file.
+func (h *RestHandler) streamEvents(c echo.Context, w exportWriter, filter *property.EventFilter, sortOrder property.SortOrder) error {
+	err := h.PropertyHandler.EachEvent(context.Background(), filter, sortOrder, w.WriteEvent)
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		return nil
+	}
+	if !c.Response().Committed {
+		c.Response().Header().Del(echo.HeaderContentType)
+		c.Response().Header().Del(echo.HeaderContentDisposition)
+		return toHTTPError(err)
+	}
+
+	slog.Error("failed to export events", slog.String("property_id", filter.PropertyID),
+		slog.String("path", c.Request().URL.Path), slog.String("err", err.Error()))
+	panic(http.ErrAbortHandler)
+}
+
+func setExportHeaders(c echo.Context, contentType string, filename string) {
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+}
@@ -0,0 +1,85 @@
+package property
+
+import (
+	"context"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"net/http"
+	"time"
+)
+
+type GetForecastReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	// Months is how many month-end balances are projected, counting the current month.
+	Months int `query:"months" validate:"required,gte=3,lte=24"`
+}
+
+type CategoryAverage struct {
+	Category string         `json:"category"`
+	Currency string         `json:"currency"`
+	Monthly  property.Money `json:"monthly"`
+}
+
+type ForecastMonth struct {
+	From time.Time `json:"from"`
+	// To is the end of the month the balances are projected at.
+	To          time.Time                 `json:"to"`
+	Expected    map[string]property.Money `json:"expected"`
+	Optimistic  map[string]property.Money `json:"optimistic"`
+	Pessimistic map[string]property.Money `json:"pessimistic"`
+}
+
+type GetForecastRes struct {
+	From            time.Time                 `json:"from"`
+	StartingBalance map[string]property.Money `json:"starting_balance"`
+	// HistoryFrom and HistoryTo bound the months the averages were taken over, and are left out
+	// for a property without history.
+	HistoryFrom *time.Time         `json:"history_from,omitempty"`
+	HistoryTo   *time.Time         `json:"history_to,omitempty"`
+	Averages    []*CategoryAverage `json:"averages"`
+	Months      []*ForecastMonth   `json:"months"`
+}
+
+func (h *RestHandler) GetForecast(c echo.Context) error {
+	req := &GetForecastReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	forecast, err := h.PropertyHandler.GetForecast(context.Background(), req.PropertyID, time.Now(), req.Months)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	res := &GetForecastRes{
+		From:            forecast.From,
+		StartingBalance: forecast.StartingBalance,
+		Averages: lo.Map(forecast.Averages, func(a *property.CategoryAverage, _ int) *CategoryAverage {
+			return &CategoryAverage{
+				Category: string(a.Category),
+				Currency: a.Currency,
+				Monthly:  a.Monthly,
+			}
+		}),
+		Months: lo.Map(forecast.Months, func(m *property.ForecastMonth, _ int) *ForecastMonth {
+			return &ForecastMonth{
+				From:        m.From,
+				To:          m.To,
+				Expected:    m.Expected,
+				Optimistic:  m.Optimistic,
+				Pessimistic: m.Pessimistic,
+			}
+		}),
+	}
+	if !forecast.HistoryFrom.IsZero() {
+		res.HistoryFrom = &forecast.HistoryFrom
+		res.HistoryTo = &forecast.HistoryTo
+	}
+
+	return c.JSON(200, res)
+}
@@ -7,18 +7,90 @@ import (
 
 type RestHandler struct {
 	PropertyHandler *property.Handler
+	Scheduler       *property.Scheduler
+	Portfolios      *property.PortfolioHandler
+	Budgets         *property.Budgets
+	Alerts          *property.Alerts
+	Reconciliations *property.Reconciliations
+	Attachments     *property.Attachments
 }
 
-func NewRestHandler(propertyHandler *property.Handler) *RestHandler {
-	return &RestHandler{PropertyHandler: propertyHandler}
+func NewRestHandler(propertyHandler *property.Handler, scheduler *property.Scheduler, portfolios *property.PortfolioHandler, budgets *property.Budgets, alerts *property.Alerts, reconciliations *property.Reconciliations, attachments *property.Attachments) *RestHandler {
+	return &RestHandler{
+		PropertyHandler: propertyHandler,
+		Scheduler:       scheduler,
+		Portfolios:      portfolios,
+		Budgets:         budgets,
+		Alerts:          alerts,
+		Reconciliations: reconciliations,
+		Attachments:     attachments,
+	}
 }
 
 func (h *RestHandler) RegisterHandlers(e *echo.Echo) *echo.Echo {
 	g := e.Group("/property")
+	g.POST("", h.CreateProperty)
+	g.GET("", h.GetProperties)
+	g.GET("/:propertyID", h.GetProperty)
+	g.PUT("/:propertyID", h.UpdateProperty)
+	g.DELETE("/:propertyID", h.DeleteProperty)
 	g.POST("/:propertyID", h.SaveEvent)
 	g.GET("/:propertyID/events", h.GetEvents)
+	g.POST("/:propertyID/import", h.ImportEvents)
+	g.PATCH("/:propertyID/events/:eventID", h.AmendEvent)
+	g.GET("/:propertyID/events/:eventID/history", h.GetEventHistory)
+	g.POST("/:propertyID/events/:eventID/reverse", h.ReverseEvent)
+	g.POST("/:propertyID/events/:eventID/attachments", h.UploadAttachment)
+	g.GET("/:propertyID/events/:eventID/attachments", h.GetAttachments)
+	g.GET("/:propertyID/events/:eventID/attachments/:attachmentID", h.DownloadAttachment)
+	g.DELETE("/:propertyID/events/:eventID/attachments/:attachmentID", h.DeleteAttachment)
 	g.GET("/:propertyID/monthly_report", h.GetMonthlyReport)
+	g.GET("/:propertyID/quarterly_report", h.GetQuarterlyReport)
+	g.GET("/:propertyID/yearly_report", h.GetYearlyReport)
+	g.GET("/:propertyID/report", h.GetPeriodReport)
 	g.GET("/:propertyID/balance", h.getBalance)
+	g.GET("/:propertyID/category_report", h.GetCategoryReport)
+	g.GET("/:propertyID/forecast", h.GetForecast)
+	g.POST("/:propertyID/schedules", h.CreateSchedule)
+	g.GET("/:propertyID/schedules", h.GetSchedules)
+	g.GET("/:propertyID/schedules/:scheduleID", h.GetSchedule)
+	g.PUT("/:propertyID/schedules/:scheduleID", h.UpdateSchedule)
+	g.DELETE("/:propertyID/schedules/:scheduleID", h.DeleteSchedule)
+	g.POST("/:propertyID/budgets", h.CreateBudget)
+	g.GET("/:propertyID/budgets", h.GetBudgets)
+	g.GET("/:propertyID/budgets/:budgetID", h.GetBudget)
+	g.PUT("/:propertyID/budgets/:budgetID", h.UpdateBudget)
+	g.DELETE("/:propertyID/budgets/:budgetID", h.DeleteBudget)
+	g.GET("/:propertyID/budget_variance", h.GetVarianceReport)
+	g.POST("/:propertyID/alerts", h.CreateAlertRule)
+	g.GET("/:propertyID/alerts", h.GetAlertRules)
+	g.GET("/:propertyID/alerts/:ruleID", h.GetAlertRule)
+	g.DELETE("/:propertyID/alerts/:ruleID", h.DeleteAlertRule)
+	g.POST("/:propertyID/reconciliations", h.CreateReconciliation)
+	g.GET("/:propertyID/reconciliations", h.GetReconciliations)
+	g.GET("/:propertyID/reconciliations/:reconciliationID", h.GetReconciliation)
+	g.DELETE("/:propertyID/reconciliations/:reconciliationID", h.DeleteReconciliation)
+	g.POST("/:propertyID/reconciliations/:reconciliationID/auto_match", h.AutoMatchReconciliation)
+	g.PUT("/:propertyID/reconciliations/:reconciliationID/lines/:row/match", h.MatchStatementLine)
+	g.DELETE("/:propertyID/reconciliations/:reconciliationID/lines/:row/match", h.UnmatchStatementLine)
+	g.POST("/:propertyID/reconciliations/:reconciliationID/complete", h.CompleteReconciliation)
+
+	p := e.Group("/portfolio")
+	p.POST("", h.CreatePortfolio)
+	p.GET("", h.GetPortfolios)
+	p.GET("/:portfolioID", h.GetPortfolio)
+	p.PUT("/:portfolioID", h.UpdatePortfolio)
+	p.DELETE("/:portfolioID", h.DeletePortfolio)
+	p.GET("/:portfolioID/balance", h.GetPortfolioBalance)
+	p.GET("/:portfolioID/monthly_report", h.GetPortfolioMonthlyReport)
+
+	w := e.Group("/webhooks")
+	w.POST("", h.CreateWebhook)
+	w.GET("", h.GetWebhooks)
+	w.DELETE("/:webhookID", h.DeleteWebhook)
+	w.GET("/deliveries", h.GetDeliveries)
+
+	e.POST("/transfers", h.Transfer)
 
 	return e
 }
@@ -0,0 +1,135 @@
+package property
+
+import (
+	"context"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/chn555/property-service/pkg/statement"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxStatementSize bounds the statements that can be imported at once.
+const maxStatementSize = 10 << 20
+
+type ImportEventsReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	Format     string `query:"format" validate:"required,oneof=csv ofx qif"`
+	// DateFormat is the Go layout of the dates of CSV and QIF statements, such as 02/01/2006.
+	DateFormat string `query:"date_format"`
+	// Timezone is the IANA name of the time zone of dates without one, and defaults to the
+	// time zone of the property.
+	Timezone string `query:"timezone"`
+	// Currency is the ISO-4217 code of lines without one, and defaults to the currency of the property.
+	Currency string `query:"currency" validate:"omitempty,len=3,alpha"`
+	// The columns of a CSV statement are named as in its header row.
+	DateColumn        string `query:"date_column"`
+	AmountColumn      string `query:"amount_column"`
+	DebitColumn       string `query:"debit_column"`
+	CreditColumn      string `query:"credit_column"`
+	DescriptionColumn string `query:"description_column"`
+	ReferenceColumn   string `query:"reference_column"`
+	CategoryColumn    string `query:"category_column"`
+	CurrencyColumn    string `query:"currency_column"`
+	Delimiter         string `query:"delimiter" validate:"omitempty,len=1"`
+}
+
+type ImportResult struct {
+	Row     int    `json:"row"`
+	Status  string `json:"status"`
+	EventID string `json:"event_id,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type ImportEventsRes struct {
+	Imported int             `json:"imported"`
+	Skipped  int             `json:"skipped"`
+	Errored  int             `json:"errored"`
+	Lines    []*ImportResult `json:"lines"`
+}
+
+func newImportEventsRes(report *property.ImportReport) *ImportEventsRes {
+	return &ImportEventsRes{
+		Imported: report.Imported,
+		Skipped:  report.Skipped,
+		Errored:  report.Errored,
+		Lines: lo.Map(report.Lines, func(r *property.ImportResult, _ int) *ImportResult {
+			return &ImportResult{
+				Row:     r.Row,
+				Status:  string(r.Status),
+				EventID: r.EventID,
+				Reason:  r.Reason,
+			}
+		}),
+	}
+}
+
+// ImportEvents imports a bank statement, sent as the request body or as the file field of a
+// multipart form.
+func (h *RestHandler) ImportEvents(c echo.Context) error {
+	req := &ImportEventsReq{}
+	binder := &echo.DefaultBinder{}
+	if err := binder.BindPathParams(c, req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := binder.BindQueryParams(c, req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	calendar, err := h.getCalendar(req.PropertyID, req.Timezone)
+	if err != nil {
+		return err
+	}
+	delimiter, _ := utf8.DecodeRuneInString(req.Delimiter)
+	options := statement.Options{
+		Format: statement.Format(req.Format),
+		CSV: statement.CSVMapping{
+			Date:        req.DateColumn,
+			Amount:      req.AmountColumn,
+			Debit:       req.DebitColumn,
+			Credit:      req.CreditColumn,
+			Description: req.DescriptionColumn,
+			Reference:   req.ReferenceColumn,
+			Category:    req.CategoryColumn,
+			Currency:    req.CurrencyColumn,
+			Delimiter:   lo.Ternary(req.Delimiter == "", 0, delimiter),
+		},
+		DateFormat: req.DateFormat,
+		Location:   calendar.Location,
+		Currency:   req.Currency,
+	}
+
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxStatementSize)
+	var body io.Reader = c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		f, err := file.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		defer f.Close()
+		body = f
+	}
+
+	lines, err := statement.Parse(body, options)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	report, err := h.PropertyHandler.ImportEvents(context.Background(), req.PropertyID, lines)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, newImportEventsRes(report))
+}
@@ -0,0 +1,191 @@
+package property
+
+import (
+	"context"
+	"github.com/chn555/property-service/internal/rest"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"net/http"
+	"time"
+)
+
+type PortfolioReq struct {
+	Name        string   `json:"name" validate:"required,max=255"`
+	PropertyIDs []string `json:"property_ids" validate:"required,min=1,dive,required,max=255"`
+}
+
+type GetPortfoliosRes struct {
+	Portfolios []*property.Portfolio `json:"portfolios"`
+}
+
+type GetPortfolioBalanceRes struct {
+	*GetBalanceRes
+	// ByProperty maps the ID of each property of the portfolio to its own balance.
+	ByProperty map[string]*GetBalanceRes `json:"by_property"`
+}
+
+type GetPortfolioMonthlyReportReq struct {
+	PortfolioID string     `param:"portfolioID" validate:"required"`
+	Month       time.Month `query:"month" validate:"required"`
+	Year        int        `query:"year" validate:"gte=1970,lte=2030"`
+	// ExcludeTransfers leaves out the legs of transfers between properties.
+	ExcludeTransfers bool   `query:"exclude_transfers"`
+	Offset           int    `query:"offset" validate:"omitempty,gt=0"`
+	Limit            int    `query:"limit" validate:"omitempty,gt=0"`
+	NextToken        string `query:"next_token"`
+}
+
+type PropertySubtotal struct {
+	PropertyID      string         `json:"property_id"`
+	StartingBalance *GetBalanceRes `json:"starting_balance"`
+	// Net is the net of the property events in the month, converted to the base currency.
+	Net           property.Money            `json:"net"`
+	NetByCurrency map[string]property.Money `json:"net_by_currency"`
+}
+
+type GetPortfolioMonthlyReportRes struct {
+	StartingBalance *GetBalanceRes      `json:"starting_balance"`
+	Subtotals       []*PropertySubtotal `json:"subtotals"`
+	Events          []*ReportEvent      `json:"events"`
+	NextToken       string              `json:"next_token"`
+}
+
+func bindPortfolio(c echo.Context) (*property.Portfolio, error) {
+	req := &PortfolioReq{}
+	if err := c.Bind(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return &property.Portfolio{
+		Name:        req.Name,
+		PropertyIDs: req.PropertyIDs,
+	}, nil
+}
+
+func (h *RestHandler) CreatePortfolio(c echo.Context) error {
+	p, err := bindPortfolio(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Portfolios.CreatePortfolio(context.Background(), p); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, p)
+}
+
+func (h *RestHandler) UpdatePortfolio(c echo.Context) error {
+	p, err := bindPortfolio(c)
+	if err != nil {
+		return err
+	}
+	p.ID = c.Param("portfolioID")
+
+	if err := h.Portfolios.UpdatePortfolio(context.Background(), p); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, p)
+}
+
+func (h *RestHandler) GetPortfolio(c echo.Context) error {
+	portfolioID := c.Param("portfolioID")
+	if portfolioID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "portfolio ID is required")
+	}
+	p, err := h.Portfolios.GetPortfolio(context.Background(), portfolioID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+	return c.JSON(200, p)
+}
+
+func (h *RestHandler) GetPortfolios(c echo.Context) error {
+	portfolios, err := h.Portfolios.GetPortfolios(context.Background())
+	if err != nil {
+		return toHTTPError(err)
+	}
+	return c.JSON(200, &GetPortfoliosRes{Portfolios: portfolios})
+}
+
+func (h *RestHandler) DeletePortfolio(c echo.Context) error {
+	portfolioID := c.Param("portfolioID")
+	if portfolioID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "portfolio ID is required")
+	}
+	if err := h.Portfolios.DeletePortfolio(context.Background(), portfolioID); err != nil {
+		return toHTTPError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *RestHandler) GetPortfolioBalance(c echo.Context) error {
+	portfolioID := c.Param("portfolioID")
+	if portfolioID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "portfolio ID is required")
+	}
+	balance, err := h.Portfolios.GetPortfolioBalance(context.Background(), portfolioID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+	return c.JSON(200, &GetPortfolioBalanceRes{
+		GetBalanceRes: newGetBalanceRes(balance.Balance),
+		ByProperty: lo.MapValues(balance.ByProperty, func(b *property.Balance, _ string) *GetBalanceRes {
+			return newGetBalanceRes(b)
+		}),
+	})
+}
+
+func (h *RestHandler) GetPortfolioMonthlyReport(c echo.Context) error {
+	req := &GetPortfolioMonthlyReportReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.NextToken != "" {
+		token, err := rest.DecodeNextToken(req.NextToken)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		req.Limit = token.Limit
+		req.Offset = token.Offset
+	}
+
+	report, err := h.Portfolios.GetPortfolioMonthlyReport(context.Background(), req.PortfolioID, req.Month, req.Year, req.ExcludeTransfers, req.Offset, req.Limit)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	res := &GetPortfolioMonthlyReportRes{
+		StartingBalance: newGetBalanceRes(report.StartingBalance),
+		Subtotals: lo.Map(report.Subtotals, func(s *property.PropertySubtotal, _ int) *PropertySubtotal {
+			return &PropertySubtotal{
+				PropertyID:      s.PropertyID,
+				StartingBalance: newGetBalanceRes(s.StartingBalance),
+				Net:             s.NetTotal,
+				NetByCurrency:   s.Net,
+			}
+		}),
+		Events: newReportEvents(report.Events),
+	}
+
+	if len(report.Events) >= req.Limit {
+		nextToken, err := rest.CreateNextToken(req.Limit, req.Offset+req.Limit)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		res.NextToken = nextToken
+	}
+
+	return c.JSON(200, res)
+}
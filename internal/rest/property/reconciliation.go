@@ -0,0 +1,275 @@
+package property
+
+import (
+	"context"
+	"fmt"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"net/http"
+)
+
+// defaultDateToleranceDays is how many days apart lines and events can be matched when the
+// request does not say.
+const defaultDateToleranceDays = 3
+
+type StatementLineReq struct {
+	// Date is an RFC 3339 timestamp, or a YYYY-MM-DD day of the property time zone.
+	Date string `json:"date" validate:"required"`
+	// Amount is a decimal string, negative for money leaving the account, such as "-120.50".
+	Amount      property.Money `json:"amount"`
+	Description string         `json:"description" validate:"max=500"`
+	Reference   string         `json:"reference" validate:"max=255"`
+}
+
+type CreateReconciliationReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	// From and To are the first and last day of the statement, as YYYY-MM-DD days of the property
+	// time zone, or RFC 3339 timestamps bounding the period [from, to).
+	From string `json:"from" validate:"required"`
+	To   string `json:"to" validate:"required"`
+	// Currency is an ISO-4217 code, and defaults to the currency of the property.
+	Currency string `json:"currency" validate:"omitempty,len=3,alpha"`
+	// ClosingBalance is the balance of the account at the end of the statement.
+	ClosingBalance property.Money `json:"closing_balance"`
+	// DateToleranceDays is how many days apart a line and an event can be dated and still be
+	// matched automatically, 3 by default.
+	DateToleranceDays *int                `json:"date_tolerance_days" validate:"omitempty,min=0,max=31"`
+	Lines             []*StatementLineReq `json:"lines" validate:"dive"`
+}
+
+type ReconciliationIDReq struct {
+	PropertyID       string `param:"propertyID" validate:"required"`
+	ReconciliationID string `param:"reconciliationID" validate:"required"`
+}
+
+type GetReconciliationsReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+}
+
+type GetReconciliationsRes struct {
+	Reconciliations []*property.Reconciliation `json:"reconciliations"`
+}
+
+type MatchLineReq struct {
+	PropertyID       string `param:"propertyID" validate:"required"`
+	ReconciliationID string `param:"reconciliationID" validate:"required"`
+	Row              int    `param:"row" validate:"required,gt=0"`
+	// EventID is the event to match the line to, and confirms the suggested event when empty.
+	EventID string `json:"event_id"`
+}
+
+type ReconciliationRes struct {
+	Reconciliation  *property.Reconciliation  `json:"reconciliation"`
+	UnmatchedLines  []*property.StatementLine `json:"unmatched_lines"`
+	UnmatchedEvents []*Event                  `json:"unmatched_events"`
+	// LedgerBalance is the balance of the ledger at the end of the period, and Difference the
+	// closing balance less it, which must be zero for the period to be reconciled.
+	LedgerBalance property.Money `json:"ledger_balance"`
+	Difference    property.Money `json:"difference"`
+}
+
+func newReconciliationRes(review *property.ReconciliationReview) *ReconciliationRes {
+	return &ReconciliationRes{
+		Reconciliation: review.Reconciliation,
+		UnmatchedLines: lo.Ternary(review.UnmatchedLines == nil, []*property.StatementLine{}, review.UnmatchedLines),
+		UnmatchedEvents: lo.Map(review.UnmatchedEvents, func(e *property.Event, _ int) *Event {
+			return newEvent(e)
+		}),
+		LedgerBalance: review.LedgerBalance,
+		Difference:    review.Difference,
+	}
+}
+
+func (h *RestHandler) CreateReconciliation(c echo.Context) error {
+	req := &CreateReconciliationReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	calendar, err := h.getCalendar(req.PropertyID, "")
+	if err != nil {
+		return err
+	}
+	from, _, err := parseDate(req.From, calendar)
+	if err != nil {
+		return err
+	}
+	// the last day of the statement is taken whole
+	to, toEnd, err := parseDate(req.To, calendar)
+	if err != nil {
+		return err
+	}
+	if !toEnd.IsZero() {
+		to = toEnd
+	}
+	if !from.Before(to) {
+		return echo.NewHTTPError(http.StatusBadRequest, "from must be before to")
+	}
+
+	reconciliation := &property.Reconciliation{
+		PropertyID:        req.PropertyID,
+		Currency:          req.Currency,
+		From:              from,
+		To:                to,
+		ClosingBalance:    req.ClosingBalance,
+		DateToleranceDays: defaultDateToleranceDays,
+		Lines:             make([]*property.StatementLine, len(req.Lines)),
+	}
+	if req.DateToleranceDays != nil {
+		reconciliation.DateToleranceDays = *req.DateToleranceDays
+	}
+	for i, l := range req.Lines {
+		date, _, err := parseDate(l.Date, calendar)
+		if err != nil {
+			return err
+		}
+		if l.Amount.IsZero() {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("line %d: amount is required", i+1))
+		} else if date.Before(from) || !date.Before(to) {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("line %d: dated outside the statement", i+1))
+		}
+		reconciliation.Lines[i] = &property.StatementLine{
+			Date:        date,
+			Amount:      l.Amount,
+			Description: l.Description,
+			Reference:   l.Reference,
+		}
+	}
+
+	review, err := h.Reconciliations.CreateReconciliation(context.Background(), reconciliation)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, newReconciliationRes(review))
+}
+
+func (h *RestHandler) GetReconciliations(c echo.Context) error {
+	req := &GetReconciliationsReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	reconciliations, err := h.Reconciliations.GetReconciliations(context.Background(), req.PropertyID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, &GetReconciliationsRes{Reconciliations: reconciliations})
+}
+
+func (h *RestHandler) GetReconciliation(c echo.Context) error {
+	req, err := bindReconciliationID(c)
+	if err != nil {
+		return err
+	}
+
+	review, err := h.Reconciliations.GetReconciliation(context.Background(), req.PropertyID, req.ReconciliationID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, newReconciliationRes(review))
+}
+
+func (h *RestHandler) DeleteReconciliation(c echo.Context) error {
+	req, err := bindReconciliationID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Reconciliations.DeleteReconciliation(context.Background(), req.PropertyID, req.ReconciliationID); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AutoMatchReconciliation matches the unmatched lines again, such as after the missing events
+// were saved.
+func (h *RestHandler) AutoMatchReconciliation(c echo.Context) error {
+	req, err := bindReconciliationID(c)
+	if err != nil {
+		return err
+	}
+
+	review, err := h.Reconciliations.AutoMatch(context.Background(), req.PropertyID, req.ReconciliationID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, newReconciliationRes(review))
+}
+
+func (h *RestHandler) MatchStatementLine(c echo.Context) error {
+	req := &MatchLineReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	review, err := h.Reconciliations.MatchLine(context.Background(), req.PropertyID, req.ReconciliationID, req.Row, req.EventID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, newReconciliationRes(review))
+}
+
+func (h *RestHandler) UnmatchStatementLine(c echo.Context) error {
+	req := &MatchLineReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	review, err := h.Reconciliations.UnmatchLine(context.Background(), req.PropertyID, req.ReconciliationID, req.Row)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, newReconciliationRes(review))
+}
+
+// CompleteReconciliation marks the period as reconciled, once the closing balance agrees with
+// the ledger.
+func (h *RestHandler) CompleteReconciliation(c echo.Context) error {
+	req, err := bindReconciliationID(c)
+	if err != nil {
+		return err
+	}
+
+	review, err := h.Reconciliations.CompleteReconciliation(context.Background(), req.PropertyID, req.ReconciliationID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, newReconciliationRes(review))
+}
+
+func bindReconciliationID(c echo.Context) (*ReconciliationIDReq, error) {
+	req := &ReconciliationIDReq{}
+	if err := c.Bind(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return req, nil
+}
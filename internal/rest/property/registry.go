@@ -0,0 +1,207 @@
+package property
+
+import (
+	"context"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"net/http"
+	"time"
+)
+
+type PropertyReq struct {
+	// PropertyID is optional when creating a property, and is generated if not given.
+	// When updating a property it is taken from the path.
+	PropertyID string `json:"id" validate:"max=255"`
+	Name       string `json:"name" validate:"required,max=255"`
+	Address    string `json:"address" validate:"max=500"`
+	Owner      string `json:"owner" validate:"max=255"`
+	// AcquisitionDate is optional.
+	AcquisitionDate time.Time `json:"acquisition_date"`
+	// Currency is an ISO-4217 code, and defaults to the base currency.
+	Currency string `json:"currency" validate:"omitempty,len=3,alpha"`
+	// Timezone is an IANA time zone name, and defaults to UTC.
+	Timezone string `json:"timezone"`
+	// FiscalYearStart is the month, from 1 to 12, fiscal years start in, and defaults to January.
+	FiscalYearStart int      `json:"fiscal_year_start" validate:"omitempty,min=1,max=12"`
+	Tags            []string `json:"tags"`
+	// OverdraftPolicy applies to expenses taking the balance below MinimumBalance, and defaults to allow.
+	// When updating a property without it, the property keeps its policy and minimum balance.
+	OverdraftPolicy string `json:"overdraft_policy" validate:"required_with=MinimumBalance,omitempty,oneof=allow warn reject"`
+	// MinimumBalance is a decimal string in the currency of the property, and defaults to zero.
+	// It is only accepted along with OverdraftPolicy.
+	MinimumBalance *property.Money `json:"minimum_balance"`
+}
+
+type Property struct {
+	ID              string         `json:"id"`
+	Name            string         `json:"name"`
+	Address         string         `json:"address,omitempty"`
+	Owner           string         `json:"owner,omitempty"`
+	AcquisitionDate *time.Time     `json:"acquisition_date,omitempty"`
+	Currency        string         `json:"currency"`
+	Timezone        string         `json:"timezone"`
+	FiscalYearStart int            `json:"fiscal_year_start"`
+	Tags            []string       `json:"tags,omitempty"`
+	OverdraftPolicy string         `json:"overdraft_policy"`
+	MinimumBalance  property.Money `json:"minimum_balance"`
+}
+
+func newProperty(p *property.Property) *Property {
+	res := &Property{
+		ID:              p.ID,
+		Name:            p.Name,
+		Address:         p.Address,
+		Owner:           p.Owner,
+		Currency:        p.Currency,
+		Timezone:        p.Timezone,
+		FiscalYearStart: int(p.FiscalYearStart),
+		Tags:            p.Tags,
+		OverdraftPolicy: string(p.OverdraftPolicy),
+		MinimumBalance:  p.MinimumBalance,
+	}
+	if !p.AcquisitionDate.IsZero() {
+		res.AcquisitionDate = &p.AcquisitionDate
+	}
+	return res
+}
+
+type GetPropertiesReq struct {
+	Owner string `query:"owner"`
+	// Tags matches properties carrying every one of the tags, given as repeated tag parameters.
+	Tags      []string `query:"tag"`
+	Sort      string   `query:"sort" validate:"omitempty,oneof=name balance"`
+	SortOrder string   `query:"sort_order" validate:"omitempty,oneof=asc desc"`
+}
+
+type PropertySummary struct {
+	*Property
+	Balance *GetBalanceRes `json:"balance"`
+	// LastEventDate is left out for a property without events.
+	LastEventDate *time.Time `json:"last_event_date,omitempty"`
+	// MonthlyNet is the net of the events of the current month in the time zone of the property,
+	// converted to the base currency.
+	MonthlyNet           property.Money            `json:"monthly_net"`
+	MonthlyNetByCurrency map[string]property.Money `json:"monthly_net_by_currency"`
+}
+
+type GetPropertiesRes struct {
+	Properties []*PropertySummary `json:"properties"`
+}
+
+func bindProperty(c echo.Context) (*property.Property, error) {
+	req := &PropertyReq{}
+	if err := c.Bind(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return &property.Property{
+		ID:              req.PropertyID,
+		Name:            req.Name,
+		Address:         req.Address,
+		Owner:           req.Owner,
+		AcquisitionDate: req.AcquisitionDate,
+		Currency:        req.Currency,
+		Timezone:        req.Timezone,
+		FiscalYearStart: time.Month(req.FiscalYearStart),
+		Tags:            req.Tags,
+		OverdraftPolicy: property.OverdraftPolicy(req.OverdraftPolicy),
+		MinimumBalance:  lo.FromPtr(req.MinimumBalance),
+	}, nil
+}
+
+func (h *RestHandler) CreateProperty(c echo.Context) error {
+	p, err := bindProperty(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.PropertyHandler.CreateProperty(context.Background(), p); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, newProperty(p))
+}
+
+func (h *RestHandler) UpdateProperty(c echo.Context) error {
+	p, err := bindProperty(c)
+	if err != nil {
+		return err
+	}
+	p.ID = c.Param("propertyID")
+
+	if err := h.PropertyHandler.UpdateProperty(context.Background(), p); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, newProperty(p))
+}
+
+func (h *RestHandler) GetProperty(c echo.Context) error {
+	propertyID := c.Param("propertyID")
+	if propertyID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "property ID is required")
+	}
+	p, err := h.PropertyHandler.GetProperty(context.Background(), propertyID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+	return c.JSON(200, newProperty(p))
+}
+
+func (h *RestHandler) GetProperties(c echo.Context) error {
+	req := &GetPropertiesReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	sortBy := property.SortByName
+	if req.Sort == "balance" {
+		sortBy = property.SortByBalance
+	}
+	sortOrder := property.Ascending
+	if req.SortOrder == "desc" {
+		sortOrder = property.Descending
+	}
+
+	filter := &property.PropertyFilter{
+		Owner: req.Owner,
+		Tags:  req.Tags,
+	}
+	summaries, err := h.PropertyHandler.ListProperties(context.Background(), filter, sortBy, sortOrder)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, &GetPropertiesRes{Properties: lo.Map(summaries, func(s *property.PropertySummary, _ int) *PropertySummary {
+		summary := &PropertySummary{
+			Property:             newProperty(s.Property),
+			Balance:              newGetBalanceRes(s.Balance),
+			MonthlyNet:           s.MonthlyNetTotal,
+			MonthlyNetByCurrency: s.MonthlyNet,
+		}
+		if !s.LastEventDate.IsZero() {
+			summary.LastEventDate = &s.LastEventDate
+		}
+		return summary
+	})})
+}
+
+func (h *RestHandler) DeleteProperty(c echo.Context) error {
+	propertyID := c.Param("propertyID")
+	if propertyID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "property ID is required")
+	}
+	if err := h.PropertyHandler.DeleteProperty(context.Background(), propertyID); err != nil {
+		return toHTTPError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,197 @@
+package property
+
+import (
+	"context"
+	"github.com/chn555/property-service/internal/rest"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"net/http"
+	"time"
+)
+
+type ReportEvent struct {
+	ID          string         `json:"id" bson:"_id"`
+	PropertyID  string         `json:"property_id,omitempty" bson:"property_id"`
+	EventAmount property.Money `json:"event_amount" bson:"event_amount"`
+	Currency    string         `json:"currency" bson:"currency"`
+	Date        time.Time      `json:"date" bson:"date"`
+	// Balance is the running balance in Currency.
+	Balance property.Money `json:"balance" bson:"balance"`
+}
+
+// ReportReq holds the parameters shared by the period report requests.
+type ReportReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	// Timezone is an IANA time zone name, and defaults to the time zone of the property.
+	Timezone string `query:"timezone"`
+	// ExcludeTransfers leaves out the legs of transfers between properties.
+	ExcludeTransfers bool   `query:"exclude_transfers"`
+	Offset           int    `query:"offset" validate:"omitempty,gt=0"`
+	Limit            int    `query:"limit" validate:"omitempty,gt=0"`
+	NextToken        string `query:"next_token"`
+	// Format is json, csv or pdf, and defaults to the Accept header. The exported files hold
+	// every event of the period, leaving out Offset, Limit and NextToken.
+	Format string `query:"format" validate:"omitempty,oneof=json csv pdf"`
+}
+
+func (r *ReportReq) reportReq() *ReportReq {
+	return r
+}
+
+// reportRequest is a period report request, embedding ReportReq.
+type reportRequest interface {
+	reportReq() *ReportReq
+}
+
+// reportPeriod returns the bounds [from, to) of the period of a bound report request in calendar.
+type reportPeriod func(calendar *property.Calendar) (time.Time, time.Time, error)
+
+type GetPeriodReportReq struct {
+	ReportReq
+	// From and To bound the period [from, to), as RFC 3339 timestamps or YYYY-MM-DD days of Timezone.
+	From string `query:"from" validate:"required"`
+	To   string `query:"to" validate:"required"`
+}
+
+type GetQuarterlyReportReq struct {
+	ReportReq
+	Quarter int `query:"quarter" validate:"required,min=1,max=4"`
+	Year    int `query:"year" validate:"gte=1970,lte=2030"`
+}
+
+type GetYearlyReportReq struct {
+	ReportReq
+	Year int `query:"year" validate:"gte=1970,lte=2030"`
+}
+
+type GetPeriodReportRes struct {
+	From            time.Time      `json:"from"`
+	To              time.Time      `json:"to"`
+	StartingBalance *GetBalanceRes `json:"starting_balance"`
+	EndingBalance   *GetBalanceRes `json:"ending_balance"`
+	// Income and Expense are the totals of the whole period, converted to the base currency.
+	Income            property.Money            `json:"income"`
+	Expense           property.Money            `json:"expense"`
+	IncomeByCurrency  map[string]property.Money `json:"income_by_currency"`
+	ExpenseByCurrency map[string]property.Money `json:"expense_by_currency"`
+	// Net is the net change of the whole period, Income plus Expense.
+	Net           property.Money            `json:"net"`
+	NetByCurrency map[string]property.Money `json:"net_by_currency"`
+	// EventCount is the number of events in the whole period, across every page.
+	EventCount int64          `json:"event_count"`
+	Events     []*ReportEvent `json:"events"`
+	NextToken  string         `json:"next_token"`
+}
+
+func (h *RestHandler) GetPeriodReport(c echo.Context) error {
+	req := &GetPeriodReportReq{}
+	return h.getReport(c, req, func(calendar *property.Calendar) (time.Time, time.Time, error) {
+		from, _, err := parseDate(req.From, calendar)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to, _, err := parseDate(req.To, calendar)
+		return from, to, err
+	})
+}
+
+func (h *RestHandler) GetQuarterlyReport(c echo.Context) error {
+	req := &GetQuarterlyReportReq{}
+	return h.getReport(c, req, func(calendar *property.Calendar) (time.Time, time.Time, error) {
+		from, to, err := calendar.Quarter(req.Quarter, req.Year)
+		if err != nil {
+			return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return from, to, nil
+	})
+}
+
+func (h *RestHandler) GetYearlyReport(c echo.Context) error {
+	req := &GetYearlyReportReq{}
+	return h.getReport(c, req, func(calendar *property.Calendar) (time.Time, time.Time, error) {
+		from, to := calendar.Year(req.Year)
+		return from, to, nil
+	})
+}
+
+// getReport binds and validates req, and responds with the report of the period that period
+// resolves it to in the calendar of the property: a page of it, or the whole of it as an export file.
+func (h *RestHandler) getReport(c echo.Context, req reportRequest, period reportPeriod) error {
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	r := req.reportReq()
+	if r.NextToken != "" {
+		token, err := rest.DecodeNextToken(r.NextToken)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		r.Limit = token.Limit
+		r.Offset = token.Offset
+	}
+
+	calendar, err := h.getCalendar(r.PropertyID, r.Timezone)
+	if err != nil {
+		return err
+	}
+	from, to, err := period(calendar)
+	if err != nil {
+		return err
+	}
+	if format := exportFormat(c, r.Format); format != "" {
+		return h.exportPeriodReport(c, format, r.PropertyID, from, to, r.ExcludeTransfers, calendar)
+	}
+	report, err := h.PropertyHandler.GetPeriodReport(context.Background(), r.PropertyID, from, to, r.ExcludeTransfers, r.Offset, r.Limit)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return respondPeriodReport(c, report, r.Offset, r.Limit)
+}
+
+// respondPeriodReport writes report, with a next token for the page following [offset, offset+limit).
+func respondPeriodReport(c echo.Context, report *property.PeriodReport, offset int, limit int) error {
+	res := &GetPeriodReportRes{
+		From:              report.From,
+		To:                report.To,
+		StartingBalance:   newGetBalanceRes(report.StartingBalance),
+		EndingBalance:     newGetBalanceRes(report.EndingBalance),
+		Income:            report.IncomeTotal,
+		Expense:           report.ExpenseTotal,
+		IncomeByCurrency:  report.Income,
+		ExpenseByCurrency: report.Expense,
+		Net:               report.NetTotal,
+		NetByCurrency:     report.Net,
+		EventCount:        report.EventCount,
+		Events:            newReportEvents(report.Events),
+	}
+
+	if len(report.Events) >= limit {
+		nextToken, err := rest.CreateNextToken(limit, offset+limit)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		res.NextToken = nextToken
+	}
+
+	return c.JSON(200, res)
+}
+
+func newReportEvents(events []*property.Event) []*ReportEvent {
+	return lo.Map(events, func(e *property.Event, _ int) *ReportEvent {
+		return &ReportEvent{
+			ID:          e.ID,
+			PropertyID:  e.PropertyID,
+			EventAmount: e.EventAmount,
+			Currency:    e.Currency,
+			Date:        e.Date,
+			Balance:     e.PostEventBalance,
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package property
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type ReverseEventReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	EventID    string `param:"eventID" validate:"required"`
+	Reason     string `json:"reason" validate:"max=500"`
+}
+
+type ReverseEventRes struct {
+	// Event is the compensating event.
+	Event *Event `json:"event"`
+}
+
+func (h *RestHandler) ReverseEvent(c echo.Context) error {
+	req := &ReverseEventReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	compensating, err := h.PropertyHandler.ReverseEvent(context.Background(), req.PropertyID, req.EventID, req.Reason)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, &ReverseEventRes{Event: newEvent(compensating)})
+}
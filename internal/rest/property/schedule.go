@@ -0,0 +1,187 @@
+package property
+
+import (
+	"context"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"net/http"
+	"time"
+)
+
+type ScheduleReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	// ScheduleID is only set when updating a schedule.
+	ScheduleID string `param:"scheduleID"`
+	// Amount is a decimal string, such as "-120.50".
+	Amount property.Money `json:"amount"`
+	// Currency is an ISO-4217 code, and defaults to the base currency.
+	Currency    string   `json:"currency" validate:"omitempty,len=3,alpha"`
+	Description string   `json:"description" validate:"max=500"`
+	Category    string   `json:"category" validate:"omitempty,category,ne=transfer"`
+	Tags        []string `json:"tags"`
+	Frequency   string   `json:"frequency" validate:"required,oneof=daily weekly monthly yearly"`
+	// Interval is the number of periods between occurrences, and defaults to 1.
+	Interval int `json:"interval" validate:"omitempty,gt=0"`
+	// Start is the date of the first occurrence.
+	Start time.Time `json:"start"`
+	// End is optional.
+	End time.Time `json:"end"`
+}
+
+type Schedule struct {
+	ID             string         `json:"id"`
+	PropertyID     string         `json:"property_id"`
+	Amount         property.Money `json:"amount"`
+	Currency       string         `json:"currency"`
+	Description    string         `json:"description,omitempty"`
+	Category       string         `json:"category,omitempty"`
+	Tags           []string       `json:"tags,omitempty"`
+	Frequency      string         `json:"frequency"`
+	Interval       int            `json:"interval"`
+	Start          time.Time      `json:"start"`
+	End            *time.Time     `json:"end,omitempty"`
+	NextOccurrence *time.Time     `json:"next_occurrence,omitempty"`
+	// Skipped are the occurrences rejected when they fell due, which were not saved.
+	Skipped []*property.SkippedOccurrence `json:"skipped,omitempty"`
+}
+
+func newSchedule(s *property.Schedule) *Schedule {
+	schedule := &Schedule{
+		ID:          s.ID,
+		PropertyID:  s.PropertyID,
+		Amount:      s.EventAmount,
+		Currency:    s.Currency,
+		Description: s.Description,
+		Category:    string(s.Category),
+		Tags:        s.Tags,
+		Frequency:   string(s.Frequency),
+		Interval:    s.Interval,
+		Start:       s.Start,
+		Skipped:     s.Skipped,
+	}
+	if !s.End.IsZero() {
+		schedule.End = &s.End
+	}
+	if !s.Done {
+		schedule.NextOccurrence = &s.NextOccurrence
+	}
+	return schedule
+}
+
+type GetSchedulesRes struct {
+	Schedules []*Schedule `json:"schedules"`
+}
+
+type ScheduleIDReq struct {
+	PropertyID string `param:"propertyID" validate:"required"`
+	ScheduleID string `param:"scheduleID" validate:"required"`
+}
+
+func (h *RestHandler) bindSchedule(c echo.Context) (*property.Schedule, error) {
+	req := &ScheduleReq{}
+	if err := c.Bind(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if req.Amount.IsZero() {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "amount is required")
+	}
+	if req.Start.IsZero() {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "start is required")
+	}
+
+	return &property.Schedule{
+		ID:          req.ScheduleID,
+		PropertyID:  req.PropertyID,
+		EventAmount: req.Amount,
+		Currency:    req.Currency,
+		Description: req.Description,
+		Category:    property.Category(req.Category),
+		Tags:        req.Tags,
+		Frequency:   property.Frequency(req.Frequency),
+		Interval:    req.Interval,
+		Start:       req.Start,
+		End:         req.End,
+	}, nil
+}
+
+func (h *RestHandler) CreateSchedule(c echo.Context) error {
+	schedule, err := h.bindSchedule(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Scheduler.CreateSchedule(context.Background(), schedule); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, newSchedule(schedule))
+}
+
+func (h *RestHandler) UpdateSchedule(c echo.Context) error {
+	schedule, err := h.bindSchedule(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Scheduler.UpdateSchedule(context.Background(), schedule); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, newSchedule(schedule))
+}
+
+func (h *RestHandler) GetSchedules(c echo.Context) error {
+	propertyID := c.Param("propertyID")
+	if propertyID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "property ID is required")
+	}
+	schedules, err := h.Scheduler.GetSchedules(context.Background(), propertyID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, &GetSchedulesRes{Schedules: lo.Map(schedules, func(s *property.Schedule, _ int) *Schedule {
+		return newSchedule(s)
+	})})
+}
+
+func (h *RestHandler) GetSchedule(c echo.Context) error {
+	req := &ScheduleIDReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	schedule, err := h.Scheduler.GetSchedule(context.Background(), req.PropertyID, req.ScheduleID)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, newSchedule(schedule))
+}
+
+func (h *RestHandler) DeleteSchedule(c echo.Context) error {
+	req := &ScheduleIDReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.Scheduler.DeleteSchedule(context.Background(), req.PropertyID, req.ScheduleID); err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
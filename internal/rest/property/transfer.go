@@ -0,0 +1,53 @@
+package property
+
+import (
+	"context"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+type TransferReq struct {
+	FromPropertyID string `json:"from_property_id" validate:"required"`
+	ToPropertyID   string `json:"to_property_id" validate:"required,nefield=FromPropertyID"`
+	// Amount is a positive decimal string, in the base currency.
+	Amount property.Money `json:"amount"`
+	// Date defaults to now.
+	Date time.Time `json:"date"`
+}
+
+type TransferRes struct {
+	TransferID string `json:"transfer_id"`
+	From       *Event `json:"from"`
+	To         *Event `json:"to"`
+}
+
+func (h *RestHandler) Transfer(c echo.Context) error {
+	req := &TransferReq{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if !req.Amount.IsPositive() {
+		return echo.NewHTTPError(http.StatusBadRequest, "amount must be positive")
+	}
+	if req.Date.IsZero() {
+		req.Date = time.Now()
+	}
+
+	transfer, err := h.PropertyHandler.Transfer(context.Background(), req.FromPropertyID, req.ToPropertyID, req.Amount, req.Date)
+	if err != nil {
+		return toHTTPError(err)
+	}
+
+	return c.JSON(200, &TransferRes{
+		TransferID: transfer.ID,
+		From:       newEvent(transfer.From),
+		To:         newEvent(transfer.To),
+	})
+}
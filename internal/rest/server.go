@@ -1,7 +1,6 @@
 package rest
 
 import (
-	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
 	"github.com/samber/lo"
 )
@@ -9,7 +8,7 @@ import (
 func NewServer(handlerRegisters ...func(e *echo.Echo) *echo.Echo) *echo.Echo {
 	e := echo.New()
 	e.Debug = true
-	e.Validator = &CustomValidator{validator: validator.New()}
+	e.Validator = NewCustomValidator()
 
 	lo.ForEach(handlerRegisters, func(handler func(e *echo.Echo) *echo.Echo, _ int) {
 		handler(e)
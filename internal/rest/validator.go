@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"github.com/chn555/property-service/pkg/property"
 	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
 	"net/http"
@@ -10,6 +11,17 @@ type CustomValidator struct {
 	validator *validator.Validate
 }
 
+// NewCustomValidator returns a validator that also knows the category tag, which accepts the
+// categories the property package lists.
+func NewCustomValidator() *CustomValidator {
+	v := validator.New()
+	// RegisterValidation only fails for an empty tag or function
+	_ = v.RegisterValidation("category", func(fl validator.FieldLevel) bool {
+		return property.Category(fl.Field().String()).IsValid()
+	})
+	return &CustomValidator{validator: v}
+}
+
 func (cv *CustomValidator) Validate(i interface{}) error {
 	if err := cv.validator.Struct(i); err != nil {
 		// Optionally, you could return the error to give each route more control over the status code
@@ -3,8 +3,11 @@ package main
 import (
 	"context"
 	"github.com/chn555/property-service/internal/rest/property"
+	"github.com/chn555/property-service/pkg/blob"
 	"github.com/chn555/property-service/pkg/db/mongo"
+	"github.com/chn555/property-service/pkg/fx"
 	property2 "github.com/chn555/property-service/pkg/property"
+	"github.com/chn555/property-service/pkg/webhook"
 	"log/slog"
 
 	"github.com/chn555/property-service/internal/config"
@@ -14,11 +17,15 @@ import (
 
 func main() {
 
-	cfg, err := config.LoadConfig(context.Background())
+	cfg, command, err := config.LoadConfig(context.Background(), os.Args[1:])
 	if err != nil {
 		slog.Error("failed to load config", slog.String("err", err.Error()))
 		os.Exit(1)
 	}
+	if len(command) > 0 && command[0] != "import" {
+		slog.Error("unknown command", slog.String("command", command[0]))
+		os.Exit(1)
+	}
 
 	mongoClient, err := mongo.NewClient(context.TODO(), cfg.MongoConfig)
 	if err != nil {
@@ -27,9 +34,86 @@ func main() {
 	}
 
 	con := mongo.NewEventState(mongoClient, cfg.MongoEventStateConfig)
+	if err := con.Migrate(context.TODO(), cfg.FXConfig.BaseCurrency); err != nil {
+		slog.Error("failed to migrate events", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+	if err := con.EnsureIndexes(context.TODO()); err != nil {
+		slog.Error("failed to create mongo indexes", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	properties := mongo.NewPropertyState(mongoClient, cfg.MongoPropertyStateConfig)
+	if err := properties.EnsureIndexes(context.TODO()); err != nil {
+		slog.Error("failed to create mongo indexes", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	schedules := mongo.NewScheduleState(mongoClient, cfg.MongoScheduleStateConfig)
+	if err := schedules.EnsureIndexes(context.TODO()); err != nil {
+		slog.Error("failed to create mongo indexes", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	budgets := mongo.NewBudgetState(mongoClient, cfg.MongoBudgetStateConfig)
+	if err := budgets.EnsureIndexes(context.TODO()); err != nil {
+		slog.Error("failed to create mongo indexes", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	alertState := mongo.NewAlertState(mongoClient, cfg.MongoAlertStateConfig)
+	if err := alertState.EnsureIndexes(context.TODO()); err != nil {
+		slog.Error("failed to create mongo indexes", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	reconciliationState := mongo.NewReconciliationState(mongoClient, cfg.MongoReconciliationStateConfig)
+	if err := reconciliationState.EnsureIndexes(context.TODO()); err != nil {
+		slog.Error("failed to create mongo indexes", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	attachmentState := mongo.NewAttachmentState(mongoClient, cfg.MongoAttachmentStateConfig)
+	if err := attachmentState.EnsureIndexes(context.TODO()); err != nil {
+		slog.Error("failed to create mongo indexes", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	blobs, err := blob.NewLocal(cfg.BlobConfig)
+	if err != nil {
+		slog.Error("failed to open blob storage", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	rates, err := fx.NewProvider(cfg.FXConfig)
+	if err != nil {
+		slog.Error("failed to load exchange rates", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	propertyHandler := property2.NewHandler(con, properties, rates, cfg.FXConfig.BaseCurrency)
+	alerts := property2.NewAlerts(propertyHandler, alertState, webhook.NewSender(cfg.WebhookConfig), property2.SystemClock{}, cfg.AlertsConfig)
+	propertyHandler.Observe(alerts)
+
+	if len(command) > 0 {
+		// the alerts of the imported events are delivered by the server
+		if err := runImport(context.Background(), propertyHandler, command[1:], os.Stdout); err != nil {
+			slog.Error("failed to import statement", slog.String("err", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	scheduler := property2.NewScheduler(propertyHandler, schedules, property2.SystemClock{})
+	go scheduler.Run(context.Background(), cfg.SchedulerConfig.Interval)
+	go alerts.Run(context.Background(), cfg.AlertsConfig.Interval)
+	portfolioHandler := property2.NewPortfolioHandler(propertyHandler, mongo.NewPortfolioState(mongoClient, cfg.MongoPortfolioStateConfig))
+	budgetHandler := property2.NewBudgets(propertyHandler, budgets, property2.SystemClock{})
+	reconciliations := property2.NewReconciliations(propertyHandler, reconciliationState, property2.SystemClock{})
+	attachments := property2.NewAttachments(propertyHandler, attachmentState, blobs, property2.SystemClock{}, cfg.AttachmentsConfig)
 
 	e := rest.NewServer(
-		property.NewRestHandler(property2.NewHandler(con)).RegisterHandlers,
+		property.NewRestHandler(propertyHandler, scheduler, portfolioHandler, budgetHandler, alerts, reconciliations, attachments).RegisterHandlers,
 	)
 
 	if err := e.Start(":1323"); err != nil {
@@ -0,0 +1,101 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+// Config holds the configuration for storing blobs
+type Config struct {
+	// Dir is the directory blobs are stored in, created if missing
+	Dir string `validate:"required"`
+}
+
+// Local is a property.BlobStore keeping every blob in a file of a local directory, named by its key.
+// A blob is written to a temporary file first, so it is never read partially written.
+type Local struct {
+	dir string
+}
+
+func NewLocal(config Config) (*Local, error) {
+	if err := os.MkdirAll(config.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create blob directory: %w", err)
+	}
+	return &Local{dir: config.Dir}, nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, r io.Reader) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(name), ".put-*")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", key, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync %s: %w", key, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", key, err)
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		return fmt.Errorf("rename %s: %w", key, err)
+	}
+	return nil
+}
+
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", key, property.ErrBlobNotFound)
+		}
+		return nil, fmt.Errorf("open %s: %w", key, err)
+	}
+	return f, nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove %s: %w", key, err)
+	}
+	return nil
+}
+
+// path returns the name of the file of the blob under key, refusing keys that would lead out of
+// the directory.
+func (l *Local) path(key string) (string, error) {
+	key = filepath.FromSlash(key)
+	if !filepath.IsLocal(key) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(l.dir, key), nil
+}
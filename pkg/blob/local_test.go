@@ -0,0 +1,78 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+func TestLocal(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "blobs")
+	l, err := NewLocal(Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewLocal() unexpected error = %v", err)
+	}
+	ctx := context.TODO()
+
+	if err := l.Put(ctx, "elm/receipt", strings.NewReader("first")); err != nil {
+		t.Fatalf("Put() unexpected error = %v", err)
+	}
+	if err := l.Put(ctx, "elm/receipt", strings.NewReader("second")); err != nil {
+		t.Fatalf("Put() unexpected error = %v", err)
+	}
+	if got := readBlob(t, l, "elm/receipt"); got != "second" {
+		t.Errorf("Open() got = %q, want %q", got, "second")
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "elm"))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Put() left %d files, want only the blob", len(entries))
+	}
+
+	if err := l.Delete(ctx, "elm/receipt"); err != nil {
+		t.Fatalf("Delete() unexpected error = %v", err)
+	}
+	if _, err := l.Open(ctx, "elm/receipt"); !errors.Is(err, property.ErrBlobNotFound) {
+		t.Errorf("Open() error = %v, want %v", err, property.ErrBlobNotFound)
+	}
+	if err := l.Delete(ctx, "elm/receipt"); err != nil {
+		t.Errorf("Delete() of a missing blob error = %v, want nil", err)
+	}
+}
+
+func TestLocal_InvalidKey(t *testing.T) {
+	l, err := NewLocal(Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewLocal() unexpected error = %v", err)
+	}
+
+	for _, key := range []string{"", "../escape", "/absolute", "elm/../../escape"} {
+		t.Run(key, func(t *testing.T) {
+			if err := l.Put(context.TODO(), key, strings.NewReader("content")); err == nil {
+				t.Errorf("Put() error = nil, want an invalid key error")
+			}
+			if _, err := l.Open(context.TODO(), key); err == nil || errors.Is(err, property.ErrBlobNotFound) {
+				t.Errorf("Open() error = %v, want an invalid key error", err)
+			}
+		})
+	}
+}
+
+func readBlob(t *testing.T, l *Local, key string) string {
+	t.Helper()
+	r, err := l.Open(context.TODO(), key)
+	if err != nil {
+		t.Fatalf("Open() unexpected error = %v", err)
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() unexpected error = %v", err)
+	}
+	return string(content)
+}
@@ -0,0 +1,196 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chn555/property-service/pkg/property"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type AlertState struct {
+	rules      *mongo.Collection
+	webhooks   *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+type AlertStateConfig struct {
+	DatabaseName             string
+	RulesCollectionName      string
+	WebhooksCollectionName   string
+	DeliveriesCollectionName string
+}
+
+func NewAlertState(client *mongo.Client, config AlertStateConfig) *AlertState {
+	database := client.Database(config.DatabaseName)
+	return &AlertState{
+		rules:      database.Collection(config.RulesCollectionName),
+		webhooks:   database.Collection(config.WebhooksCollectionName),
+		deliveries: database.Collection(config.DeliveriesCollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes the alert state relies on.
+func (a *AlertState) EnsureIndexes(ctx context.Context) error {
+	if _, err := a.rules.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "property_id", Value: 1}},
+	}); err != nil {
+		return fmt.Errorf("create indexes: %w", err)
+	}
+	_, err := a.deliveries.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: -1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create indexes: %w", err)
+	}
+	return nil
+}
+
+func (a *AlertState) CreateAlertRule(ctx context.Context, rule *property.AlertRule) error {
+	rule.ID = primitive.NewObjectID().Hex()
+	if _, err := a.rules.InsertOne(ctx, rule); err != nil {
+		return fmt.Errorf("insert one: %w", err)
+	}
+	return nil
+}
+
+func (a *AlertState) GetAlertRule(ctx context.Context, propertyID string, ruleID string) (*property.AlertRule, bool, error) {
+	rule := &property.AlertRule{}
+	err := a.rules.FindOne(ctx, alertRuleFilter(propertyID, ruleID)).Decode(rule)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("find: %w", err)
+	}
+
+	return rule, true, nil
+}
+
+func (a *AlertState) GetAlertRules(ctx context.Context, propertyID string) ([]*property.AlertRule, error) {
+	cursor, err := a.rules.Find(ctx, bson.D{{Key: "property_id", Value: propertyID}})
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	var rules []*property.AlertRule
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+	return rules, nil
+}
+
+func (a *AlertState) DeleteAlertRule(ctx context.Context, propertyID string, ruleID string) (bool, error) {
+	res, err := a.rules.DeleteOne(ctx, alertRuleFilter(propertyID, ruleID))
+	if err != nil {
+		return false, fmt.Errorf("delete one: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (a *AlertState) CreateWebhook(ctx context.Context, webhook *property.Webhook) error {
+	webhook.ID = primitive.NewObjectID().Hex()
+	if _, err := a.webhooks.InsertOne(ctx, webhook); err != nil {
+		return fmt.Errorf("insert one: %w", err)
+	}
+	return nil
+}
+
+func (a *AlertState) GetWebhooks(ctx context.Context) ([]*property.Webhook, error) {
+	cursor, err := a.webhooks.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	var webhooks []*property.Webhook
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (a *AlertState) DeleteWebhook(ctx context.Context, webhookID string) (bool, error) {
+	res, err := a.webhooks.DeleteOne(ctx, bson.D{{Key: "_id", Value: webhookID}})
+	if err != nil {
+		return false, fmt.Errorf("delete one: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (a *AlertState) CreateDeliveries(ctx context.Context, deliveries []*property.Delivery) error {
+	for _, d := range deliveries {
+		d.ID = primitive.NewObjectID().Hex()
+	}
+	if _, err := a.deliveries.InsertMany(ctx, lo.ToAnySlice(deliveries)); err != nil {
+		return fmt.Errorf("insert many: %w", err)
+	}
+	return nil
+}
+
+func (a *AlertState) UpdateDelivery(ctx context.Context, delivery *property.Delivery) error {
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "status", Value: delivery.Status},
+		{Key: "attempts", Value: delivery.Attempts},
+		{Key: "next_attempt", Value: delivery.NextAttempt},
+		{Key: "last_error", Value: delivery.LastError},
+		{Key: "status_code", Value: delivery.StatusCode},
+		{Key: "delivered_at", Value: delivery.DeliveredAt},
+	}}}
+	if _, err := a.deliveries.UpdateByID(ctx, delivery.ID, update); err != nil {
+		return fmt.Errorf("update one: %w", err)
+	}
+	return nil
+}
+
+func (a *AlertState) GetDueDeliveries(ctx context.Context, date time.Time) ([]*property.Delivery, error) {
+	filter := bson.D{
+		{Key: "status", Value: property.DeliveryPending},
+		{Key: "next_attempt", Value: bson.D{{Key: "$lte", Value: date}}},
+	}
+	return a.findDeliveries(ctx, filter, options.Find().SetSort(bson.D{{Key: "next_attempt", Value: 1}}))
+}
+
+func (a *AlertState) GetDeliveries(ctx context.Context, filter *property.DeliveryFilter, limit int, offset int) ([]*property.Delivery, error) {
+	f := bson.D{}
+	if filter.WebhookID != "" {
+		f = append(f, bson.E{Key: "webhook_id", Value: filter.WebhookID})
+	}
+	if filter.PropertyID != "" {
+		f = append(f, bson.E{Key: "alert.property_id", Value: filter.PropertyID})
+	}
+	if filter.Status != "" {
+		f = append(f, bson.E{Key: "status", Value: filter.Status})
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+	return a.findDeliveries(ctx, f, opts)
+}
+
+func (a *AlertState) findDeliveries(ctx context.Context, filter bson.D, opts *options.FindOptions) ([]*property.Delivery, error) {
+	cursor, err := a.deliveries.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	var deliveries []*property.Delivery
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+	return deliveries, nil
+}
+
+func alertRuleFilter(propertyID string, ruleID string) bson.D {
+	return bson.D{{Key: "_id", Value: ruleID}, {Key: "property_id", Value: propertyID}}
+}
@@ -0,0 +1,89 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chn555/property-service/pkg/property"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AttachmentState struct {
+	collection *mongo.Collection
+}
+
+type AttachmentStateConfig struct {
+	DatabaseName   string
+	CollectionName string
+}
+
+func NewAttachmentState(client *mongo.Client, config AttachmentStateConfig) *AttachmentState {
+	return &AttachmentState{
+		collection: client.Database(config.DatabaseName).Collection(config.CollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes the attachment state relies on.
+func (a *AttachmentState) EnsureIndexes(ctx context.Context) error {
+	_, err := a.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "property_id", Value: 1}, {Key: "event_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("create indexes: %w", err)
+	}
+	return nil
+}
+
+func (a *AttachmentState) CreateAttachment(ctx context.Context, attachment *property.Attachment) error {
+	attachment.ID = primitive.NewObjectID().Hex()
+	if _, err := a.collection.InsertOne(ctx, attachment); err != nil {
+		return fmt.Errorf("insert one: %w", err)
+	}
+	return nil
+}
+
+func (a *AttachmentState) GetAttachment(ctx context.Context, propertyID string, attachmentID string) (*property.Attachment, bool, error) {
+	attachment := &property.Attachment{}
+	err := a.collection.FindOne(ctx, attachmentFilter(propertyID, attachmentID)).Decode(attachment)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("find: %w", err)
+	}
+
+	return attachment, true, nil
+}
+
+func (a *AttachmentState) GetAttachments(ctx context.Context, propertyID string, eventIDs []string) ([]*property.Attachment, error) {
+	filter := bson.D{
+		{Key: "property_id", Value: propertyID},
+		{Key: "event_id", Value: bson.D{{Key: "$in", Value: eventIDs}}},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := a.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	var attachments []*property.Attachment
+	if err := cursor.All(ctx, &attachments); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+	return attachments, nil
+}
+
+func (a *AttachmentState) DeleteAttachment(ctx context.Context, propertyID string, attachmentID string) (bool, error) {
+	res, err := a.collection.DeleteOne(ctx, attachmentFilter(propertyID, attachmentID))
+	if err != nil {
+		return false, fmt.Errorf("delete one: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func attachmentFilter(propertyID string, attachmentID string) bson.D {
+	return bson.D{{Key: "_id", Value: attachmentID}, {Key: "property_id", Value: propertyID}}
+}
@@ -0,0 +1,108 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chn555/property-service/pkg/property"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BudgetState struct {
+	collection *mongo.Collection
+}
+
+type BudgetStateConfig struct {
+	DatabaseName   string
+	CollectionName string
+}
+
+func NewBudgetState(client *mongo.Client, config BudgetStateConfig) *BudgetState {
+	return &BudgetState{
+		collection: client.Database(config.DatabaseName).Collection(config.CollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes the budget state relies on, including the unique index
+// allowing a single budget per property, year, category and currency.
+func (b *BudgetState) EnsureIndexes(ctx context.Context) error {
+	_, err := b.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "property_id", Value: 1},
+			{Key: "year", Value: 1},
+			{Key: "category", Value: 1},
+			{Key: "currency", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("create indexes: %w", err)
+	}
+	return nil
+}
+
+func (b *BudgetState) CreateBudget(ctx context.Context, budget *property.Budget) error {
+	budget.ID = primitive.NewObjectID().Hex()
+	if _, err := b.collection.InsertOne(ctx, budget); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return property.ErrBudgetExists
+		}
+		return fmt.Errorf("insert one: %w", err)
+	}
+	return nil
+}
+
+func (b *BudgetState) UpdateBudget(ctx context.Context, budget *property.Budget) (bool, error) {
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "amount", Value: budget.Amount}}}}
+	res, err := b.collection.UpdateOne(ctx, budgetFilter(budget.PropertyID, budget.ID), update)
+	if err != nil {
+		return false, fmt.Errorf("update one: %w", err)
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (b *BudgetState) GetBudget(ctx context.Context, propertyID string, budgetID string) (*property.Budget, bool, error) {
+	budget := &property.Budget{}
+	err := b.collection.FindOne(ctx, budgetFilter(propertyID, budgetID)).Decode(budget)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("find: %w", err)
+	}
+
+	return budget, true, nil
+}
+
+func (b *BudgetState) GetBudgets(ctx context.Context, propertyID string, year int) ([]*property.Budget, error) {
+	filter := bson.D{{Key: "property_id", Value: propertyID}}
+	if year != 0 {
+		filter = append(filter, bson.E{Key: "year", Value: year})
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "year", Value: 1}, {Key: "category", Value: 1}, {Key: "currency", Value: 1}})
+	cursor, err := b.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	var budgets []*property.Budget
+	if err := cursor.All(ctx, &budgets); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+	return budgets, nil
+}
+
+func (b *BudgetState) DeleteBudget(ctx context.Context, propertyID string, budgetID string) (bool, error) {
+	res, err := b.collection.DeleteOne(ctx, budgetFilter(propertyID, budgetID))
+	if err != nil {
+		return false, fmt.Errorf("delete one: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func budgetFilter(propertyID string, budgetID string) bson.D {
+	return bson.D{{Key: "_id", Value: budgetID}, {Key: "property_id", Value: propertyID}}
+}
@@ -17,7 +17,7 @@ type Config struct {
 
 // NewClient creates a new MongoDB client
 func NewClient(ctx context.Context, config Config) (*mongo.Client, error) {
-	clientOptions := options.Client().ApplyURI(config.URI)
+	clientOptions := options.Client().ApplyURI(config.URI).SetRegistry(newRegistry())
 
 	connectCtx, cancel := context.WithTimeout(ctx, config.Timeout)
 	defer cancel()
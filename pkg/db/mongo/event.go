@@ -7,28 +7,46 @@ import (
 	"github.com/aaydin-tr/kyte"
 	"github.com/chn555/property-service/pkg/property"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
 )
 
 type EventState struct {
 	client     *mongo.Client
 	database   *mongo.Database
 	collection *mongo.Collection
+	// revisions holds the prior versions of amended events.
+	revisions *mongo.Collection
+	// ledgers holds the head of each property ledger, which every ledger write moves.
+	ledgers *mongo.Collection
 }
 
 type EventStateConfig struct {
-	DatabaseName   string
-	CollectionName string
+	DatabaseName            string
+	CollectionName          string
+	RevisionsCollectionName string
+	LedgersCollectionName   string
 }
 
 func NewEventState(client *mongo.Client, config EventStateConfig) *EventState {
 	database := client.Database(config.DatabaseName)
 	collection := database.Collection(config.CollectionName)
+	revisionsCollectionName := config.RevisionsCollectionName
+	if revisionsCollectionName == "" {
+		revisionsCollectionName = config.CollectionName + "_revisions"
+	}
+	ledgersCollectionName := config.LedgersCollectionName
+	if ledgersCollectionName == "" {
+		ledgersCollectionName = config.CollectionName + "_ledgers"
+	}
 	return &EventState{
 		client:     client,
 		database:   database,
 		collection: collection,
+		revisions:  database.Collection(revisionsCollectionName),
+		ledgers:    database.Collection(ledgersCollectionName),
 	}
 }
 
@@ -36,15 +54,229 @@ func (e *EventState) Close(ctx context.Context) error {
 	return e.client.Disconnect(ctx)
 }
 
-func (e *EventState) SaveEvent(ctx context.Context, event *property.Event) error {
-	_, err := e.collection.InsertOne(ctx, event)
+// EnsureIndexes creates the indexes the event state relies on.
+// The unique ledger sequence index is what makes WriteLedger a conditional write, and needs
+// Migrate to have numbered the events saved before ledgers had sequences.
+func (e *EventState) EnsureIndexes(ctx context.Context) error {
+	_, err := e.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "property_id", Value: 1}, {Key: "sequence", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "property_id", Value: 1}, {Key: "date", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "property_id", Value: 1}, {Key: "idempotency_key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(
+				bson.D{{Key: "idempotency_key", Value: bson.D{{Key: "$exists", Value: true}}}},
+			),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create indexes: %w", err)
+	}
+
+	_, err = e.revisions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "property_id", Value: 1}, {Key: "event_id", Value: 1}, {Key: "revision", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
 	if err != nil {
+		return fmt.Errorf("create revision indexes: %w", err)
+	}
+	return nil
+}
+
+// WriteLedger applies write in a transaction.
+// Inserted events get the sequences following write.Head, so two concurrent writes
+// computed from the same head would insert the same sequence, and the unique index
+// rejects all but the first of them. The idempotency key index rejects replays the same way.
+// Every write also moves the head document of the ledger from write.Head, which makes writes
+// that insert nothing, such as amendments, conflict with any concurrent write as well.
+func (e *EventState) WriteLedger(ctx context.Context, propertyID string, write *property.LedgerWrite) error {
+	return e.WriteLedgers(ctx, map[string]*property.LedgerWrite{propertyID: write})
+}
+
+// WriteLedgers applies every write in a single transaction, see WriteLedger.
+func (e *EventState) WriteLedgers(ctx context.Context, writes map[string]*property.LedgerWrite) error {
+	session, err := e.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		for propertyID, write := range writes {
+			if err := e.writeLedger(sc, propertyID, write); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return property.ErrConflict
+		}
+		return fmt.Errorf("with transaction: %w", err)
+	}
+	return nil
+}
+
+func (e *EventState) writeLedger(ctx context.Context, propertyID string, write *property.LedgerWrite) error {
+	sequence := int64(0)
+	if write.Head != nil {
+		sequence = write.Head.Sequence
+	}
+	if len(write.Insert) == 0 {
+		// without an insert for the unique index to reject, the head is checked here instead,
+		// for the ledgers whose head document the first write since Migrate has yet to create.
+		// A write committed after the check is caught by moveHead
+		later := bson.D{{Key: "property_id", Value: propertyID}, {Key: "sequence", Value: bson.D{{Key: "$gt", Value: sequence}}}}
+		count, err := e.collection.CountDocuments(ctx, later, options.Count().SetLimit(1))
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return property.ErrConflict
+		}
+	}
+	head := sequence
+	for _, event := range write.Insert {
+		sequence++
+		event.Sequence = sequence
+		event.ID = primitive.NewObjectID().Hex()
+		if _, err := e.collection.InsertOne(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	for _, event := range write.Update {
+		filter := bson.D{{Key: "_id", Value: event.ID}, {Key: "property_id", Value: propertyID}}
+		res, err := e.collection.ReplaceOne(ctx, filter, event)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return property.ErrConflict
+		}
+		sequence = max(sequence, event.Sequence)
+	}
+
+	for _, revision := range write.Revisions {
+		if _, err := e.revisions.InsertOne(ctx, revision); err != nil {
+			return err
+		}
+	}
+	return e.moveHead(ctx, propertyID, head, sequence)
+}
+
+// moveHead moves the head document of the ledger from sequence head to sequence, counting the
+// write so that it changes the document even when the sequence stays, as for an amended
+// description. A concurrent transaction writing the same document fails with a write conflict,
+// and is retried by WithTransaction; a head moved in the meantime then no longer matches the
+// filter, and the upsert fails with a duplicate key error, which is reported as ErrConflict for
+// the write to be computed again.
+func (e *EventState) moveHead(ctx context.Context, propertyID string, head int64, sequence int64) error {
+	filter := bson.D{{Key: "_id", Value: propertyID}, {Key: "sequence", Value: head}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "sequence", Value: sequence}}},
+		{Key: "$inc", Value: bson.D{{Key: "writes", Value: 1}}},
+	}
+	if _, err := e.ledgers.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (e *EventState) GetEventsForFilter(ctx context.Context, filter *property.EventFilter, limit int, offset int) ([]*property.Event, error) {
+func (e *EventState) GetLedgerHead(ctx context.Context, propertyID string) (*property.Event, bool, error) {
+	event := &property.Event{}
+	opts := options.FindOne().SetSort(bson.D{{Key: "sequence", Value: -1}})
+	err := e.collection.FindOne(ctx, bson.D{{Key: "property_id", Value: propertyID}}, opts).Decode(event)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("find: %w", err)
+	}
+
+	return event, true, nil
+}
+
+func (e *EventState) GetEventRevisions(ctx context.Context, propertyID string, eventID string) ([]*property.EventRevision, error) {
+	filter := bson.D{{Key: "property_id", Value: propertyID}, {Key: "event_id", Value: eventID}}
+	opts := options.Find().SetSort(bson.D{{Key: "revision", Value: 1}})
+	cursor, err := e.revisions.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	var revisions []*property.EventRevision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+	return revisions, nil
+}
+
+type ledgerSummaryResult struct {
+	ID struct {
+		PropertyID string `bson:"property_id"`
+		Currency   string `bson:"currency"`
+	} `bson:"_id"`
+	Balance       property.Money `bson:"balance"`
+	LastEventDate time.Time      `bson:"last_event_date"`
+	MonthlyNet    property.Money `bson:"monthly_net"`
+}
+
+// GetLedgerSummaries summarizes every ledger in a single aggregation, taking the most recent
+// event of each property and currency.
+func (e *EventState) GetLedgerSummaries(ctx context.Context, propertyIDs []string, monthStart time.Time, monthEnd time.Time) ([]*property.LedgerSummary, error) {
+	// decimal zero keeps the sum Decimal128 for ledgers without events in the month
+	zero := property.Money{}
+	inMonth := bson.D{{Key: "$and", Value: bson.A{
+		bson.D{{Key: "$gte", Value: bson.A{"$date", monthStart}}},
+		bson.D{{Key: "$lt", Value: bson.A{"$date", monthEnd}}},
+	}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "property_id", Value: bson.D{{Key: "$in", Value: propertyIDs}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: -1}, {Key: "sequence", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "property_id", Value: "$property_id"},
+				{Key: "currency", Value: "$currency"},
+			}},
+			{Key: "balance", Value: bson.D{{Key: "$first", Value: "$post_event_balance"}}},
+			{Key: "last_event_date", Value: bson.D{{Key: "$first", Value: "$date"}}},
+			{Key: "monthly_net", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				inMonth, "$event_amount", zero,
+			}}}}}},
+		}}},
+	}
+	cursor, err := e.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate: %w", err)
+	}
+
+	var results []*ledgerSummaryResult
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+
+	summaries := make([]*property.LedgerSummary, 0, len(results))
+	for _, r := range results {
+		summaries = append(summaries, &property.LedgerSummary{
+			PropertyID:    r.ID.PropertyID,
+			Currency:      r.ID.Currency,
+			Balance:       r.Balance,
+			LastEventDate: r.LastEventDate,
+			MonthlyNet:    r.MonthlyNet,
+		})
+	}
+	return summaries, nil
+}
+
+func (e *EventState) GetEventsForFilter(ctx context.Context, filter *property.EventFilter, sortOrder property.SortOrder, limit int, offset int) ([]*property.Event, error) {
 	if filter == nil {
 		return nil, fmt.Errorf("filter is nil")
 	}
@@ -55,7 +287,16 @@ func (e *EventState) GetEventsForFilter(ctx context.Context, filter *property.Ev
 	}
 
 	var events []*property.Event
-	opts := options.Find().SetLimit(int64(limit)).SetSkip(int64(offset))
+	direction := 1
+	if sortOrder == property.Descending {
+		direction = -1
+	}
+	// pages are only consistent with one another in a stable order. Sequences are only unique
+	// within a property, so _id breaks the ties between the events of several.
+	opts := options.Find().
+		SetSort(bson.D{{Key: "date", Value: direction}, {Key: "sequence", Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
 	cursor, err := e.collection.Find(ctx, mongoFilter, opts)
 	if err != nil {
 		return nil, fmt.Errorf("find: %w", err)
@@ -68,14 +309,62 @@ func (e *EventState) GetEventsForFilter(ctx context.Context, filter *property.Ev
 	return events, nil
 }
 
+func (e *EventState) IterateEvents(ctx context.Context, filter *property.EventFilter, sortOrder property.SortOrder, fn func(*property.Event) error) error {
+	if filter == nil {
+		return fmt.Errorf("filter is nil")
+	}
+
+	mongoFilter, err := buildFilter(filter)
+	if err != nil {
+		return fmt.Errorf("build filter: %w", err)
+	}
+
+	direction := 1
+	if sortOrder == property.Descending {
+		direction = -1
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: direction}, {Key: "sequence", Value: direction}, {Key: "_id", Value: direction}})
+	cursor, err := e.collection.Find(ctx, mongoFilter, opts)
+	if err != nil {
+		return fmt.Errorf("find: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		event := &property.Event{}
+		if err := cursor.Decode(event); err != nil {
+			return fmt.Errorf("decode: %w", err)
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor: %w", err)
+	}
+	return nil
+}
+
 func buildFilter(filter *property.EventFilter) (bson.D, error) {
 	event := &property.Event{}
 	nonEmptyFilter := false
 	filterBuilder := kyte.Filter(kyte.Source(event))
+	if filter.ID != "" {
+		filterBuilder = filterBuilder.Equal(&event.ID, filter.ID)
+		nonEmptyFilter = true
+	}
+	if len(filter.IDs) > 0 {
+		filterBuilder = filterBuilder.In(&event.ID, filter.IDs)
+		nonEmptyFilter = true
+	}
 	if filter.PropertyID != "" {
 		filterBuilder = filterBuilder.Equal(&event.PropertyID, filter.PropertyID)
 		nonEmptyFilter = true
 	}
+	if len(filter.PropertyIDs) > 0 {
+		filterBuilder = filterBuilder.In(&event.PropertyID, filter.PropertyIDs)
+		nonEmptyFilter = true
+	}
 	if !filter.AfterTime.IsZero() {
 		filterBuilder = filterBuilder.GreaterThanOrEqual(&event.Date, filter.AfterTime)
 		nonEmptyFilter = true
@@ -84,6 +373,10 @@ func buildFilter(filter *property.EventFilter) (bson.D, error) {
 		filterBuilder = filterBuilder.LessThanOrEqual(&event.Date, filter.BeforeTime)
 		nonEmptyFilter = true
 	}
+	if !filter.EndTime.IsZero() {
+		filterBuilder = filterBuilder.LessThan(&event.Date, filter.EndTime)
+		nonEmptyFilter = true
+	}
 	if filter.AmountType == property.Expense {
 		filterBuilder = filterBuilder.LessThan(&event.EventAmount, 0)
 		nonEmptyFilter = true
@@ -92,12 +385,40 @@ func buildFilter(filter *property.EventFilter) (bson.D, error) {
 		filterBuilder = filterBuilder.GreaterThan(&event.EventAmount, 0)
 		nonEmptyFilter = true
 	}
+	if filter.Currency != "" {
+		filterBuilder = filterBuilder.Equal(&event.Currency, filter.Currency)
+		nonEmptyFilter = true
+	}
+	if filter.Category != property.Uncategorized {
+		filterBuilder = filterBuilder.Equal(&event.Category, filter.Category)
+		nonEmptyFilter = true
+	}
+	if filter.IdempotencyKey != "" {
+		filterBuilder = filterBuilder.Equal(&event.IdempotencyKey, filter.IdempotencyKey)
+		nonEmptyFilter = true
+	}
 
-	if !nonEmptyFilter {
+	if !nonEmptyFilter && len(filter.Tags) == 0 {
 		return nil, fmt.Errorf("no filter criteria specified")
 	}
 
-	return filterBuilder.Build()
+	mongoFilter, err := filterBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+	if len(filter.Tags) > 0 {
+		mongoFilter = append(mongoFilter, bson.E{Key: "tags", Value: bson.D{{Key: "$all", Value: filter.Tags}}})
+	}
+	if filter.ExcludeReversed {
+		mongoFilter = append(mongoFilter,
+			bson.E{Key: "reversed", Value: bson.D{{Key: "$ne", Value: true}}},
+			bson.E{Key: "reversal_of", Value: bson.D{{Key: "$exists", Value: false}}},
+		)
+	}
+	if filter.ExcludeTransfers {
+		mongoFilter = append(mongoFilter, bson.E{Key: "transfer_id", Value: bson.D{{Key: "$exists", Value: false}}})
+	}
+	return mongoFilter, nil
 }
 
 func (e *EventState) GetMostRecentEventForFilter(ctx context.Context, filter *property.EventFilter) (*property.Event, bool, error) {
@@ -111,7 +432,7 @@ func (e *EventState) GetMostRecentEventForFilter(ctx context.Context, filter *pr
 	}
 
 	event := &property.Event{}
-	opts := options.FindOne().SetSort(bson.M{"date": -1})
+	opts := options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "sequence", Value: -1}})
 	err = e.collection.FindOne(ctx, mongoFilter, opts).Decode(event)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -122,3 +443,96 @@ func (e *EventState) GetMostRecentEventForFilter(ctx context.Context, filter *pr
 
 	return event, true, nil
 }
+
+func (e *EventState) GetMostRecentEventPerCurrency(ctx context.Context, filter *property.EventFilter) ([]*property.Event, error) {
+	if filter == nil {
+		return nil, fmt.Errorf("filter is nil")
+	}
+
+	mongoFilter, err := buildFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("build filter: %w", err)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: mongoFilter}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: -1}, {Key: "sequence", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$currency"},
+			{Key: "event", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}},
+		}}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$event"}}}},
+	}
+	cursor, err := e.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate: %w", err)
+	}
+
+	var events []*property.Event
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+	return events, nil
+}
+
+type categoryTotalResult struct {
+	ID struct {
+		Category property.Category `bson:"category"`
+		Currency string            `bson:"currency"`
+	} `bson:"_id"`
+	Income     property.Money `bson:"income"`
+	Expense    property.Money `bson:"expense"`
+	EventCount int64          `bson:"event_count"`
+}
+
+func (e *EventState) GetCategoryTotals(ctx context.Context, filter *property.EventFilter) ([]*property.CategoryTotal, error) {
+	if filter == nil {
+		return nil, fmt.Errorf("filter is nil")
+	}
+
+	mongoFilter, err := buildFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("build filter: %w", err)
+	}
+
+	// decimal zero keeps the sums Decimal128 even for groups with only income or only expense
+	zero := property.Money{}
+	sumIf := func(operator string) bson.D {
+		return bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: operator, Value: bson.A{"$event_amount", 0}}}, "$event_amount", zero,
+		}}}}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: mongoFilter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "category", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$category", ""}}}},
+				{Key: "currency", Value: "$currency"},
+			}},
+			{Key: "income", Value: sumIf("$gt")},
+			{Key: "expense", Value: sumIf("$lt")},
+			{Key: "event_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+	cursor, err := e.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate: %w", err)
+	}
+
+	var results []*categoryTotalResult
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+
+	totals := make([]*property.CategoryTotal, 0, len(results))
+	for _, r := range results {
+		totals = append(totals, &property.CategoryTotal{
+			Category:   r.ID.Category,
+			Currency:   r.ID.Currency,
+			Income:     r.Income,
+			Expense:    r.Expense,
+			EventCount: r.EventCount,
+		})
+	}
+	return totals, nil
+}
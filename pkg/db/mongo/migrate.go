@@ -0,0 +1,145 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrateBatchSize bounds the updates sent to the database at once while migrating.
+const migrateBatchSize = 1000
+
+// legacyEvent holds the fields of an event the migrations need.
+type legacyEvent struct {
+	ID any `bson:"_id"`
+}
+
+// Migrate brings the events saved by earlier versions of the service up to date, and must run
+// before EnsureIndexes. It only touches events it has not migrated yet, so it runs on every start.
+// Events saved before events had currencies are given baseCurrency.
+func (e *EventState) Migrate(ctx context.Context, baseCurrency string) error {
+	if err := e.migrateIDs(ctx); err != nil {
+		return fmt.Errorf("migrate IDs: %w", err)
+	}
+	if err := e.backfillSequences(ctx); err != nil {
+		return fmt.Errorf("backfill sequences: %w", err)
+	}
+	if err := e.backfillCurrencies(ctx, baseCurrency); err != nil {
+		return fmt.Errorf("backfill currencies: %w", err)
+	}
+	return nil
+}
+
+// backfillCurrencies gives baseCurrency to the events saved before events had currencies, whose
+// running balances were all kept in it. Otherwise their balances could not be converted, and the
+// next event in baseCurrency would start from zero.
+func (e *EventState) backfillCurrencies(ctx context.Context, baseCurrency string) error {
+	missing := bson.D{{Key: "currency", Value: bson.D{{Key: "$in", Value: bson.A{nil, ""}}}}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "currency", Value: baseCurrency}}}}
+	if _, err := e.collection.UpdateMany(ctx, missing, update); err != nil {
+		return fmt.Errorf("update many: %w", err)
+	}
+	return nil
+}
+
+// backfillSequences numbers the events saved before ledgers had sequences, in (date, _id) order
+// per property, following the sequences the property already has. The unique sequence index
+// cannot be created until every event has one.
+func (e *EventState) backfillSequences(ctx context.Context) error {
+	missing := bson.D{{Key: "sequence", Value: nil}}
+	propertyIDs, err := e.collection.Distinct(ctx, "property_id", missing)
+	if err != nil {
+		return fmt.Errorf("distinct: %w", err)
+	}
+
+	for _, v := range propertyIDs {
+		propertyID, ok := v.(string)
+		if !ok {
+			continue
+		}
+		head, exists, err := e.GetLedgerHead(ctx, propertyID)
+		if err != nil {
+			return err
+		}
+		sequence := int64(0)
+		if exists {
+			sequence = head.Sequence
+		}
+
+		filter := append(bson.D{{Key: "property_id", Value: propertyID}}, missing...)
+		opts := options.Find().
+			SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}).
+			SetProjection(bson.D{{Key: "_id", Value: 1}})
+		cursor, err := e.collection.Find(ctx, filter, opts)
+		if err != nil {
+			return fmt.Errorf("find: %w", err)
+		}
+		var events []*legacyEvent
+		if err := cursor.All(ctx, &events); err != nil {
+			return fmt.Errorf("cursor all: %w", err)
+		}
+
+		models := make([]mongo.WriteModel, 0, min(len(events), migrateBatchSize))
+		for i, event := range events {
+			sequence++
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(append(bson.D{{Key: "_id", Value: event.ID}}, missing...)).
+				SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: "sequence", Value: sequence}}}}))
+			if len(models) == migrateBatchSize || i == len(events)-1 {
+				if _, err := e.collection.BulkWrite(ctx, models); err != nil {
+					return fmt.Errorf("bulk write: %w", err)
+				}
+				models = models[:0]
+			}
+		}
+	}
+	return nil
+}
+
+// migrateIDs replaces the ObjectID _id of the events saved before events had IDs of their own
+// with its hex string, which is the ID they are served with and updated by.
+// An _id cannot be changed in place, so each event is deleted and inserted again in a transaction.
+func (e *EventState) migrateIDs(ctx context.Context) error {
+	cursor, err := e.collection.Find(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$type", Value: "objectId"}}}})
+	if err != nil {
+		return fmt.Errorf("find: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	session, err := e.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	for cursor.Next(ctx) {
+		var event bson.D
+		if err := cursor.Decode(&event); err != nil {
+			return fmt.Errorf("decode: %w", err)
+		}
+		var id primitive.ObjectID
+		for i, field := range event {
+			if field.Key == "_id" {
+				id = field.Value.(primitive.ObjectID)
+				event[i].Value = id.Hex()
+			}
+		}
+
+		_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+			if _, err := e.collection.DeleteOne(sc, bson.D{{Key: "_id", Value: id}}); err != nil {
+				return nil, err
+			}
+			return e.collection.InsertOne(sc, event)
+		})
+		if err != nil {
+			return fmt.Errorf("event %s: %w", id.Hex(), err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package mongo
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/chn555/property-service/pkg/property"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var moneyType = reflect.TypeOf(property.Money{})
+
+// newRegistry returns the default bson registry, with property.Money stored losslessly as Decimal128.
+func newRegistry() *bsoncodec.Registry {
+	registry := bson.NewRegistry()
+	registry.RegisterTypeEncoder(moneyType, bsoncodec.ValueEncoderFunc(encodeMoney))
+	registry.RegisterTypeDecoder(moneyType, bsoncodec.ValueDecoderFunc(decodeMoney))
+	return registry
+}
+
+func encodeMoney(_ bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+	if !val.IsValid() || val.Type() != moneyType {
+		return bsoncodec.ValueEncoderError{Name: "encodeMoney", Types: []reflect.Type{moneyType}, Received: val}
+	}
+
+	m := val.Interface().(property.Money)
+	d, ok := primitive.ParseDecimal128FromBigInt(big.NewInt(m.Units()), -property.MoneyScale)
+	if !ok {
+		return fmt.Errorf("convert %v to decimal128", m)
+	}
+	return vw.WriteDecimal128(d)
+}
+
+// decodeMoney decodes Decimal128 values, and the plain numbers events were stored as before
+// property.Money existed.
+func decodeMoney(_ bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	if !val.CanSet() || val.Type() != moneyType {
+		return bsoncodec.ValueDecoderError{Name: "decodeMoney", Types: []reflect.Type{moneyType}, Received: val}
+	}
+
+	var m property.Money
+	var err error
+	switch vr.Type() {
+	case bsontype.Decimal128:
+		var d primitive.Decimal128
+		if d, err = vr.ReadDecimal128(); err != nil {
+			return err
+		}
+		m, err = moneyFromDecimal128(d)
+	case bsontype.Double:
+		var f float64
+		if f, err = vr.ReadDouble(); err != nil {
+			return err
+		}
+		// doubles drift past the scale of Money, as 0.1+0.2 does, so they are rounded to it
+		m, err = property.ParseMoney(strconv.FormatFloat(f, 'f', property.MoneyScale, 64))
+	case bsontype.Int64:
+		var i int64
+		if i, err = vr.ReadInt64(); err != nil {
+			return err
+		}
+		m, err = property.NewMoney(i)
+	case bsontype.Int32:
+		var i int32
+		if i, err = vr.ReadInt32(); err != nil {
+			return err
+		}
+		m, err = property.NewMoney(int64(i))
+	case bsontype.Null:
+		err = vr.ReadNull()
+	default:
+		return fmt.Errorf("cannot decode %v into property.Money", vr.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("decode money: %w", err)
+	}
+
+	val.Set(reflect.ValueOf(m))
+	return nil
+}
+
+func moneyFromDecimal128(d primitive.Decimal128) (property.Money, error) {
+	coefficient, exp, err := d.BigInt()
+	if err != nil {
+		return property.Money{}, err
+	}
+
+	// rescale the coefficient to exactly property.MoneyScale decimal places
+	shift := exp + property.MoneyScale
+	ten := big.NewInt(10)
+	for ; shift > 0; shift-- {
+		coefficient.Mul(coefficient, ten)
+	}
+	remainder := new(big.Int)
+	for ; shift < 0; shift++ {
+		coefficient.QuoRem(coefficient, ten, remainder)
+		if remainder.Sign() != 0 {
+			return property.Money{}, fmt.Errorf("%v has more than %d decimal places", d, property.MoneyScale)
+		}
+	}
+
+	if !coefficient.IsInt64() {
+		return property.Money{}, property.ErrOverflow
+	}
+	return property.MoneyFromUnits(coefficient.Int64()), nil
+}
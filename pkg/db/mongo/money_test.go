@@ -0,0 +1,93 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/chn555/property-service/pkg/property"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type moneyDocument struct {
+	Amount property.Money `bson:"amount"`
+}
+
+func decodeMoneyDocument(t *testing.T, value any) (property.Money, error) {
+	t.Helper()
+	data, err := bson.Marshal(bson.D{{Key: "amount", Value: value}})
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error = %v", err)
+	}
+	decoder, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(data))
+	if err != nil {
+		t.Fatalf("NewDecoder() unexpected error = %v", err)
+	}
+	if err := decoder.SetRegistry(newRegistry()); err != nil {
+		t.Fatalf("SetRegistry() unexpected error = %v", err)
+	}
+	doc := &moneyDocument{}
+	err = decoder.Decode(doc)
+	return doc.Amount, err
+}
+
+func mustDecimal(t *testing.T, s string) primitive.Decimal128 {
+	t.Helper()
+	d, err := primitive.ParseDecimal128(s)
+	if err != nil {
+		t.Fatalf("ParseDecimal128() unexpected error = %v", err)
+	}
+	return d
+}
+
+func TestDecodeMoney(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    string
+		wantErr bool
+	}{
+		{name: "decimal", value: mustDecimal(t, "123.4567"), want: "123.4567"},
+		{name: "drifted double", value: 0.30000000000000004, want: "0.30"},
+		{name: "drifted negative double", value: -0.7999999999999999, want: "-0.80"},
+		{name: "double", value: -120.5, want: "-120.50"},
+		{name: "double past the scale", value: 0.00004, want: "0.00"},
+		{name: "int32", value: int32(7), want: "7.00"},
+		{name: "int64", value: int64(-3), want: "-3.00"},
+		{name: "null", value: nil, want: "0.00"},
+		{name: "decimal past the scale", value: mustDecimal(t, "1.23456"), wantErr: true},
+		{name: "string", value: "12.50", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeMoneyDocument(t, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeMoney() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("decodeMoney() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeMoney_RoundTrip(t *testing.T) {
+	amount, err := property.ParseMoney("-1234.5678")
+	if err != nil {
+		t.Fatalf("ParseMoney() unexpected error = %v", err)
+	}
+	data, err := bson.MarshalWithRegistry(newRegistry(), &moneyDocument{Amount: amount})
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error = %v", err)
+	}
+	var raw bson.Raw = data
+	if raw.Lookup("amount").Type != bsontype.Decimal128 {
+		t.Errorf("encodeMoney() stored %v, want a decimal", raw.Lookup("amount").Type)
+	}
+
+	got, err := decodeMoneyDocument(t, raw.Lookup("amount").Decimal128())
+	if err != nil || got != amount {
+		t.Errorf("decodeMoney() got = %v, %v, want %v", got, err, amount)
+	}
+}
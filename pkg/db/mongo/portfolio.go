@@ -0,0 +1,78 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chn555/property-service/pkg/property"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PortfolioState struct {
+	collection *mongo.Collection
+}
+
+type PortfolioStateConfig struct {
+	DatabaseName   string
+	CollectionName string
+}
+
+func NewPortfolioState(client *mongo.Client, config PortfolioStateConfig) *PortfolioState {
+	return &PortfolioState{
+		collection: client.Database(config.DatabaseName).Collection(config.CollectionName),
+	}
+}
+
+func (p *PortfolioState) CreatePortfolio(ctx context.Context, portfolio *property.Portfolio) error {
+	portfolio.ID = primitive.NewObjectID().Hex()
+	if _, err := p.collection.InsertOne(ctx, portfolio); err != nil {
+		return fmt.Errorf("insert one: %w", err)
+	}
+	return nil
+}
+
+func (p *PortfolioState) UpdatePortfolio(ctx context.Context, portfolio *property.Portfolio) (bool, error) {
+	res, err := p.collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: portfolio.ID}}, portfolio)
+	if err != nil {
+		return false, fmt.Errorf("replace one: %w", err)
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (p *PortfolioState) GetPortfolio(ctx context.Context, portfolioID string) (*property.Portfolio, bool, error) {
+	portfolio := &property.Portfolio{}
+	err := p.collection.FindOne(ctx, bson.D{{Key: "_id", Value: portfolioID}}).Decode(portfolio)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("find: %w", err)
+	}
+
+	return portfolio, true, nil
+}
+
+func (p *PortfolioState) GetPortfolios(ctx context.Context) ([]*property.Portfolio, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := p.collection.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	var portfolios []*property.Portfolio
+	if err := cursor.All(ctx, &portfolios); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+	return portfolios, nil
+}
+
+func (p *PortfolioState) DeletePortfolio(ctx context.Context, portfolioID string) (bool, error) {
+	res, err := p.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: portfolioID}})
+	if err != nil {
+		return false, fmt.Errorf("delete one: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
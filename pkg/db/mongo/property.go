@@ -0,0 +1,107 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chn555/property-service/pkg/property"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PropertyState struct {
+	collection *mongo.Collection
+}
+
+type PropertyStateConfig struct {
+	DatabaseName   string
+	CollectionName string
+}
+
+func NewPropertyState(client *mongo.Client, config PropertyStateConfig) *PropertyState {
+	return &PropertyState{
+		collection: client.Database(config.DatabaseName).Collection(config.CollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes the property state relies on.
+func (p *PropertyState) EnsureIndexes(ctx context.Context) error {
+	_, err := p.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "owner", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "tags", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create indexes: %w", err)
+	}
+	return nil
+}
+
+func (p *PropertyState) CreateProperty(ctx context.Context, prop *property.Property) error {
+	if prop.ID == "" {
+		prop.ID = primitive.NewObjectID().Hex()
+	}
+	if _, err := p.collection.InsertOne(ctx, prop); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return property.ErrPropertyExists
+		}
+		return fmt.Errorf("insert one: %w", err)
+	}
+	return nil
+}
+
+func (p *PropertyState) UpdateProperty(ctx context.Context, prop *property.Property) (bool, error) {
+	res, err := p.collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: prop.ID}}, prop)
+	if err != nil {
+		return false, fmt.Errorf("replace one: %w", err)
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (p *PropertyState) GetProperty(ctx context.Context, propertyID string) (*property.Property, bool, error) {
+	prop := &property.Property{}
+	err := p.collection.FindOne(ctx, bson.D{{Key: "_id", Value: propertyID}}).Decode(prop)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("find: %w", err)
+	}
+
+	return prop, true, nil
+}
+
+func (p *PropertyState) GetProperties(ctx context.Context, filter *property.PropertyFilter) ([]*property.Property, error) {
+	mongoFilter := bson.D{}
+	if filter.Owner != "" {
+		mongoFilter = append(mongoFilter, bson.E{Key: "owner", Value: filter.Owner})
+	}
+	if len(filter.Tags) > 0 {
+		mongoFilter = append(mongoFilter, bson.E{Key: "tags", Value: bson.D{{Key: "$all", Value: filter.Tags}}})
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := p.collection.Find(ctx, mongoFilter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	var properties []*property.Property
+	if err := cursor.All(ctx, &properties); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+	return properties, nil
+}
+
+func (p *PropertyState) DeleteProperty(ctx context.Context, propertyID string) (bool, error) {
+	res, err := p.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: propertyID}})
+	if err != nil {
+		return false, fmt.Errorf("delete one: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
@@ -0,0 +1,198 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chn555/property-service/pkg/property"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// reconciliationMatch claims an event for the line of a reconciliation matched to it. The unique
+// index over the property and the event lets a single line of the property claim an event.
+type reconciliationMatch struct {
+	PropertyID       string `bson:"property_id"`
+	EventID          string `bson:"event_id"`
+	ReconciliationID string `bson:"reconciliation_id"`
+	Row              int    `bson:"row"`
+}
+
+type ReconciliationState struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+	matches    *mongo.Collection
+}
+
+type ReconciliationStateConfig struct {
+	DatabaseName          string
+	CollectionName        string
+	MatchesCollectionName string
+}
+
+func NewReconciliationState(client *mongo.Client, config ReconciliationStateConfig) *ReconciliationState {
+	database := client.Database(config.DatabaseName)
+	matchesCollectionName := config.MatchesCollectionName
+	if matchesCollectionName == "" {
+		matchesCollectionName = config.CollectionName + "_matches"
+	}
+	return &ReconciliationState{
+		client:     client,
+		collection: database.Collection(config.CollectionName),
+		matches:    database.Collection(matchesCollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes the reconciliation state relies on.
+// The unique match index is what keeps an event from being matched to two lines.
+func (r *ReconciliationState) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "property_id", Value: 1}, {Key: "from", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("create indexes: %w", err)
+	}
+
+	_, err = r.matches.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "property_id", Value: 1}, {Key: "event_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "reconciliation_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create match indexes: %w", err)
+	}
+	return nil
+}
+
+func (r *ReconciliationState) CreateReconciliation(ctx context.Context, reconciliation *property.Reconciliation) error {
+	reconciliation.ID = primitive.NewObjectID().Hex()
+	return r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := r.collection.InsertOne(sc, reconciliation); err != nil {
+			return fmt.Errorf("insert one: %w", err)
+		}
+		return r.claimMatches(sc, reconciliation)
+	})
+}
+
+func (r *ReconciliationState) UpdateReconciliation(ctx context.Context, reconciliation *property.Reconciliation) error {
+	filter := append(reconciliationFilter(reconciliation.PropertyID, reconciliation.ID),
+		bson.E{Key: "revision", Value: reconciliation.Revision})
+	updated := *reconciliation
+	updated.Revision++
+	err := r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		res, err := r.collection.ReplaceOne(sc, filter, &updated)
+		if err != nil {
+			return fmt.Errorf("replace one: %w", err)
+		}
+		if res.MatchedCount == 0 {
+			return property.ErrConflict
+		}
+		return r.claimMatches(sc, &updated)
+	})
+	if err != nil {
+		return err
+	}
+	reconciliation.Revision = updated.Revision
+	return nil
+}
+
+func (r *ReconciliationState) GetReconciliation(ctx context.Context, propertyID string, reconciliationID string) (*property.Reconciliation, bool, error) {
+	reconciliation := &property.Reconciliation{}
+	err := r.collection.FindOne(ctx, reconciliationFilter(propertyID, reconciliationID)).Decode(reconciliation)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("find: %w", err)
+	}
+
+	return reconciliation, true, nil
+}
+
+func (r *ReconciliationState) GetReconciliations(ctx context.Context, propertyID string) ([]*property.Reconciliation, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "from", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.D{{Key: "property_id", Value: propertyID}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	var reconciliations []*property.Reconciliation
+	if err := cursor.All(ctx, &reconciliations); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+	return reconciliations, nil
+}
+
+func (r *ReconciliationState) DeleteReconciliation(ctx context.Context, propertyID string, reconciliationID string) (bool, error) {
+	exists := false
+	err := r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		res, err := r.collection.DeleteOne(sc, reconciliationFilter(propertyID, reconciliationID))
+		if err != nil {
+			return fmt.Errorf("delete one: %w", err)
+		}
+		exists = res.DeletedCount > 0
+		if _, err := r.matches.DeleteMany(sc, bson.D{{Key: "reconciliation_id", Value: reconciliationID}}); err != nil {
+			return fmt.Errorf("delete matches: %w", err)
+		}
+		return nil
+	})
+	return exists, err
+}
+
+// claimMatches replaces the matches of reconciliation with those of its lines. An event claimed
+// by another line fails the insert with a duplicate key error.
+func (r *ReconciliationState) claimMatches(ctx context.Context, reconciliation *property.Reconciliation) error {
+	if _, err := r.matches.DeleteMany(ctx, bson.D{{Key: "reconciliation_id", Value: reconciliation.ID}}); err != nil {
+		return fmt.Errorf("delete matches: %w", err)
+	}
+
+	var matches []any
+	for _, line := range reconciliation.Lines {
+		if line.EventID != "" {
+			matches = append(matches, &reconciliationMatch{
+				PropertyID:       reconciliation.PropertyID,
+				EventID:          line.EventID,
+				ReconciliationID: reconciliation.ID,
+				Row:              line.Row,
+			})
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	if _, err := r.matches.InsertMany(ctx, matches); err != nil {
+		return fmt.Errorf("insert matches: %w", err)
+	}
+	return nil
+}
+
+// withTransaction runs fn in a transaction, so that a reconciliation and its matches are written
+// together. A match rejected by the unique index fails it with property.ErrEventMatched.
+func (r *ReconciliationState) withTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	session, err := r.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return property.ErrEventMatched
+		}
+		return fmt.Errorf("with transaction: %w", err)
+	}
+	return nil
+}
+
+func reconciliationFilter(propertyID string, reconciliationID string) bson.D {
+	return bson.D{{Key: "_id", Value: reconciliationID}, {Key: "property_id", Value: propertyID}}
+}
@@ -0,0 +1,138 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chn555/property-service/pkg/property"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type ScheduleState struct {
+	collection *mongo.Collection
+}
+
+type ScheduleStateConfig struct {
+	DatabaseName   string
+	CollectionName string
+}
+
+func NewScheduleState(client *mongo.Client, config ScheduleStateConfig) *ScheduleState {
+	return &ScheduleState{
+		collection: client.Database(config.DatabaseName).Collection(config.CollectionName),
+	}
+}
+
+// EnsureIndexes creates the indexes the schedule state relies on.
+func (s *ScheduleState) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "property_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "done", Value: 1}, {Key: "next_occurrence", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create indexes: %w", err)
+	}
+	return nil
+}
+
+func (s *ScheduleState) CreateSchedule(ctx context.Context, schedule *property.Schedule) error {
+	schedule.ID = primitive.NewObjectID().Hex()
+	if _, err := s.collection.InsertOne(ctx, schedule); err != nil {
+		return fmt.Errorf("insert one: %w", err)
+	}
+	return nil
+}
+
+func (s *ScheduleState) UpdateSchedule(ctx context.Context, schedule *property.Schedule) (bool, error) {
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "event_amount", Value: schedule.EventAmount},
+		{Key: "currency", Value: schedule.Currency},
+		{Key: "description", Value: schedule.Description},
+		{Key: "category", Value: schedule.Category},
+		{Key: "tags", Value: schedule.Tags},
+		{Key: "end", Value: schedule.End},
+		{Key: "done", Value: schedule.Done},
+	}}}
+	res, err := s.collection.UpdateOne(ctx, scheduleFilter(schedule.PropertyID, schedule.ID), update)
+	if err != nil {
+		return false, fmt.Errorf("update one: %w", err)
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (s *ScheduleState) AdvanceSchedule(ctx context.Context, schedule *property.Schedule, materialized int) error {
+	filter := append(scheduleFilter(schedule.PropertyID, schedule.ID), bson.E{Key: "materialized", Value: materialized})
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "materialized", Value: schedule.Materialized},
+		{Key: "next_occurrence", Value: schedule.NextOccurrence},
+		{Key: "done", Value: schedule.Done},
+		{Key: "skipped", Value: schedule.Skipped},
+	}}}
+	res, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("update one: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return property.ErrConflict
+	}
+	return nil
+}
+
+func (s *ScheduleState) GetSchedule(ctx context.Context, propertyID string, scheduleID string) (*property.Schedule, bool, error) {
+	schedule := &property.Schedule{}
+	err := s.collection.FindOne(ctx, scheduleFilter(propertyID, scheduleID)).Decode(schedule)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("find: %w", err)
+	}
+
+	return schedule, true, nil
+}
+
+func (s *ScheduleState) GetSchedules(ctx context.Context, propertyID string) ([]*property.Schedule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "start", Value: 1}})
+	return s.find(ctx, bson.D{{Key: "property_id", Value: propertyID}}, opts)
+}
+
+func (s *ScheduleState) GetDueSchedules(ctx context.Context, date time.Time) ([]*property.Schedule, error) {
+	filter := bson.D{
+		{Key: "done", Value: false},
+		{Key: "next_occurrence", Value: bson.D{{Key: "$lte", Value: date}}},
+	}
+	return s.find(ctx, filter, options.Find())
+}
+
+func (s *ScheduleState) DeleteSchedule(ctx context.Context, propertyID string, scheduleID string) (bool, error) {
+	res, err := s.collection.DeleteOne(ctx, scheduleFilter(propertyID, scheduleID))
+	if err != nil {
+		return false, fmt.Errorf("delete one: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (s *ScheduleState) find(ctx context.Context, filter bson.D, opts *options.FindOptions) ([]*property.Schedule, error) {
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	var schedules []*property.Schedule
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, fmt.Errorf("cursor all: %w", err)
+	}
+	return schedules, nil
+}
+
+func scheduleFilter(propertyID string, scheduleID string) bson.D {
+	return bson.D{{Key: "_id", Value: scheduleID}, {Key: "property_id", Value: propertyID}}
+}
@@ -0,0 +1,95 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+var csvHeader = []string{"date", "id", "description", "category", "tags", "currency", "amount", "balance"}
+
+// CSV writes events as the rows of a CSV file, one column per field of csvHeader. A CSV report
+// also has a row with the starting balance in each currency before its events, and rows with
+// its totals and ending balances after them, carrying their label in the description column.
+type CSV struct {
+	w        *csv.Writer
+	location *time.Location
+	report   *property.PeriodReport
+}
+
+// NewCSVEvents starts a CSV file of events, writing their dates in location.
+func NewCSVEvents(w io.Writer, location *time.Location) (*CSV, error) {
+	c := &CSV{w: csv.NewWriter(w), location: location}
+	if err := c.w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewCSVReport starts the CSV file of report, writing its dates in location. The events of the
+// report are written with WriteEvent, whichever of them report.Events holds.
+func NewCSVReport(w io.Writer, report *property.PeriodReport, location *time.Location) (*CSV, error) {
+	c, err := NewCSVEvents(w, location)
+	if err != nil {
+		return nil, err
+	}
+	c.report = report
+
+	for _, currency := range sortedCurrencies(report.StartingBalance.ByCurrency) {
+		if err := c.writeSummary(report.From, "Starting balance", currency, "", report.StartingBalance.ByCurrency[currency].String()); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *CSV) WriteEvent(event *property.Event) error {
+	return c.w.Write([]string{
+		event.Date.In(c.location).Format(time.RFC3339),
+		event.ID,
+		event.Description,
+		string(event.Category),
+		strings.Join(event.Tags, ";"),
+		event.Currency,
+		event.EventAmount.String(),
+		event.PostEventBalance.String(),
+	})
+}
+
+// Close writes the totals of a report, and flushes the file.
+func (c *CSV) Close() error {
+	if report := c.report; report != nil {
+		for _, total := range []struct {
+			label string
+			sums  map[string]property.Money
+		}{{"Income", report.Income}, {"Expense", report.Expense}, {"Net", report.Net}} {
+			for _, currency := range sortedCurrencies(total.sums) {
+				if err := c.writeSummary(report.To, total.label, currency, total.sums[currency].String(), ""); err != nil {
+					return err
+				}
+			}
+		}
+		for _, currency := range sortedCurrencies(report.EndingBalance.ByCurrency) {
+			if err := c.writeSummary(report.To, "Ending balance", currency, "", report.EndingBalance.ByCurrency[currency].String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// writeSummary writes a row of the report summary, with either an amount or a balance.
+func (c *CSV) writeSummary(date time.Time, label string, currency string, amount string, balance string) error {
+	return c.w.Write([]string{date.In(c.location).Format(time.RFC3339), "", label, "", "", currency, amount, balance})
+}
+
+func sortedCurrencies(m map[string]property.Money) []string {
+	return slices.Sorted(maps.Keys(m))
+}
@@ -0,0 +1,119 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+func mustMoney(s string) property.Money {
+	m, err := property.ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func testReport() *property.PeriodReport {
+	return &property.PeriodReport{
+		PropertyID: "p1",
+		From:       time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		To:         time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		StartingBalance: &property.Balance{
+			ByCurrency:   map[string]property.Money{"USD": mustMoney("100"), "EUR": mustMoney("10")},
+			Total:        mustMoney("111"),
+			BaseCurrency: "USD",
+		},
+		EndingBalance: &property.Balance{
+			ByCurrency:   map[string]property.Money{"USD": mustMoney("979.5"), "EUR": mustMoney("10")},
+			Total:        mustMoney("990.5"),
+			BaseCurrency: "USD",
+		},
+		Income:       map[string]property.Money{"USD": mustMoney("1000")},
+		Expense:      map[string]property.Money{"USD": mustMoney("-120.5")},
+		Net:          map[string]property.Money{"USD": mustMoney("879.5")},
+		IncomeTotal:  mustMoney("1000"),
+		ExpenseTotal: mustMoney("-120.5"),
+		NetTotal:     mustMoney("879.5"),
+		EventCount:   2,
+	}
+}
+
+func testEvents() []*property.Event {
+	return []*property.Event{
+		{ID: "e1", EventAmount: mustMoney("1000"), Currency: "USD", Date: time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC),
+			Description: "Rent, March", Category: property.Rent, PostEventBalance: mustMoney("1100")},
+		{ID: "e2", EventAmount: mustMoney("-120.5"), Currency: "USD", Date: time.Date(2024, time.March, 7, 23, 30, 0, 0, time.UTC),
+			Description: "Plumber", Category: property.Maintenance, Tags: []string{"unit-1", "urgent"}, PostEventBalance: mustMoney("979.5")},
+	}
+}
+
+func TestCSV_Report(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Berlin")
+	var buf bytes.Buffer
+	c, err := NewCSVReport(&buf, testReport(), location)
+	if err != nil {
+		t.Fatalf("NewCSVReport() unexpected error = %v", err)
+	}
+	for _, e := range testEvents() {
+		if err := c.WriteEvent(e); err != nil {
+			t.Fatalf("WriteEvent() unexpected error = %v", err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+
+	got, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() unexpected error = %v", err)
+	}
+	want := [][]string{
+		csvHeader,
+		{"2024-03-01T01:00:00+01:00", "", "Starting balance", "", "", "EUR", "", "10.00"},
+		{"2024-03-01T01:00:00+01:00", "", "Starting balance", "", "", "USD", "", "100.00"},
+		{"2024-03-01T10:00:00+01:00", "e1", "Rent, March", "rent", "", "USD", "1000.00", "1100.00"},
+		{"2024-03-08T00:30:00+01:00", "e2", "Plumber", "maintenance", "unit-1;urgent", "USD", "-120.50", "979.50"},
+		{"2024-04-01T02:00:00+02:00", "", "Income", "", "", "USD", "1000.00", ""},
+		{"2024-04-01T02:00:00+02:00", "", "Expense", "", "", "USD", "-120.50", ""},
+		{"2024-04-01T02:00:00+02:00", "", "Net", "", "", "USD", "879.50", ""},
+		{"2024-04-01T02:00:00+02:00", "", "Ending balance", "", "", "EUR", "", "10.00"},
+		{"2024-04-01T02:00:00+02:00", "", "Ending balance", "", "", "USD", "", "979.50"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CSV report got = %v, want %v", got, want)
+	}
+}
+
+func TestCSV_Events(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := NewCSVEvents(&buf, time.UTC)
+	if err != nil {
+		t.Fatalf("NewCSVEvents() unexpected error = %v", err)
+	}
+	for _, e := range testEvents() {
+		if err := c.WriteEvent(e); err != nil {
+			t.Fatalf("WriteEvent() unexpected error = %v", err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+
+	got, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() unexpected error = %v", err)
+	}
+	want := [][]string{
+		csvHeader,
+		{"2024-03-01T09:00:00Z", "e1", "Rent, March", "rent", "", "USD", "1000.00", "1100.00"},
+		{"2024-03-07T23:30:00Z", "e2", "Plumber", "maintenance", "unit-1;urgent", "USD", "-120.50", "979.50"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CSV events got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,353 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+// The pages are A4 in points, with text in the standard Helvetica fonts, which PDF readers
+// provide so that nothing needs to be embedded.
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	margin       = 40.0
+	footerHeight = 30.0
+	fontSize     = 8.0
+	titleSize    = 14.0
+	rowHeight    = 12.0
+)
+
+// The objects written before the pages. The page tree is written last, when its pages are known.
+const (
+	catalogObject = iota + 1
+	pagesObject
+	regularFontObject
+	boldFontObject
+	firstPageObject
+)
+
+type pdfColumn struct {
+	title string
+	// x is the left edge of the column, or its right edge if it is aligned right.
+	x     float64
+	width float64
+	right bool
+}
+
+var pdfEventColumns = []pdfColumn{
+	{title: "Date", x: margin, width: 55},
+	{title: "Description", x: 100, width: 190},
+	{title: "Category", x: 295, width: 65},
+	{title: "Currency", x: 365, width: 40},
+	{title: "Amount", x: 475, width: 65, right: true},
+	{title: "Balance", x: pageWidth - margin, width: 75, right: true},
+}
+
+var pdfTotalColumns = []pdfColumn{
+	{title: "Currency", x: margin, width: 100},
+	{title: "Income", x: 250, width: 90, right: true},
+	{title: "Expense", x: 345, width: 90, right: true},
+	{title: "Net", x: 440, width: 90, right: true},
+	{title: "Ending balance", x: pageWidth - margin, width: 110, right: true},
+}
+
+// PDF writes a report as a printable PDF document: a header with the period and starting balance,
+// a table of the events, and the totals of the period with its ending balance. Each page is
+// written out once it is full, so that only one is held in memory.
+type PDF struct {
+	out      *countingWriter
+	offsets  map[int]int64
+	pages    []int
+	page     *bytes.Buffer
+	y        float64
+	events   int
+	report   *property.PeriodReport
+	location *time.Location
+}
+
+// NewPDFReport starts the PDF document of report, titled title and with its dates in location.
+// The events of the report are written with WriteEvent, whichever of them report.Events holds.
+func NewPDFReport(w io.Writer, report *property.PeriodReport, title string, location *time.Location) (*PDF, error) {
+	p := &PDF{
+		out:      &countingWriter{w: bufio.NewWriter(w)},
+		offsets:  make(map[int]int64),
+		report:   report,
+		location: location,
+	}
+
+	// the binary comment marks the file as binary for transfer programs
+	fmt.Fprint(p.out, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	p.writeObject(catalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObject))
+	p.writeObject(regularFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	p.writeObject(boldFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	p.startPage()
+	p.text(margin, p.y, titleSize, true, title)
+	p.y -= titleSize * 1.5
+	last := report.To.Add(-time.Nanosecond)
+	p.text(margin, p.y, fontSize+1, false, fmt.Sprintf("%s to %s", p.date(report.From), p.date(last)))
+	p.y -= rowHeight * 2
+
+	p.text(margin, p.y, fontSize, true, "Starting balance")
+	p.y -= rowHeight
+	for _, currency := range sortedCurrencies(report.StartingBalance.ByCurrency) {
+		p.text(margin, p.y, fontSize, false, currency)
+		p.textRight(200, p.y, fontSize, false, report.StartingBalance.ByCurrency[currency].String())
+		p.y -= rowHeight
+	}
+	p.text(margin, p.y, fontSize, true, fmt.Sprintf("Total (%s)", report.StartingBalance.BaseCurrency))
+	p.textRight(200, p.y, fontSize, true, report.StartingBalance.Total.String())
+	p.y -= rowHeight * 2
+
+	p.tableHeader(pdfEventColumns)
+	return p, p.out.w.Flush()
+}
+
+func (p *PDF) WriteEvent(event *property.Event) error {
+	if p.y-rowHeight < margin+footerHeight {
+		if err := p.finishPage(); err != nil {
+			return err
+		}
+		p.startPage()
+		p.tableHeader(pdfEventColumns)
+	}
+
+	p.row(pdfEventColumns, false, []string{
+		p.date(event.Date),
+		event.Description,
+		string(event.Category),
+		event.Currency,
+		event.EventAmount.String(),
+		event.PostEventBalance.String(),
+	})
+	p.events++
+	return nil
+}
+
+// Close writes the totals of the report and ends the document.
+func (p *PDF) Close() error {
+	report := p.report
+	if p.events == 0 {
+		p.text(margin, p.y, fontSize, false, "No events in this period.")
+		p.y -= rowHeight
+	}
+
+	currencies := slices.Collect(maps.Keys(report.EndingBalance.ByCurrency))
+	for _, sums := range []map[string]property.Money{report.Income, report.Expense} {
+		for currency := range sums {
+			if !slices.Contains(currencies, currency) {
+				currencies = append(currencies, currency)
+			}
+		}
+	}
+	slices.Sort(currencies)
+
+	// the totals are kept together on one page
+	if p.y-rowHeight*float64(len(currencies)+5) < margin+footerHeight {
+		if err := p.finishPage(); err != nil {
+			return err
+		}
+		p.startPage()
+	}
+	p.y -= rowHeight
+	p.tableHeader(pdfTotalColumns)
+	for _, currency := range currencies {
+		p.row(pdfTotalColumns, false, []string{
+			currency,
+			report.Income[currency].String(),
+			report.Expense[currency].String(),
+			report.Net[currency].String(),
+			report.EndingBalance.ByCurrency[currency].String(),
+		})
+	}
+	p.row(pdfTotalColumns, true, []string{
+		fmt.Sprintf("Total (%s)", report.EndingBalance.BaseCurrency),
+		report.IncomeTotal.String(),
+		report.ExpenseTotal.String(),
+		report.NetTotal.String(),
+		report.EndingBalance.Total.String(),
+	})
+	p.y -= rowHeight
+	p.text(margin, p.y, fontSize, false, fmt.Sprintf("%d events", report.EventCount))
+
+	if err := p.finishPage(); err != nil {
+		return err
+	}
+
+	kids := make([]string, len(p.pages))
+	for i, page := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	p.writeObject(pagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+
+	objects := len(p.offsets) + 1
+	xref := p.out.n
+	fmt.Fprintf(p.out, "xref\n0 %d\n0000000000 65535 f \n", objects)
+	for n := 1; n < objects; n++ {
+		fmt.Fprintf(p.out, "%010d 00000 n \n", p.offsets[n])
+	}
+	fmt.Fprintf(p.out, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", objects, catalogObject, xref)
+	return p.out.w.Flush()
+}
+
+func (p *PDF) writeObject(n int, body string) {
+	p.offsets[n] = p.out.n
+	fmt.Fprintf(p.out, "%d 0 obj\n%s\nendobj\n", n, body)
+}
+
+func (p *PDF) startPage() {
+	p.page = &bytes.Buffer{}
+	p.y = pageHeight - margin
+}
+
+// finishPage writes out the current page, with its number in the footer.
+func (p *PDF) finishPage() error {
+	footer := fmt.Sprintf("Page %d", len(p.pages)+1)
+	p.text((pageWidth-textWidth(footer, fontSize))/2, margin/2+footerHeight/2, fontSize, false, footer)
+
+	var content bytes.Buffer
+	z := zlib.NewWriter(&content)
+	if _, err := z.Write(p.page.Bytes()); err != nil {
+		return err
+	}
+	if err := z.Close(); err != nil {
+		return err
+	}
+
+	contentObject := firstPageObject + 2*len(p.pages)
+	pageObject := contentObject + 1
+	p.offsets[contentObject] = p.out.n
+	fmt.Fprintf(p.out, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", contentObject, content.Len())
+	p.out.Write(content.Bytes())
+	fmt.Fprint(p.out, "\nendstream\nendobj\n")
+	p.writeObject(pageObject, fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %g %g] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+		pagesObject, pageWidth, pageHeight, regularFontObject, boldFontObject, contentObject))
+	p.pages = append(p.pages, pageObject)
+	p.page = nil
+
+	return p.out.w.Flush()
+}
+
+// tableHeader writes the titles of columns, underlined.
+func (p *PDF) tableHeader(columns []pdfColumn) {
+	p.row(columns, true, columnTitles(columns))
+	fmt.Fprintf(p.page, "0.5 w %g %.2f m %g %.2f l S\n", margin, p.y+rowHeight-3, pageWidth-margin, p.y+rowHeight-3)
+}
+
+// row writes a row of cells, each cut to the width of its column.
+func (p *PDF) row(columns []pdfColumn, bold bool, cells []string) {
+	for i, column := range columns {
+		cell := truncate(cells[i], column.width, fontSize)
+		if column.right {
+			p.textRight(column.x, p.y, fontSize, bold, cell)
+		} else {
+			p.text(column.x, p.y, fontSize, bold, cell)
+		}
+	}
+	p.y -= rowHeight
+}
+
+func (p *PDF) text(x float64, y float64, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(p.page, "BT /%s %g Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+}
+
+func (p *PDF) textRight(x float64, y float64, size float64, bold bool, s string) {
+	p.text(x-textWidth(s, size), y, size, bold, s)
+}
+
+func (p *PDF) date(t time.Time) string {
+	return t.In(p.location).Format(time.DateOnly)
+}
+
+func columnTitles(columns []pdfColumn) []string {
+	titles := make([]string, len(columns))
+	for i, column := range columns {
+		titles[i] = column.title
+	}
+	return titles
+}
+
+// countingWriter counts the bytes written, for the offsets of the cross-reference table.
+// Write errors are kept by the bufio.Writer and returned when it is flushed.
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// pdfString encodes s as the content of a PDF literal string in WinAnsiEncoding, replacing the
+// characters it cannot represent.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r == '€':
+			b.WriteByte(0x80)
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		case unicode.IsSpace(r):
+			b.WriteByte(' ')
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// helveticaWidths are the widths of the printable ASCII characters in Helvetica, in thousandths
+// of the font size. Helvetica-Bold is measured with them too, its digits being as wide.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
+
+func textWidth(s string, size float64) float64 {
+	width := 0
+	for _, r := range s {
+		if r >= 0x20 && r < 0x7f {
+			width += helveticaWidths[r-0x20]
+		} else {
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+// truncate cuts s to fit in width, ending it with an ellipsis if it is cut.
+func truncate(s string, width float64, size float64) string {
+	if textWidth(s, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
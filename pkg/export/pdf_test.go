@@ -0,0 +1,136 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+// readPDF checks the cross-reference table of a PDF document against the objects it points to,
+// and returns the number of pages and the text shown on them.
+func readPDF(t *testing.T, data []byte) (int, string) {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("PDF is missing its header or trailer")
+	}
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	if m == nil {
+		t.Fatalf("PDF has no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point to the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(data[xref:], -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points to %q", i+1, data[offset:offset+10])
+		}
+	}
+
+	var text strings.Builder
+	streams := regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindAllSubmatch(data, -1)
+	for _, stream := range streams {
+		z, err := zlib.NewReader(bytes.NewReader(stream[1]))
+		if err != nil {
+			t.Fatalf("zlib.NewReader() unexpected error = %v", err)
+		}
+		content, err := io.ReadAll(z)
+		if err != nil {
+			t.Fatalf("ReadAll() unexpected error = %v", err)
+		}
+		for _, s := range regexp.MustCompile(`\(((?:\\.|[^\\)])*)\) Tj`).FindAllSubmatch(content, -1) {
+			text.Write(s[1])
+			text.WriteByte('\n')
+		}
+	}
+
+	pages := regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`).FindSubmatch(data)
+	if pages == nil {
+		t.Fatalf("PDF has no page tree")
+	}
+	count, _ := strconv.Atoi(string(pages[1]))
+	if count != len(streams) {
+		t.Errorf("page tree counts %d pages, want %d", count, len(streams))
+	}
+	return count, text.String()
+}
+
+func TestPDF_Report(t *testing.T) {
+	tests := []struct {
+		name      string
+		events    []*property.Event
+		wantPages int
+		wantText  []string
+	}{
+		{
+			name:      "one page",
+			events:    testEvents(),
+			wantPages: 1,
+			wantText: []string{"Main St 1\n", "2024-03-01 to 2024-03-31\n", "Rent, March\n", "Plumber\n", "-120.50\n",
+				"Total \\(USD\\)\n", "990.50\n", "2 events\n", "Page 1\n"},
+		},
+		{
+			name:      "no events",
+			wantPages: 1,
+			wantText:  []string{"No events in this period.\n"},
+		},
+		{
+			name: "many pages",
+			events: func() []*property.Event {
+				events := make([]*property.Event, 150)
+				for i := range events {
+					events[i] = &property.Event{ID: strconv.Itoa(i), EventAmount: mustMoney("1"), Currency: "USD",
+						Date: time.Date(2024, time.March, 1+i/10, 0, 0, 0, 0, time.UTC), Description: "Event " + strconv.Itoa(i)}
+				}
+				return events
+			}(),
+			wantPages: 3,
+			wantText:  []string{"Event 0\n", "Event 149\n", "Page 3\n"},
+		},
+		{
+			name: "long and non-Latin text",
+			events: []*property.Event{{ID: "e1", EventAmount: mustMoney("1"), Currency: "EUR", Date: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+				Description: "Repairs (roof) 5€ 日本 " + strings.Repeat("and more ", 20)}},
+			wantPages: 1,
+			wantText:  []string{"Repairs \\(roof\\) 5\x80 ?? and more", "...\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p, err := NewPDFReport(&buf, testReport(), "Main St 1", time.UTC)
+			if err != nil {
+				t.Fatalf("NewPDFReport() unexpected error = %v", err)
+			}
+			for _, e := range tt.events {
+				if err := p.WriteEvent(e); err != nil {
+					t.Fatalf("WriteEvent() unexpected error = %v", err)
+				}
+			}
+			if err := p.Close(); err != nil {
+				t.Fatalf("Close() unexpected error = %v", err)
+			}
+
+			pages, text := readPDF(t, buf.Bytes())
+			if pages != tt.wantPages {
+				t.Errorf("PDF pages got = %d, want %d", pages, tt.wantPages)
+			}
+			for _, want := range tt.wantText {
+				if !strings.Contains(text, want) {
+					t.Errorf("PDF text is missing %q, got %q", want, text)
+				}
+			}
+		})
+	}
+}
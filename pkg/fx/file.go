@@ -0,0 +1,71 @@
+package fx
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+)
+
+// Config holds the configuration for currency conversion
+type Config struct {
+	// BaseCurrency is the ISO-4217 code balances are totalled in
+	BaseCurrency string `validate:"required,len=3,alpha,uppercase"`
+	// RatesFile is an optional YAML file of exchange rates, see LoadFile
+	RatesFile string
+}
+
+type fileRate struct {
+	From string `koanf:"from"`
+	To   string `koanf:"to"`
+	// Date is formatted as YYYY-MM-DD
+	Date string `koanf:"date"`
+	// Rate is kept as a string so it's parsed exactly
+	Rate string `koanf:"rate"`
+}
+
+// NewProvider returns a Memory loaded from config.RatesFile, or an empty one if there is none.
+func NewProvider(config Config) (*Memory, error) {
+	if config.RatesFile == "" {
+		return NewMemory(), nil
+	}
+	return LoadFile(config.RatesFile)
+}
+
+// LoadFile loads exchange rates from a YAML file shaped like:
+//
+//	rates:
+//	  - from: EUR
+//	    to: USD
+//	    date: 2025-01-01
+//	    rate: "1.0832"
+func LoadFile(path string) (*Memory, error) {
+	k := koanf.New(".")
+	if err := k.Load(file.Provider(path), yaml.Parser()); err != nil {
+		return nil, fmt.Errorf("load rates file: %w", err)
+	}
+
+	var rates []fileRate
+	if err := k.Unmarshal("rates", &rates); err != nil {
+		return nil, fmt.Errorf("unmarshal rates: %w", err)
+	}
+
+	m := NewMemory()
+	for i, r := range rates {
+		date, err := time.Parse(time.DateOnly, r.Date)
+		if err != nil {
+			return nil, fmt.Errorf("rate %d: invalid date: %w", i, err)
+		}
+		rate, ok := new(big.Rat).SetString(r.Rate)
+		if !ok {
+			return nil, fmt.Errorf("rate %d: invalid rate %q", i, r.Rate)
+		}
+		if err := m.Set(r.From, r.To, date, rate); err != nil {
+			return nil, fmt.Errorf("rate %d: %w", i, err)
+		}
+	}
+	return m, nil
+}
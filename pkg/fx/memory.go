@@ -0,0 +1,89 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+type pair struct {
+	from string
+	to   string
+}
+
+type datedRate struct {
+	date time.Time
+	rate *big.Rat
+}
+
+// Memory is an in-memory property.RateProvider.
+// A rate applies from its date until the next rate of the same pair, and a pair with no
+// rate of its own is served by the inverse of the opposite pair.
+type Memory struct {
+	mu    sync.RWMutex
+	rates map[pair][]datedRate
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		rates: make(map[pair][]datedRate),
+	}
+}
+
+// Set records that from one unit of from was worth rate units of to, starting at date.
+func (m *Memory) Set(from string, to string, date time.Time, rate *big.Rat) error {
+	if rate.Sign() <= 0 {
+		return fmt.Errorf("rate %s to %s must be positive", from, to)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := pair{from: from, to: to}
+	rates := slices.DeleteFunc(m.rates[p], func(r datedRate) bool {
+		return r.date.Equal(date)
+	})
+	rates = append(rates, datedRate{date: date, rate: new(big.Rat).Set(rate)})
+	slices.SortFunc(rates, func(a, b datedRate) int {
+		return a.date.Compare(b.date)
+	})
+	m.rates[p] = rates
+	return nil
+}
+
+func (m *Memory) GetRate(_ context.Context, from string, to string, date time.Time) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if rate, ok := m.rateAt(pair{from: from, to: to}, date); ok {
+		return rate, nil
+	}
+	if rate, ok := m.rateAt(pair{from: to, to: from}, date); ok {
+		return rate.Inv(rate), nil
+	}
+	return nil, fmt.Errorf("%s to %s at %s: %w", from, to, date.Format(time.DateOnly), property.ErrNoRate)
+}
+
+// rateAt returns a copy of the latest rate of p dated at or before date.
+func (m *Memory) rateAt(p pair, date time.Time) (*big.Rat, bool) {
+	rates := m.rates[p]
+	i, _ := slices.BinarySearchFunc(rates, date, func(r datedRate, date time.Time) int {
+		if r.date.After(date) {
+			return 1
+		}
+		return -1
+	})
+	if i == 0 {
+		return nil, false
+	}
+	return new(big.Rat).Set(rates[i-1].rate), true
+}
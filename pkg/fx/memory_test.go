@@ -0,0 +1,48 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+func TestMemory_GetRate(t *testing.T) {
+	jan := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)
+	m := NewMemory()
+	_ = m.Set("EUR", "USD", jan, big.NewRat(108, 100))
+	_ = m.Set("EUR", "USD", feb, big.NewRat(110, 100))
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		date    time.Time
+		want    *big.Rat
+		wantErr error
+	}{
+		{name: "same currency", from: "GBP", to: "GBP", date: jan, want: big.NewRat(1, 1)},
+		{name: "on rate date", from: "EUR", to: "USD", date: jan, want: big.NewRat(108, 100)},
+		{name: "between rate dates", from: "EUR", to: "USD", date: feb.Add(-time.Hour), want: big.NewRat(108, 100)},
+		{name: "after last rate date", from: "EUR", to: "USD", date: feb.AddDate(1, 0, 0), want: big.NewRat(110, 100)},
+		{name: "inverse pair", from: "USD", to: "EUR", date: feb, want: big.NewRat(100, 110)},
+		{name: "before first rate date", from: "EUR", to: "USD", date: jan.Add(-time.Hour), wantErr: property.ErrNoRate},
+		{name: "unknown pair", from: "GBP", to: "USD", date: feb, wantErr: property.ErrNoRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.GetRate(context.TODO(), tt.from, tt.to, tt.date)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetRate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.Cmp(tt.want) != 0 {
+				t.Errorf("GetRate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
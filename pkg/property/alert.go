@@ -0,0 +1,522 @@
+package property
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+var (
+	// ErrAlertRuleNotFound is returned when an alert rule ID does not match any rule of the property.
+	ErrAlertRuleNotFound = errors.New("alert rule not found")
+	// ErrWebhookNotFound is returned when a webhook ID does not match any registered webhook.
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// minWebhookSecretLength bounds the secrets webhook payloads are signed with from below.
+const minWebhookSecretLength = 16
+
+type AlertKind string
+
+const (
+	// NegativeBalance alerts when the balance of the property in the rule currency drops below zero.
+	NegativeBalance AlertKind = "negative_balance"
+	// BalanceBelow alerts when the balance of the property in the rule currency drops below the threshold.
+	BalanceBelow AlertKind = "balance_below"
+	// ExpenseAbove alerts when a single expense in the rule currency is larger than the threshold.
+	ExpenseAbove AlertKind = "expense_above"
+)
+
+func (k AlertKind) IsValid() bool {
+	switch k {
+	case NegativeBalance, BalanceBelow, ExpenseAbove:
+		return true
+	}
+	return false
+}
+
+// AlertRule is a condition checked against every event saved to a property.
+type AlertRule struct {
+	// ID is assigned by the AlertStore when the rule is created.
+	ID         string    `json:"id" bson:"_id"`
+	PropertyID string    `json:"property_id" bson:"property_id"`
+	Kind       AlertKind `json:"kind" bson:"kind"`
+	// Threshold is the balance floor of a BalanceBelow rule, or the expense limit of an
+	// ExpenseAbove rule, as a positive amount. NegativeBalance rules have none.
+	Threshold Money  `json:"threshold" bson:"threshold"`
+	Currency  string `json:"currency" bson:"currency"`
+}
+
+// Webhook is an HTTP endpoint every alert is posted to.
+type Webhook struct {
+	// ID is assigned by the AlertStore when the webhook is registered.
+	ID  string `json:"id" bson:"_id"`
+	URL string `json:"url" bson:"url"`
+	// Secret is the key payloads posted to the webhook are signed with. It is never returned.
+	Secret string `json:"-" bson:"secret"`
+}
+
+// Alert is a rule triggered by a saved event.
+type Alert struct {
+	RuleID      string    `json:"rule_id" bson:"rule_id"`
+	PropertyID  string    `json:"property_id" bson:"property_id"`
+	Kind        AlertKind `json:"kind" bson:"kind"`
+	EventID     string    `json:"event_id" bson:"event_id"`
+	EventAmount Money     `json:"event_amount" bson:"event_amount"`
+	// Balance is the current balance of the property in Currency, including the event.
+	Balance     Money     `json:"balance" bson:"balance"`
+	Threshold   Money     `json:"threshold" bson:"threshold"`
+	Currency    string    `json:"currency" bson:"currency"`
+	TriggeredAt time.Time `json:"triggered_at" bson:"triggered_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryFailed marks a delivery given up on after AlertsConfig.MaxAttempts attempts.
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// Delivery is the posting of an alert to a webhook, and the log of its attempts.
+type Delivery struct {
+	// ID is assigned by the AlertStore when the delivery is created. It is sent along with the
+	// alert, so webhooks can tell a retried delivery from a new one.
+	ID        string         `json:"id" bson:"_id"`
+	WebhookID string         `json:"webhook_id" bson:"webhook_id"`
+	URL       string         `json:"url" bson:"url"`
+	Alert     *Alert         `json:"alert" bson:"alert"`
+	Status    DeliveryStatus `json:"status" bson:"status"`
+	Attempts  int            `json:"attempts" bson:"attempts"`
+	// NextAttempt is when a pending delivery is due to be attempted.
+	NextAttempt time.Time `json:"next_attempt,omitempty" bson:"next_attempt"`
+	// LastError describes why the last attempt failed.
+	LastError string `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	// StatusCode is the HTTP status the webhook answered the last attempt with, if it answered.
+	StatusCode  int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+	DeliveredAt time.Time `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+}
+
+type DeliveryFilter struct {
+	WebhookID  string
+	PropertyID string
+	Status     DeliveryStatus
+}
+
+// deliveryPayload is the body posted to webhooks.
+type deliveryPayload struct {
+	DeliveryID string `json:"delivery_id"`
+	*Alert
+}
+
+type AlertStore interface {
+	// CreateAlertRule saves a new rule, assigning it an ID.
+	CreateAlertRule(ctx context.Context, rule *AlertRule) error
+	GetAlertRule(ctx context.Context, propertyID string, ruleID string) (*AlertRule, bool, error)
+	GetAlertRules(ctx context.Context, propertyID string) ([]*AlertRule, error)
+	// DeleteAlertRule reports whether the rule existed.
+	DeleteAlertRule(ctx context.Context, propertyID string, ruleID string) (bool, error)
+	// CreateWebhook saves a new webhook, assigning it an ID.
+	CreateWebhook(ctx context.Context, webhook *Webhook) error
+	GetWebhooks(ctx context.Context) ([]*Webhook, error)
+	// DeleteWebhook reports whether the webhook existed.
+	DeleteWebhook(ctx context.Context, webhookID string) (bool, error)
+	// CreateDeliveries saves new deliveries, assigning them IDs.
+	CreateDeliveries(ctx context.Context, deliveries []*Delivery) error
+	// UpdateDelivery replaces the status and attempt log of a delivery.
+	UpdateDelivery(ctx context.Context, delivery *Delivery) error
+	// GetDueDeliveries returns the pending deliveries due at or before date.
+	GetDueDeliveries(ctx context.Context, date time.Time) ([]*Delivery, error)
+	// GetDeliveries returns the deliveries matching filter, most recent first.
+	GetDeliveries(ctx context.Context, filter *DeliveryFilter, limit int, offset int) ([]*Delivery, error)
+}
+
+// WebhookSender posts payloads to webhooks.
+type WebhookSender interface {
+	// Send posts payload to url signed with secret, and returns the HTTP status of the answer.
+	// It returns an error if the webhook could not be reached or did not accept the payload.
+	Send(ctx context.Context, url string, secret string, payload []byte) (int, error)
+}
+
+// AlertsConfig holds the configuration for delivering alerts to webhooks
+type AlertsConfig struct {
+	// Interval is how often due deliveries are looked for, besides right after an alert
+	Interval time.Duration `validate:"required"`
+	// MaxAttempts is how many times a delivery is attempted before it is given up on
+	MaxAttempts int `validate:"required,gt=0"`
+	// Backoff is the delay before the first retry of a delivery, doubled after every further attempt
+	Backoff time.Duration `validate:"required"`
+}
+
+// Alerts checks the events saved to properties against their alert rules, and delivers the alerts
+// they trigger to every webhook.
+// A delivery is retried until the webhook accepts it, so a webhook may receive it more than once.
+type Alerts struct {
+	handler *Handler
+	store   AlertStore
+	sender  WebhookSender
+	clock   Clock
+	config  AlertsConfig
+	// due wakes Run up when alerts were triggered.
+	due chan struct{}
+}
+
+func NewAlerts(handler *Handler, store AlertStore, sender WebhookSender, clock Clock, config AlertsConfig) *Alerts {
+	return &Alerts{
+		handler: handler,
+		store:   store,
+		sender:  sender,
+		clock:   clock,
+		config:  config,
+		due:     make(chan struct{}, 1),
+	}
+}
+
+// CreateAlertRule saves a new alert rule of a registered property, in the property currency if
+// it has none. The saved rule, including its ID, is written back to rule.
+func (a *Alerts) CreateAlertRule(ctx context.Context, rule *AlertRule) error {
+	property, err := a.handler.getRegisteredProperty(ctx, rule.PropertyID)
+	if err != nil {
+		return err
+	}
+	if rule.Currency == "" && property != nil {
+		rule.Currency = property.Currency
+	}
+
+	if err := a.validateAlertRule(rule); err != nil {
+		return err
+	}
+
+	if err := a.store.CreateAlertRule(ctx, rule); err != nil {
+		return fmt.Errorf("create alert rule: %v", err)
+	}
+	return nil
+}
+
+func (a *Alerts) GetAlertRule(ctx context.Context, PropertyID string, ruleID string) (*AlertRule, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	} else if ruleID == "" {
+		return nil, fmt.Errorf("empty alert rule ID")
+	}
+
+	rule, exists, err := a.store.GetAlertRule(ctx, PropertyID, ruleID)
+	if err != nil {
+		return nil, fmt.Errorf("get alert rule: %v", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("alert rule %s: %w", ruleID, ErrAlertRuleNotFound)
+	}
+	return rule, nil
+}
+
+func (a *Alerts) GetAlertRules(ctx context.Context, PropertyID string) ([]*AlertRule, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	}
+
+	rules, err := a.store.GetAlertRules(ctx, PropertyID)
+	if err != nil {
+		return nil, fmt.Errorf("get alert rules: %v", err)
+	}
+	return rules, nil
+}
+
+func (a *Alerts) DeleteAlertRule(ctx context.Context, PropertyID string, ruleID string) error {
+	if PropertyID == "" {
+		return fmt.Errorf("empty property ID")
+	} else if ruleID == "" {
+		return fmt.Errorf("empty alert rule ID")
+	}
+
+	exists, err := a.store.DeleteAlertRule(ctx, PropertyID, ruleID)
+	if err != nil {
+		return fmt.Errorf("delete alert rule: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("alert rule %s: %w", ruleID, ErrAlertRuleNotFound)
+	}
+	return nil
+}
+
+// validateAlertRule checks the definition of rule, normalizing its currency.
+func (a *Alerts) validateAlertRule(rule *AlertRule) error {
+	if rule.PropertyID == "" {
+		return fmt.Errorf("%w: empty property ID", ErrInvalid)
+	} else if !rule.Kind.IsValid() {
+		return fmt.Errorf("%w: invalid alert kind %q", ErrInvalid, rule.Kind)
+	} else if rule.Kind == NegativeBalance && !rule.Threshold.IsZero() {
+		return fmt.Errorf("%w: %s rules have no threshold", ErrInvalid, NegativeBalance)
+	} else if rule.Kind != NegativeBalance && !rule.Threshold.IsPositive() {
+		return fmt.Errorf("%w: invalid threshold %v, %s rules need a positive one", ErrInvalid, rule.Threshold, rule.Kind)
+	}
+
+	if rule.Currency == "" {
+		rule.Currency = a.handler.baseCurrency
+	} else {
+		var err error
+		if rule.Currency, err = NormalizeCurrency(rule.Currency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateWebhook registers a webhook every alert is delivered to from then on.
+// The saved webhook, including its ID, is written back to webhook.
+func (a *Alerts) CreateWebhook(ctx context.Context, webhook *Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: invalid webhook URL %q", ErrInvalid, webhook.URL)
+	} else if len(webhook.Secret) < minWebhookSecretLength {
+		return fmt.Errorf("%w: webhook secret shorter than %d characters", ErrInvalid, minWebhookSecretLength)
+	}
+
+	if err := a.store.CreateWebhook(ctx, webhook); err != nil {
+		return fmt.Errorf("create webhook: %v", err)
+	}
+	return nil
+}
+
+func (a *Alerts) GetWebhooks(ctx context.Context) ([]*Webhook, error) {
+	webhooks, err := a.store.GetWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get webhooks: %v", err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook unregisters a webhook. Its pending deliveries fail on their next attempt.
+func (a *Alerts) DeleteWebhook(ctx context.Context, webhookID string) error {
+	if webhookID == "" {
+		return fmt.Errorf("empty webhook ID")
+	}
+
+	exists, err := a.store.DeleteWebhook(ctx, webhookID)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("webhook %s: %w", webhookID, ErrWebhookNotFound)
+	}
+	return nil
+}
+
+// GetDeliveries returns the deliveries matching filter, most recent first.
+func (a *Alerts) GetDeliveries(ctx context.Context, filter *DeliveryFilter, offset int, limit int) ([]*Delivery, error) {
+	deliveries, err := a.store.GetDeliveries(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("get deliveries: %v", err)
+	}
+	return deliveries, nil
+}
+
+// EventSaved checks event against the alert rules of its property, and queues a delivery of
+// every alert it triggers to every webhook. Failures are logged, as the event is already saved.
+func (a *Alerts) EventSaved(ctx context.Context, event *Event) {
+	n, err := a.checkEvent(ctx, event)
+	if err != nil {
+		slog.Error("failed to check alert rules",
+			slog.String("property_id", event.PropertyID), slog.String("event_id", event.ID), slog.String("err", err.Error()))
+	}
+	if n > 0 {
+		select {
+		case a.due <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// checkEvent queues the deliveries of the alerts triggered by event, and returns how many it queued.
+func (a *Alerts) checkEvent(ctx context.Context, event *Event) (int, error) {
+	rules, err := a.store.GetAlertRules(ctx, event.PropertyID)
+	if err != nil {
+		return 0, fmt.Errorf("get alert rules: %v", err)
+	}
+	rules = filterRules(rules, event.Currency)
+	if len(rules) == 0 {
+		return 0, nil
+	}
+
+	// the event may be backdated, so the current balance is that of the ledger head in its currency
+	current, _, err := a.handler.store.GetMostRecentEventForFilter(ctx, &EventFilter{
+		PropertyID: event.PropertyID,
+		Currency:   event.Currency,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("get events for filter: %v", err)
+	}
+	balance := event.PostEventBalance
+	if current != nil {
+		balance = current.PostEventBalance
+	}
+	previous, err := balance.Sub(event.EventAmount)
+	if err != nil {
+		return 0, fmt.Errorf("compute previous balance: %w", err)
+	}
+
+	now := a.clock.Now()
+	var alerts []*Alert
+	for _, rule := range rules {
+		if !rule.triggeredBy(event, balance, previous) {
+			continue
+		}
+		alerts = append(alerts, &Alert{
+			RuleID:      rule.ID,
+			PropertyID:  event.PropertyID,
+			Kind:        rule.Kind,
+			EventID:     event.ID,
+			EventAmount: event.EventAmount,
+			Balance:     balance,
+			Threshold:   rule.Threshold,
+			Currency:    event.Currency,
+			TriggeredAt: now,
+		})
+	}
+	if len(alerts) == 0 {
+		return 0, nil
+	}
+
+	webhooks, err := a.store.GetWebhooks(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get webhooks: %v", err)
+	}
+	var deliveries []*Delivery
+	for _, alert := range alerts {
+		for _, webhook := range webhooks {
+			deliveries = append(deliveries, &Delivery{
+				WebhookID:   webhook.ID,
+				URL:         webhook.URL,
+				Alert:       alert,
+				Status:      DeliveryPending,
+				NextAttempt: now,
+				CreatedAt:   now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+	if err := a.store.CreateDeliveries(ctx, deliveries); err != nil {
+		return 0, fmt.Errorf("create deliveries: %v", err)
+	}
+	return len(deliveries), nil
+}
+
+func filterRules(rules []*AlertRule, currency string) []*AlertRule {
+	var filtered []*AlertRule
+	for _, rule := range rules {
+		if rule.Currency == currency {
+			filtered = append(filtered, rule)
+		}
+	}
+	return filtered
+}
+
+// triggeredBy reports whether event triggers the rule, balance being the current balance of the
+// property and previous what it was before the event.
+// Balance rules only trigger when the event takes the balance below their floor, not on every
+// event while it stays there.
+func (r *AlertRule) triggeredBy(event *Event, balance Money, previous Money) bool {
+	switch r.Kind {
+	case NegativeBalance:
+		return balance.IsNegative() && !previous.IsNegative()
+	case BalanceBelow:
+		return balance.Cmp(r.Threshold) < 0 && previous.Cmp(r.Threshold) >= 0
+	case ExpenseAbove:
+		expense, err := event.EventAmount.Neg()
+		return err == nil && expense.Cmp(r.Threshold) > 0
+	}
+	return false
+}
+
+// DeliverDue attempts every delivery due by now, according to the clock of the alerts, and
+// returns how many were delivered.
+// A failed attempt is retried after a delay doubling with every attempt, until
+// AlertsConfig.MaxAttempts attempts were made.
+func (a *Alerts) DeliverDue(ctx context.Context) (int, error) {
+	due, err := a.store.GetDueDeliveries(ctx, a.clock.Now())
+	if err != nil {
+		return 0, fmt.Errorf("get due deliveries: %v", err)
+	}
+	if len(due) == 0 {
+		return 0, nil
+	}
+
+	webhooks, err := a.store.GetWebhooks(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get webhooks: %v", err)
+	}
+	secrets := make(map[string]string, len(webhooks))
+	for _, webhook := range webhooks {
+		secrets[webhook.ID] = webhook.Secret
+	}
+
+	delivered := 0
+	var errs []error
+	for _, delivery := range due {
+		secret, registered := secrets[delivery.WebhookID]
+		a.attempt(ctx, delivery, secret, registered)
+		if delivery.Status == DeliveryDelivered {
+			delivered++
+		}
+		if err := a.store.UpdateDelivery(ctx, delivery); err != nil {
+			errs = append(errs, fmt.Errorf("delivery %s: update delivery: %v", delivery.ID, err))
+		}
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// attempt posts delivery to its webhook, recording the outcome in delivery.
+func (a *Alerts) attempt(ctx context.Context, delivery *Delivery, secret string, registered bool) {
+	if !registered {
+		delivery.Status = DeliveryFailed
+		delivery.LastError = fmt.Sprintf("webhook %s: %v", delivery.WebhookID, ErrWebhookNotFound)
+		return
+	}
+
+	delivery.Attempts++
+	payload, err := json.Marshal(&deliveryPayload{DeliveryID: delivery.ID, Alert: delivery.Alert})
+	if err == nil {
+		delivery.StatusCode, err = a.sender.Send(ctx, delivery.URL, secret, payload)
+	}
+	now := a.clock.Now()
+	if err == nil {
+		delivery.Status = DeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= a.config.MaxAttempts {
+		delivery.Status = DeliveryFailed
+		return
+	}
+	delivery.NextAttempt = now.Add(a.config.Backoff << (delivery.Attempts - 1))
+}
+
+// Run calls DeliverDue every interval, and whenever an event triggered alerts, until ctx is done.
+func (a *Alerts) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := a.DeliverDue(ctx); err != nil {
+			slog.Error("failed to deliver alerts", slog.String("err", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-a.due:
+		}
+	}
+}
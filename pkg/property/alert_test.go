@@ -0,0 +1,346 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/samber/lo"
+)
+
+type MockAlertStore struct {
+	rules      []*AlertRule
+	webhooks   []*Webhook
+	deliveries []*Delivery
+}
+
+func (m *MockAlertStore) CreateAlertRule(ctx context.Context, rule *AlertRule) error {
+	rule.ID = gofakeit.UUID()
+	c := *rule
+	m.rules = append(m.rules, &c)
+	return nil
+}
+
+func (m *MockAlertStore) GetAlertRule(ctx context.Context, propertyID string, ruleID string) (*AlertRule, bool, error) {
+	r, ok := lo.Find(m.rules, func(r *AlertRule) bool {
+		return r.ID == ruleID && r.PropertyID == propertyID
+	})
+	return r, ok, nil
+}
+
+func (m *MockAlertStore) GetAlertRules(ctx context.Context, propertyID string) ([]*AlertRule, error) {
+	return lo.Filter(m.rules, func(r *AlertRule, _ int) bool {
+		return r.PropertyID == propertyID
+	}), nil
+}
+
+func (m *MockAlertStore) DeleteAlertRule(ctx context.Context, propertyID string, ruleID string) (bool, error) {
+	n := len(m.rules)
+	m.rules = lo.Reject(m.rules, func(r *AlertRule, _ int) bool {
+		return r.ID == ruleID && r.PropertyID == propertyID
+	})
+	return len(m.rules) < n, nil
+}
+
+func (m *MockAlertStore) CreateWebhook(ctx context.Context, webhook *Webhook) error {
+	webhook.ID = gofakeit.UUID()
+	c := *webhook
+	m.webhooks = append(m.webhooks, &c)
+	return nil
+}
+
+func (m *MockAlertStore) GetWebhooks(ctx context.Context) ([]*Webhook, error) {
+	return m.webhooks, nil
+}
+
+func (m *MockAlertStore) DeleteWebhook(ctx context.Context, webhookID string) (bool, error) {
+	n := len(m.webhooks)
+	m.webhooks = lo.Reject(m.webhooks, func(w *Webhook, _ int) bool {
+		return w.ID == webhookID
+	})
+	return len(m.webhooks) < n, nil
+}
+
+func (m *MockAlertStore) CreateDeliveries(ctx context.Context, deliveries []*Delivery) error {
+	for _, d := range deliveries {
+		d.ID = gofakeit.UUID()
+		c := *d
+		m.deliveries = append(m.deliveries, &c)
+	}
+	return nil
+}
+
+func (m *MockAlertStore) UpdateDelivery(ctx context.Context, delivery *Delivery) error {
+	i := slices.IndexFunc(m.deliveries, func(d *Delivery) bool {
+		return d.ID == delivery.ID
+	})
+	if i < 0 {
+		return errors.New("no such delivery")
+	}
+	c := *delivery
+	m.deliveries[i] = &c
+	return nil
+}
+
+func (m *MockAlertStore) GetDueDeliveries(ctx context.Context, date time.Time) ([]*Delivery, error) {
+	due := lo.Filter(m.deliveries, func(d *Delivery, _ int) bool {
+		return d.Status == DeliveryPending && !d.NextAttempt.After(date)
+	})
+	return lo.Map(due, func(d *Delivery, _ int) *Delivery {
+		c := *d
+		return &c
+	}), nil
+}
+
+func (m *MockAlertStore) GetDeliveries(ctx context.Context, filter *DeliveryFilter, limit int, offset int) ([]*Delivery, error) {
+	return lo.Filter(m.deliveries, func(d *Delivery, _ int) bool {
+		return (filter.WebhookID == "" || d.WebhookID == filter.WebhookID) &&
+			(filter.PropertyID == "" || d.Alert.PropertyID == filter.PropertyID) &&
+			(filter.Status == "" || d.Status == filter.Status)
+	}), nil
+}
+
+// MockWebhookSender answers the posts to each URL with the statuses queued for it, and 200
+// once they are used up.
+type MockWebhookSender struct {
+	statuses map[string][]int
+	sent     map[string]int
+}
+
+func (m *MockWebhookSender) Send(ctx context.Context, url string, secret string, payload []byte) (int, error) {
+	if m.sent == nil {
+		m.sent = map[string]int{}
+	}
+	m.sent[url]++
+	status := 200
+	if len(m.statuses[url]) > 0 {
+		status, m.statuses[url] = m.statuses[url][0], m.statuses[url][1:]
+	}
+	if status != 200 {
+		return status, errors.New("unexpected status")
+	}
+	return status, nil
+}
+
+func TestAlerts_EventSaved(t *testing.T) {
+	date := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		rules     []*AlertRule
+		balance   string
+		amount    string
+		currency  string
+		wantKinds []AlertKind
+	}{
+		{
+			name:      "balance goes negative",
+			rules:     []*AlertRule{{Kind: NegativeBalance, Currency: "USD"}},
+			balance:   "100",
+			amount:    "-150",
+			wantKinds: []AlertKind{NegativeBalance},
+		},
+		{
+			name:    "balance stays negative",
+			rules:   []*AlertRule{{Kind: NegativeBalance, Currency: "USD"}},
+			balance: "-100",
+			amount:  "-50",
+		},
+		{
+			name:      "balance goes below the floor",
+			rules:     []*AlertRule{{Kind: BalanceBelow, Threshold: mustMoney("500"), Currency: "USD"}},
+			balance:   "600",
+			amount:    "-200",
+			wantKinds: []AlertKind{BalanceBelow},
+		},
+		{
+			name:    "balance reaches the floor",
+			rules:   []*AlertRule{{Kind: BalanceBelow, Threshold: mustMoney("500"), Currency: "USD"}},
+			balance: "600",
+			amount:  "-100",
+		},
+		{
+			name:      "expense above the limit",
+			rules:     []*AlertRule{{Kind: ExpenseAbove, Threshold: mustMoney("1000"), Currency: "USD"}},
+			balance:   "5000",
+			amount:    "-1000.01",
+			wantKinds: []AlertKind{ExpenseAbove},
+		},
+		{
+			name:    "income above the limit",
+			rules:   []*AlertRule{{Kind: ExpenseAbove, Threshold: mustMoney("1000"), Currency: "USD"}},
+			balance: "5000",
+			amount:  "2000",
+		},
+		{
+			name:     "rule in another currency",
+			rules:    []*AlertRule{{Kind: NegativeBalance, Currency: "EUR"}},
+			balance:  "100",
+			amount:   "-150",
+			currency: "USD",
+		},
+		{
+			name: "several rules triggered",
+			rules: []*AlertRule{
+				{Kind: NegativeBalance, Currency: "USD"},
+				{Kind: ExpenseAbove, Threshold: mustMoney("100"), Currency: "USD"},
+			},
+			balance:   "100",
+			amount:    "-150",
+			wantKinds: []AlertKind{NegativeBalance, ExpenseAbove},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := NewMockEventStore([]*Event{
+				{ID: "1", PropertyID: "propID", EventAmount: mustMoney(tt.balance), PostEventBalance: mustMoney(tt.balance), Currency: "USD", Date: date, Sequence: 1},
+			}, false)
+			h := NewHandler(events, nil, nil, "USD")
+			store := &MockAlertStore{webhooks: []*Webhook{{ID: "hook", URL: "http://localhost/hook", Secret: "0123456789abcdef"}}}
+			alerts := NewAlerts(h, store, &MockWebhookSender{}, &MockClock{now: date}, AlertsConfig{MaxAttempts: 3, Backoff: time.Minute})
+			h.Observe(alerts)
+			for _, rule := range tt.rules {
+				rule.PropertyID = "propID"
+				if err := alerts.CreateAlertRule(context.TODO(), rule); err != nil {
+					t.Fatalf("CreateAlertRule() unexpected error = %v", err)
+				}
+			}
+
+			event := &Event{PropertyID: "propID", EventAmount: mustMoney(tt.amount), Currency: tt.currency, Date: date.AddDate(0, 0, 1), IdempotencyKey: "key"}
+			if _, err := h.SaveEvent(context.TODO(), event); err != nil {
+				t.Fatalf("SaveEvent() unexpected error = %v", err)
+			}
+			// a replayed save is not a new event, and triggers nothing
+			if _, err := h.SaveEvent(context.TODO(), &Event{PropertyID: "propID", EventAmount: mustMoney(tt.amount), Currency: tt.currency, Date: date.AddDate(0, 0, 1), IdempotencyKey: "key"}); err != nil {
+				t.Fatalf("SaveEvent() unexpected error = %v", err)
+			}
+
+			gotKinds := lo.Map(store.deliveries, func(d *Delivery, _ int) AlertKind {
+				return d.Alert.Kind
+			})
+			if !slices.Equal(gotKinds, tt.wantKinds) {
+				t.Errorf("EventSaved() got alerts %v, want %v", gotKinds, tt.wantKinds)
+			}
+			for _, d := range store.deliveries {
+				if d.Alert.EventID != event.ID || d.WebhookID != "hook" || d.Status != DeliveryPending {
+					t.Errorf("EventSaved() got delivery %+v, want a pending delivery of event %s to hook", d, event.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestAlerts_DeliverDue(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	clock := &MockClock{now: start}
+	store := &MockAlertStore{
+		webhooks: []*Webhook{
+			{ID: "up", URL: "http://localhost/up", Secret: "0123456789abcdef"},
+			{ID: "flaky", URL: "http://localhost/flaky", Secret: "0123456789abcdef"},
+			{ID: "down", URL: "http://localhost/down", Secret: "0123456789abcdef"},
+		},
+	}
+	sender := &MockWebhookSender{statuses: map[string][]int{
+		"http://localhost/flaky": {503},
+		"http://localhost/down":  {500, 500, 500},
+	}}
+	alerts := NewAlerts(NewHandler(nil, nil, nil, "USD"), store, sender, clock, AlertsConfig{MaxAttempts: 3, Backoff: time.Minute})
+	alert := &Alert{PropertyID: "propID", Kind: NegativeBalance, Currency: "USD"}
+	_ = store.CreateDeliveries(context.TODO(), lo.Map(store.webhooks, func(w *Webhook, _ int) *Delivery {
+		return &Delivery{WebhookID: w.ID, URL: w.URL, Alert: alert, Status: DeliveryPending, NextAttempt: start}
+	}))
+
+	steps := []struct {
+		after         time.Duration
+		wantDelivered int
+		wantStatus    map[string]DeliveryStatus
+		wantAttempts  map[string]int
+	}{
+		{
+			after:         0,
+			wantDelivered: 1,
+			wantStatus:    map[string]DeliveryStatus{"up": DeliveryDelivered, "flaky": DeliveryPending, "down": DeliveryPending},
+			wantAttempts:  map[string]int{"up": 1, "flaky": 1, "down": 1},
+		},
+		{
+			// the first retry is due after a minute
+			after:         time.Minute - time.Second,
+			wantDelivered: 0,
+			wantStatus:    map[string]DeliveryStatus{"up": DeliveryDelivered, "flaky": DeliveryPending, "down": DeliveryPending},
+			wantAttempts:  map[string]int{"up": 1, "flaky": 1, "down": 1},
+		},
+		{
+			after:         time.Minute,
+			wantDelivered: 1,
+			wantStatus:    map[string]DeliveryStatus{"up": DeliveryDelivered, "flaky": DeliveryDelivered, "down": DeliveryPending},
+			wantAttempts:  map[string]int{"up": 1, "flaky": 2, "down": 2},
+		},
+		{
+			// the second retry is due two minutes after the first
+			after:         time.Minute + 2*time.Minute,
+			wantDelivered: 0,
+			wantStatus:    map[string]DeliveryStatus{"up": DeliveryDelivered, "flaky": DeliveryDelivered, "down": DeliveryFailed},
+			wantAttempts:  map[string]int{"up": 1, "flaky": 2, "down": 3},
+		},
+	}
+	for _, step := range steps {
+		clock.now = start.Add(step.after)
+		delivered, err := alerts.DeliverDue(context.TODO())
+		if err != nil {
+			t.Fatalf("DeliverDue() unexpected error = %v", err)
+		}
+		if delivered != step.wantDelivered {
+			t.Errorf("DeliverDue() after %v got %d delivered, want %d", step.after, delivered, step.wantDelivered)
+		}
+		for _, d := range store.deliveries {
+			if d.Status != step.wantStatus[d.WebhookID] || d.Attempts != step.wantAttempts[d.WebhookID] {
+				t.Errorf("DeliverDue() after %v got %s %s after %d attempts, want %s after %d", step.after,
+					d.WebhookID, d.Status, d.Attempts, step.wantStatus[d.WebhookID], step.wantAttempts[d.WebhookID])
+			}
+		}
+	}
+
+	failed, err := alerts.GetDeliveries(context.TODO(), &DeliveryFilter{Status: DeliveryFailed}, 0, 0)
+	if err != nil {
+		t.Fatalf("GetDeliveries() unexpected error = %v", err)
+	}
+	if len(failed) != 1 || failed[0].WebhookID != "down" || failed[0].StatusCode != 500 || failed[0].LastError == "" {
+		t.Errorf("GetDeliveries() got %+v, want the failed delivery to down", failed)
+	}
+}
+
+func TestAlerts_Invalid(t *testing.T) {
+	alerts := NewAlerts(NewHandler(NewMockEventStore(nil, false), nil, nil, "USD"), &MockAlertStore{}, &MockWebhookSender{},
+		SystemClock{}, AlertsConfig{MaxAttempts: 3, Backoff: time.Minute})
+
+	rules := map[string]*AlertRule{
+		"unknown kind":                 {PropertyID: "propID", Kind: "balance_above"},
+		"negative balance threshold":   {PropertyID: "propID", Kind: NegativeBalance, Threshold: mustMoney("10")},
+		"balance below no threshold":   {PropertyID: "propID", Kind: BalanceBelow},
+		"balance below negative":       {PropertyID: "propID", Kind: BalanceBelow, Threshold: mustMoney("-10")},
+		"expense above zero threshold": {PropertyID: "propID", Kind: ExpenseAbove},
+	}
+	for name, rule := range rules {
+		t.Run(name, func(t *testing.T) {
+			if err := alerts.CreateAlertRule(context.TODO(), rule); !errors.Is(err, ErrInvalid) {
+				t.Errorf("CreateAlertRule() error = %v, want %v", err, ErrInvalid)
+			}
+		})
+	}
+
+	webhooks := map[string]*Webhook{
+		"relative URL": {URL: "/hook", Secret: "0123456789abcdef"},
+		"ftp URL":      {URL: "ftp://localhost/hook", Secret: "0123456789abcdef"},
+		"short secret": {URL: "http://localhost/hook", Secret: "secret"},
+	}
+	for name, webhook := range webhooks {
+		t.Run(name, func(t *testing.T) {
+			if err := alerts.CreateWebhook(context.TODO(), webhook); !errors.Is(err, ErrInvalid) {
+				t.Errorf("CreateWebhook() error = %v, want %v", err, ErrInvalid)
+			}
+		})
+	}
+}
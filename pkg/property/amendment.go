@@ -0,0 +1,185 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotAmendable is returned when amending a reversed event, a reversal, or a transfer leg.
+var ErrNotAmendable = errors.New("event cannot be amended")
+
+// Amendment is a correction of a saved event. Only the fields that are set are changed.
+type Amendment struct {
+	EventAmount *Money
+	Date        *time.Time
+	Description *string
+	// AmendedBy identifies who made the amendment.
+	AmendedBy string
+	// Reason explains why the event was amended.
+	Reason string
+}
+
+// EventRevision is a prior version of an amended event.
+type EventRevision struct {
+	EventID    string `json:"event_id" bson:"event_id"`
+	PropertyID string `json:"property_id" bson:"property_id"`
+	// Revision is the revision of Event, the event as saved before the amendment.
+	Revision int    `json:"revision" bson:"revision"`
+	Event    *Event `json:"event" bson:"event"`
+	// AmendedBy, Reason and AmendedAt describe the amendment that replaced Event.
+	AmendedBy string    `json:"amended_by" bson:"amended_by"`
+	Reason    string    `json:"reason" bson:"reason"`
+	AmendedAt time.Time `json:"amended_at" bson:"amended_at"`
+}
+
+// AmendEvent corrects the amount, date or description of an event, keeping its prior version
+// in the event history, and recomputes the running balance of every later event in its currency,
+// in a single ledger write. It returns the amended event. An amendment lowering balances is subject
+// to the overdraft policy of the property, as an expense is.
+func (h *Handler) AmendEvent(ctx context.Context, PropertyID string, eventID string, amendment *Amendment) (*Event, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("%w: empty property ID", ErrInvalid)
+	} else if eventID == "" {
+		return nil, fmt.Errorf("%w: empty event ID", ErrInvalid)
+	} else if amendment.AmendedBy == "" {
+		return nil, fmt.Errorf("%w: empty amended by", ErrInvalid)
+	} else if amendment.EventAmount == nil && amendment.Date == nil && amendment.Description == nil {
+		return nil, fmt.Errorf("%w: nothing to amend", ErrInvalid)
+	} else if amendment.EventAmount != nil && amendment.EventAmount.IsZero() {
+		return nil, fmt.Errorf("%w: invalid amount, reverse the event instead", ErrInvalid)
+	} else if amendment.Date != nil && amendment.Date.IsZero() {
+		return nil, fmt.Errorf("%w: invalid date", ErrInvalid)
+	}
+
+	var amended *Event
+	err := retryOnConflict(ctx, func() error {
+		original, err := h.getEvent(ctx, PropertyID, eventID)
+		if err != nil {
+			return err
+		}
+		if original.Reversed {
+			return fmt.Errorf("event %s is reversed: %w", eventID, ErrNotAmendable)
+		} else if original.ReversalOf != "" {
+			return fmt.Errorf("event %s is a reversal: %w", eventID, ErrNotAmendable)
+		} else if original.TransferID != "" {
+			return fmt.Errorf("event %s is part of transfer %s: %w", eventID, original.TransferID, ErrNotAmendable)
+		}
+
+		var write *LedgerWrite
+		if write, amended, err = h.planAmend(ctx, original, amendment); err != nil {
+			return err
+		}
+		return h.writeLedger(ctx, PropertyID, write)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("amend event: %w", err)
+	}
+	return amended, nil
+}
+
+// planAmend computes the ledger write replacing original with its amended version: the running
+// balance of every event in its currency from the earlier of its old and new dates onwards.
+// An event whose amount or date is amended is saved again, so it takes the sequence following
+// the ledger head. Otherwise it keeps its sequence, and with it its place among the events of its date.
+func (h *Handler) planAmend(ctx context.Context, original *Event, amendment *Amendment) (*LedgerWrite, *Event, error) {
+	head, exists, err := h.store.GetLedgerHead(ctx, original.PropertyID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get ledger head: %v", err)
+	}
+	if !exists {
+		return nil, nil, ErrConflict
+	}
+
+	amended := *original
+	if amendment.EventAmount != nil {
+		amended.EventAmount = *amendment.EventAmount
+	}
+	if amendment.Date != nil {
+		amended.Date = *amendment.Date
+	}
+	if amendment.Description != nil {
+		amended.Description = *amendment.Description
+	}
+	if amended.EventAmount != original.EventAmount || !amended.Date.Equal(original.Date) {
+		amended.Sequence = head.Sequence + 1
+	}
+	amended.Revision++
+
+	from := original.Date
+	if amended.Date.Before(from) {
+		from = amended.Date
+	}
+	affected, err := h.store.GetEventsForFilter(ctx, &EventFilter{
+		PropertyID: original.PropertyID,
+		AfterTime:  from,
+		Currency:   original.Currency,
+	}, Ascending, 0, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get events for filter: %v", err)
+	}
+	if len(affected) == 0 {
+		return nil, nil, ErrConflict
+	}
+	sortByDateAsc(affected)
+
+	// the balance before the first affected event, which the amendment does not change
+	balance, err := affected[0].PostEventBalance.Sub(affected[0].EventAmount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("compute balance: %w", err)
+	}
+
+	for i, e := range affected {
+		if e.ID == amended.ID {
+			affected[i] = &amended
+		}
+	}
+	sortByDateAsc(affected)
+
+	write := &LedgerWrite{
+		Head: head,
+		Revisions: []*EventRevision{{
+			EventID:    original.ID,
+			PropertyID: original.PropertyID,
+			Revision:   original.Revision,
+			Event:      original,
+			AmendedBy:  amendment.AmendedBy,
+			Reason:     amendment.Reason,
+			AmendedAt:  time.Now(),
+		}},
+		event: &amended,
+	}
+	for _, e := range affected {
+		if balance, err = balance.Add(e.EventAmount); err != nil {
+			return nil, nil, fmt.Errorf("recompute balance: %w", err)
+		}
+		if e == &amended || e.PostEventBalance != balance {
+			if balance.Cmp(e.PostEventBalance) < 0 {
+				write.lowered = append(write.lowered, e)
+			}
+			e.PostEventBalance = balance
+			write.Update = append(write.Update, e)
+		}
+	}
+	return write, &amended, nil
+}
+
+// GetEventHistory returns an event of the property along with its prior versions, oldest first.
+func (h *Handler) GetEventHistory(ctx context.Context, PropertyID string, eventID string) (*Event, []*EventRevision, error) {
+	if PropertyID == "" {
+		return nil, nil, fmt.Errorf("empty property ID")
+	} else if eventID == "" {
+		return nil, nil, fmt.Errorf("empty event ID")
+	}
+
+	event, err := h.getEvent(ctx, PropertyID, eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	revisions, err := h.store.GetEventRevisions(ctx, PropertyID, eventID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get event revisions: %v", err)
+	}
+	return event, revisions, nil
+}
@@ -0,0 +1,172 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"github.com/brianvoe/gofakeit/v7"
+	"testing"
+	"time"
+)
+
+func TestHandler_AmendEvent(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+	}
+	amount := mustMoney("-300")
+	lateDate := day(30).Add(24 * time.Hour)
+	description := "plumber, second visit"
+
+	tests := []struct {
+		name      string
+		amendment *Amendment
+		// wantOrder are the event amounts in ledger order, and wantBalances their running balances
+		wantOrder    []string
+		wantBalances []string
+		wantErr      bool
+	}{
+		{
+			name:         "amount",
+			amendment:    &Amendment{EventAmount: &amount, AmendedBy: "alice", Reason: "invoice corrected"},
+			wantOrder:    []string{"1000.00", "-300.00", "40.00"},
+			wantBalances: []string{"1000.00", "700.00", "740.00"},
+		},
+		{
+			name:         "date moves the event later",
+			amendment:    &Amendment{Date: &lateDate, AmendedBy: "alice", Reason: "paid later"},
+			wantOrder:    []string{"1000.00", "40.00", "-250.00"},
+			wantBalances: []string{"1000.00", "1040.00", "790.00"},
+		},
+		{
+			name:         "description only",
+			amendment:    &Amendment{Description: &description, AmendedBy: "alice", Reason: "typo"},
+			wantOrder:    []string{"1000.00", "-250.00", "40.00"},
+			wantBalances: []string{"1000.00", "750.00", "790.00"},
+		},
+		{
+			name:      "nothing to amend",
+			amendment: &Amendment{AmendedBy: "alice"},
+			wantErr:   true,
+		},
+		{
+			name:      "zero amount",
+			amendment: &Amendment{EventAmount: &Money{}, AmendedBy: "alice"},
+			wantErr:   true,
+		},
+		{
+			name:      "no author",
+			amendment: &Amendment{EventAmount: &amount},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			propertyID := gofakeit.Address().Address
+			h := NewHandler(NewMockEventStore(nil, false), nil, nil, "USD")
+			var saved []*Event
+			for i, amount := range []string{"1000", "-250", "40"} {
+				event := &Event{PropertyID: propertyID, EventAmount: mustMoney(amount), Date: day(10 * (i + 1))}
+				if _, err := h.SaveEvent(context.TODO(), event); err != nil {
+					t.Fatalf("SaveEvent() unexpected error = %v", err)
+				}
+				saved = append(saved, event)
+			}
+
+			amended, err := h.AmendEvent(context.TODO(), propertyID, saved[1].ID, tt.amendment)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AmendEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Errorf("AmendEvent() error = %v, want %v", err, ErrInvalid)
+				}
+				return
+			}
+			if amended.Revision != 1 {
+				t.Errorf("AmendEvent() revision got = %v, want %v", amended.Revision, 1)
+			}
+
+			events, err := h.GetPropertyEvents(context.TODO(), &EventFilter{PropertyID: propertyID}, Ascending, 0, 0)
+			if err != nil {
+				t.Fatalf("GetPropertyEvents() unexpected error = %v", err)
+			}
+			if len(events) != len(tt.wantOrder) {
+				t.Fatalf("GetPropertyEvents() got %d events, want %d", len(events), len(tt.wantOrder))
+			}
+			for i, e := range events {
+				if e.EventAmount.String() != tt.wantOrder[i] || e.PostEventBalance.String() != tt.wantBalances[i] {
+					t.Errorf("event %d got = %v/%v, want %v/%v", i, e.EventAmount, e.PostEventBalance, tt.wantOrder[i], tt.wantBalances[i])
+				}
+			}
+
+			current, revisions, err := h.GetEventHistory(context.TODO(), propertyID, saved[1].ID)
+			if err != nil {
+				t.Fatalf("GetEventHistory() unexpected error = %v", err)
+			}
+			if current.Revision != 1 || len(revisions) != 1 {
+				t.Fatalf("GetEventHistory() got revision %d with %d prior versions, want 1 with 1", current.Revision, len(revisions))
+			}
+			prior := revisions[0]
+			if prior.Revision != 0 || prior.Event.EventAmount != mustMoney("-250") || !prior.Event.Date.Equal(day(20)) ||
+				prior.AmendedBy != tt.amendment.AmendedBy || prior.Reason != tt.amendment.Reason {
+				t.Errorf("GetEventHistory() got prior version %+v", prior)
+			}
+		})
+	}
+}
+
+func TestHandler_AmendEvent_Reversed(t *testing.T) {
+	propertyID := gofakeit.Address().Address
+	h := NewHandler(NewMockEventStore(nil, false), nil, nil, "USD")
+	event := &Event{PropertyID: propertyID, EventAmount: mustMoney("-250"), Date: time.Now().Add(-time.Hour)}
+	if _, err := h.SaveEvent(context.TODO(), event); err != nil {
+		t.Fatalf("SaveEvent() unexpected error = %v", err)
+	}
+	compensating, err := h.ReverseEvent(context.TODO(), propertyID, event.ID, "")
+	if err != nil {
+		t.Fatalf("ReverseEvent() unexpected error = %v", err)
+	}
+
+	amount := mustMoney("-200")
+	for _, id := range []string{event.ID, compensating.ID} {
+		_, err := h.AmendEvent(context.TODO(), propertyID, id, &Amendment{EventAmount: &amount, AmendedBy: "alice"})
+		if !errors.Is(err, ErrNotAmendable) {
+			t.Errorf("AmendEvent() error = %v, want %v", err, ErrNotAmendable)
+		}
+	}
+	if _, err := h.AmendEvent(context.TODO(), propertyID, "missing", &Amendment{EventAmount: &amount, AmendedBy: "alice"}); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("AmendEvent() of a missing event error = %v, want %v", err, ErrEventNotFound)
+	}
+}
+
+func TestHandler_AmendEvent_KeepsSequence(t *testing.T) {
+	propertyID := gofakeit.Address().Address
+	h := NewHandler(NewMockEventStore(nil, false), nil, nil, "USD")
+	date := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+	var saved []*Event
+	for _, amount := range []string{"1000", "-250", "40"} {
+		event := &Event{PropertyID: propertyID, EventAmount: mustMoney(amount), Date: date}
+		if _, err := h.SaveEvent(context.TODO(), event); err != nil {
+			t.Fatalf("SaveEvent() unexpected error = %v", err)
+		}
+		saved = append(saved, event)
+	}
+
+	description := "plumber"
+	amended, err := h.AmendEvent(context.TODO(), propertyID, saved[0].ID, &Amendment{Description: &description, AmendedBy: "alice"})
+	if err != nil {
+		t.Fatalf("AmendEvent() unexpected error = %v", err)
+	}
+	if amended.Sequence != saved[0].Sequence {
+		t.Errorf("AmendEvent() sequence got = %v, want %v", amended.Sequence, saved[0].Sequence)
+	}
+
+	events, err := h.GetPropertyEvents(context.TODO(), &EventFilter{PropertyID: propertyID}, Ascending, 0, 0)
+	if err != nil {
+		t.Fatalf("GetPropertyEvents() unexpected error = %v", err)
+	}
+	for i, e := range events {
+		if e.ID != saved[i].ID || e.PostEventBalance != saved[i].PostEventBalance {
+			t.Errorf("event %d got = %v/%v, want %v/%v", i, e.ID, e.PostEventBalance, saved[i].ID, saved[i].PostEventBalance)
+		}
+	}
+}
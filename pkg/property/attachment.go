@@ -0,0 +1,252 @@
+package property
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrAttachmentNotFound is returned when an attachment ID does not match any attachment of the property.
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	// ErrAttachmentTooLarge is returned for an upload larger than AttachmentsConfig.MaxSize.
+	ErrAttachmentTooLarge = errors.New("attachment too large")
+	// ErrAttachmentEmpty is returned for an upload with no content.
+	ErrAttachmentEmpty = errors.New("attachment is empty")
+	// ErrUnsupportedContentType is returned for an upload whose content is neither a PDF document
+	// nor an image.
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+	// ErrBlobNotFound is returned by a BlobStore holding no blob under a key.
+	ErrBlobNotFound = errors.New("blob not found")
+)
+
+// attachmentContentTypes are the content types attachments can have, as sniffed from their content.
+var attachmentContentTypes = []string{"application/pdf", "image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// maxFilenameLength bounds the file names kept for attachments.
+const maxFilenameLength = 255
+
+// Attachment is a file, such as a receipt or an invoice, attached to an event.
+type Attachment struct {
+	// ID is assigned by the AttachmentStore when the attachment is created.
+	ID         string `json:"id" bson:"_id"`
+	PropertyID string `json:"property_id" bson:"property_id"`
+	EventID    string `json:"event_id" bson:"event_id"`
+	Filename   string `json:"filename" bson:"filename"`
+	// ContentType is sniffed from the content, whatever the uploader claimed it to be.
+	ContentType string `json:"content_type" bson:"content_type"`
+	Size        int64  `json:"size" bson:"size"`
+	// SHA256 is the hex encoded SHA-256 checksum of the content.
+	SHA256    string    `json:"sha256" bson:"sha256"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// key is the key the content of the attachment is stored under in the BlobStore.
+func (a *Attachment) key() string {
+	return path.Join(a.PropertyID, a.ID)
+}
+
+type AttachmentStore interface {
+	// CreateAttachment saves the metadata of a new attachment, assigning it an ID.
+	CreateAttachment(ctx context.Context, attachment *Attachment) error
+	GetAttachment(ctx context.Context, propertyID string, attachmentID string) (*Attachment, bool, error)
+	// GetAttachments returns the attachments of the events of the property with eventIDs, oldest first.
+	GetAttachments(ctx context.Context, propertyID string, eventIDs []string) ([]*Attachment, error)
+	// DeleteAttachment reports whether the attachment existed.
+	DeleteAttachment(ctx context.Context, propertyID string, attachmentID string) (bool, error)
+}
+
+// BlobStore keeps the content of attachments.
+type BlobStore interface {
+	// Put saves the content of r under key, replacing any blob already there.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the content saved under key, or ErrBlobNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob saved under key, if there is one.
+	Delete(ctx context.Context, key string) error
+}
+
+// AttachmentsConfig holds the configuration for attaching files to events
+type AttachmentsConfig struct {
+	// MaxSize is the size of the largest attachment accepted, in bytes
+	MaxSize int64 `validate:"required,gt=0"`
+}
+
+// Attachments manages the files attached to the events of properties, keeping their metadata in
+// an AttachmentStore and their content in a BlobStore.
+type Attachments struct {
+	handler *Handler
+	store   AttachmentStore
+	blobs   BlobStore
+	clock   Clock
+	config  AttachmentsConfig
+}
+
+func NewAttachments(handler *Handler, store AttachmentStore, blobs BlobStore, clock Clock, config AttachmentsConfig) *Attachments {
+	return &Attachments{
+		handler: handler,
+		store:   store,
+		blobs:   blobs,
+		clock:   clock,
+		config:  config,
+	}
+}
+
+// MaxSize is the size of the largest attachment accepted, in bytes.
+func (a *Attachments) MaxSize() int64 {
+	return a.config.MaxSize
+}
+
+// UploadAttachment attaches content to an event, which must be a PDF document or an image of at
+// most AttachmentsConfig.MaxSize bytes. The saved attachment, including its ID, content type, size
+// and checksum, is written back to attachment.
+func (a *Attachments) UploadAttachment(ctx context.Context, attachment *Attachment, content io.Reader) error {
+	if _, err := a.handler.getEvent(ctx, attachment.PropertyID, attachment.EventID); err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, a.config.MaxSize+1))
+	if err != nil {
+		return fmt.Errorf("read attachment: %w", err)
+	}
+	if int64(len(data)) > a.config.MaxSize {
+		return fmt.Errorf("%w: larger than %d bytes", ErrAttachmentTooLarge, a.config.MaxSize)
+	} else if len(data) == 0 {
+		return ErrAttachmentEmpty
+	}
+	// DetectContentType always returns a valid media type
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if !slices.Contains(attachmentContentTypes, contentType) {
+		return fmt.Errorf("%w %s", ErrUnsupportedContentType, contentType)
+	}
+	sum := sha256.Sum256(data)
+
+	attachment.Filename = attachmentFilename(attachment.Filename)
+	attachment.ContentType = contentType
+	attachment.Size = int64(len(data))
+	attachment.SHA256 = hex.EncodeToString(sum[:])
+	attachment.CreatedAt = a.clock.Now()
+	if err := a.store.CreateAttachment(ctx, attachment); err != nil {
+		return fmt.Errorf("create attachment: %v", err)
+	}
+	if err := a.blobs.Put(ctx, attachment.key(), bytes.NewReader(data)); err != nil {
+		// the content is lost, so neither is the attachment kept
+		if _, deleteErr := a.store.DeleteAttachment(ctx, attachment.PropertyID, attachment.ID); deleteErr != nil {
+			return fmt.Errorf("put blob: %v, and delete attachment: %v", err, deleteErr)
+		}
+		return fmt.Errorf("put blob: %v", err)
+	}
+	return nil
+}
+
+// GetAttachment returns an attachment of the event with eventID.
+func (a *Attachments) GetAttachment(ctx context.Context, PropertyID string, eventID string, attachmentID string) (*Attachment, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	} else if attachmentID == "" {
+		return nil, fmt.Errorf("empty attachment ID")
+	}
+
+	attachment, exists, err := a.store.GetAttachment(ctx, PropertyID, attachmentID)
+	if err != nil {
+		return nil, fmt.Errorf("get attachment: %v", err)
+	}
+	if !exists || attachment.EventID != eventID {
+		return nil, fmt.Errorf("attachment %s: %w", attachmentID, ErrAttachmentNotFound)
+	}
+	return attachment, nil
+}
+
+// GetAttachments returns the attachments of an event, oldest first.
+func (a *Attachments) GetAttachments(ctx context.Context, PropertyID string, eventID string) ([]*Attachment, error) {
+	if _, err := a.handler.getEvent(ctx, PropertyID, eventID); err != nil {
+		return nil, err
+	}
+
+	attachments, err := a.store.GetAttachments(ctx, PropertyID, []string{eventID})
+	if err != nil {
+		return nil, fmt.Errorf("get attachments: %v", err)
+	}
+	return attachments, nil
+}
+
+// GetEventAttachments returns the attachments of the events of the property with eventIDs,
+// by event ID. Events without attachments are left out.
+func (a *Attachments) GetEventAttachments(ctx context.Context, PropertyID string, eventIDs []string) (map[string][]*Attachment, error) {
+	byEvent := make(map[string][]*Attachment)
+	if len(eventIDs) == 0 {
+		return byEvent, nil
+	}
+
+	attachments, err := a.store.GetAttachments(ctx, PropertyID, eventIDs)
+	if err != nil {
+		return nil, fmt.Errorf("get attachments: %v", err)
+	}
+	for _, attachment := range attachments {
+		byEvent[attachment.EventID] = append(byEvent[attachment.EventID], attachment)
+	}
+	return byEvent, nil
+}
+
+// OpenAttachment returns an attachment along with its content, which the caller must close.
+func (a *Attachments) OpenAttachment(ctx context.Context, PropertyID string, eventID string, attachmentID string) (*Attachment, io.ReadCloser, error) {
+	attachment, err := a.GetAttachment(ctx, PropertyID, eventID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := a.blobs.Open(ctx, attachment.key())
+	if err != nil {
+		if errors.Is(err, ErrBlobNotFound) {
+			// the upload of the attachment has not finished, or failed
+			return nil, nil, fmt.Errorf("attachment %s: %w", attachmentID, ErrAttachmentNotFound)
+		}
+		return nil, nil, fmt.Errorf("open blob: %v", err)
+	}
+	return attachment, content, nil
+}
+
+// DeleteAttachment deletes an attachment of the event with eventID, along with its content.
+func (a *Attachments) DeleteAttachment(ctx context.Context, PropertyID string, eventID string, attachmentID string) error {
+	attachment, err := a.GetAttachment(ctx, PropertyID, eventID, attachmentID)
+	if err != nil {
+		return err
+	}
+
+	exists, err := a.store.DeleteAttachment(ctx, PropertyID, attachmentID)
+	if err != nil {
+		return fmt.Errorf("delete attachment: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("attachment %s: %w", attachmentID, ErrAttachmentNotFound)
+	}
+	if err := a.blobs.Delete(ctx, attachment.key()); err != nil {
+		return fmt.Errorf("delete blob: %v", err)
+	}
+	return nil
+}
+
+// attachmentFilename returns the base name of the file name given by the uploader, shortened to
+// maxFilenameLength bytes, or "attachment" if there is none.
+func attachmentFilename(filename string) string {
+	filename = strings.TrimSpace(path.Base(strings.ReplaceAll(filename, "\\", "/")))
+	if filename == "." || filename == "/" || filename == "" {
+		return "attachment"
+	}
+	for len(filename) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(filename)
+		filename = filename[:len(filename)-size]
+	}
+	return filename
+}
@@ -0,0 +1,262 @@
+package property
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/samber/lo"
+)
+
+type MockAttachmentStore struct {
+	attachments map[string]*Attachment
+}
+
+func NewMockAttachmentStore() *MockAttachmentStore {
+	return &MockAttachmentStore{attachments: map[string]*Attachment{}}
+}
+
+func (m *MockAttachmentStore) CreateAttachment(ctx context.Context, attachment *Attachment) error {
+	attachment.ID = gofakeit.UUID()
+	stored := *attachment
+	m.attachments[attachment.ID] = &stored
+	return nil
+}
+
+func (m *MockAttachmentStore) GetAttachment(ctx context.Context, propertyID string, attachmentID string) (*Attachment, bool, error) {
+	a, ok := m.attachments[attachmentID]
+	if !ok || a.PropertyID != propertyID {
+		return nil, false, nil
+	}
+	found := *a
+	return &found, true, nil
+}
+
+func (m *MockAttachmentStore) GetAttachments(ctx context.Context, propertyID string, eventIDs []string) ([]*Attachment, error) {
+	attachments := lo.FilterMap(lo.Values(m.attachments), func(a *Attachment, _ int) (*Attachment, bool) {
+		found := *a
+		return &found, a.PropertyID == propertyID && slices.Contains(eventIDs, a.EventID)
+	})
+	slices.SortFunc(attachments, func(a, b *Attachment) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return attachments, nil
+}
+
+func (m *MockAttachmentStore) DeleteAttachment(ctx context.Context, propertyID string, attachmentID string) (bool, error) {
+	a, ok := m.attachments[attachmentID]
+	if !ok || a.PropertyID != propertyID {
+		return false, nil
+	}
+	delete(m.attachments, attachmentID)
+	return true, nil
+}
+
+type MockBlobStore struct {
+	blobs   map[string][]byte
+	putFail bool
+}
+
+func NewMockBlobStore() *MockBlobStore {
+	return &MockBlobStore{blobs: map[string][]byte{}}
+}
+
+func (m *MockBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	if m.putFail {
+		return errors.New("disk full")
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.blobs[key] = content
+	return nil
+}
+
+func (m *MockBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	content, ok := m.blobs[key]
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (m *MockBlobStore) Delete(ctx context.Context, key string) error {
+	delete(m.blobs, key)
+	return nil
+}
+
+var (
+	testPDF = []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\n%%EOF\n")
+	testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01")
+)
+
+// seedAttachments returns Attachments over a property with a single expense, and the ID of the expense.
+func seedAttachments(t *testing.T, blobs *MockBlobStore) (*Attachments, string) {
+	t.Helper()
+	properties := NewMockPropertyStore(&Property{ID: "elm", Currency: "USD"})
+	h := NewHandler(NewMockEventStore(nil, false), properties, nil, "USD")
+	event := &Event{PropertyID: "elm", EventAmount: mustMoney("-120.50"), Date: time.Now(), Description: "plumber"}
+	if _, err := h.SaveEvent(context.TODO(), event); err != nil {
+		t.Fatalf("SaveEvent() unexpected error = %v", err)
+	}
+	clock := &MockClock{now: time.Date(2024, time.March, 5, 12, 0, 0, 0, time.UTC)}
+	return NewAttachments(h, NewMockAttachmentStore(), blobs, clock, AttachmentsConfig{MaxSize: 1024}), event.ID
+}
+
+func TestAttachments_UploadAttachment(t *testing.T) {
+	tests := []struct {
+		name            string
+		eventID         string
+		filename        string
+		content         []byte
+		wantFilename    string
+		wantContentType string
+		wantErr         error
+	}{
+		{name: "pdf", filename: "invoice.pdf", content: testPDF, wantFilename: "invoice.pdf", wantContentType: "application/pdf"},
+		{name: "image with a windows path", filename: `C:\scans\receipt.png`, content: testPNG,
+			wantFilename: "receipt.png", wantContentType: "image/png"},
+		{name: "no file name", content: testPDF, wantFilename: "attachment", wantContentType: "application/pdf"},
+		{name: "long file name", filename: strings.Repeat("é", 200) + ".pdf", content: testPDF,
+			wantFilename: strings.Repeat("é", 127), wantContentType: "application/pdf"},
+		{name: "pdf claimed as an image", filename: "receipt.png", content: testPDF,
+			wantFilename: "receipt.png", wantContentType: "application/pdf"},
+		{name: "text", filename: "receipt.pdf", content: []byte("paid in cash"), wantErr: ErrUnsupportedContentType},
+		{name: "html", filename: "receipt.pdf", content: []byte("<html><body>paid</body></html>"), wantErr: ErrUnsupportedContentType},
+		{name: "empty", filename: "receipt.pdf", wantErr: ErrAttachmentEmpty},
+		{name: "too large", filename: "receipt.pdf", content: append(slices.Clone(testPDF), make([]byte, 1024)...),
+			wantErr: ErrAttachmentTooLarge},
+		{name: "unknown event", eventID: "nothing", filename: "receipt.pdf", content: testPDF, wantErr: ErrEventNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blobs := NewMockBlobStore()
+			a, eventID := seedAttachments(t, blobs)
+			attachment := &Attachment{
+				PropertyID: "elm",
+				EventID:    lo.Ternary(tt.eventID == "", eventID, tt.eventID),
+				Filename:   tt.filename,
+			}
+
+			err := a.UploadAttachment(context.TODO(), attachment, bytes.NewReader(tt.content))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("UploadAttachment() error = %v, want %v", err, tt.wantErr)
+				}
+				if len(blobs.blobs) != 0 {
+					t.Errorf("UploadAttachment() stored %d blobs, want none", len(blobs.blobs))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UploadAttachment() unexpected error = %v", err)
+			}
+
+			sum := sha256.Sum256(tt.content)
+			want := &Attachment{
+				ID:          attachment.ID,
+				PropertyID:  "elm",
+				EventID:     eventID,
+				Filename:    tt.wantFilename,
+				ContentType: tt.wantContentType,
+				Size:        int64(len(tt.content)),
+				SHA256:      hex.EncodeToString(sum[:]),
+				CreatedAt:   time.Date(2024, time.March, 5, 12, 0, 0, 0, time.UTC),
+			}
+			if attachment.ID == "" || *attachment != *want {
+				t.Errorf("UploadAttachment() got = %+v, want %+v", attachment, want)
+			}
+			if !bytes.Equal(blobs.blobs["elm/"+attachment.ID], tt.content) {
+				t.Errorf("UploadAttachment() did not store the content under elm/%s", attachment.ID)
+			}
+		})
+	}
+}
+
+func TestAttachments_UploadAttachment_BlobFailure(t *testing.T) {
+	blobs := NewMockBlobStore()
+	blobs.putFail = true
+	a, eventID := seedAttachments(t, blobs)
+
+	attachment := &Attachment{PropertyID: "elm", EventID: eventID, Filename: "invoice.pdf"}
+	if err := a.UploadAttachment(context.TODO(), attachment, bytes.NewReader(testPDF)); err == nil {
+		t.Fatalf("UploadAttachment() error = nil, want the blob error")
+	}
+	attachments, err := a.GetAttachments(context.TODO(), "elm", eventID)
+	if err != nil || len(attachments) != 0 {
+		t.Errorf("GetAttachments() got = %v, %v, want no attachment", attachments, err)
+	}
+}
+
+func TestAttachments_Workflow(t *testing.T) {
+	blobs := NewMockBlobStore()
+	a, eventID := seedAttachments(t, blobs)
+	ctx := context.TODO()
+
+	invoice := &Attachment{PropertyID: "elm", EventID: eventID, Filename: "invoice.pdf"}
+	if err := a.UploadAttachment(ctx, invoice, bytes.NewReader(testPDF)); err != nil {
+		t.Fatalf("UploadAttachment() unexpected error = %v", err)
+	}
+	a.clock.(*MockClock).now = a.clock.Now().Add(time.Hour)
+	receipt := &Attachment{PropertyID: "elm", EventID: eventID, Filename: "receipt.png"}
+	if err := a.UploadAttachment(ctx, receipt, bytes.NewReader(testPNG)); err != nil {
+		t.Fatalf("UploadAttachment() unexpected error = %v", err)
+	}
+
+	attachments, err := a.GetAttachments(ctx, "elm", eventID)
+	if err != nil {
+		t.Fatalf("GetAttachments() unexpected error = %v", err)
+	}
+	if len(attachments) != 2 || attachments[0].ID != invoice.ID || attachments[1].ID != receipt.ID {
+		t.Errorf("GetAttachments() got = %+v, want the invoice then the receipt", attachments)
+	}
+	if _, err := a.GetAttachments(ctx, "elm", "nothing"); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("GetAttachments() error = %v, want %v", err, ErrEventNotFound)
+	}
+
+	byEvent, err := a.GetEventAttachments(ctx, "elm", []string{eventID, "other"})
+	if err != nil {
+		t.Fatalf("GetEventAttachments() unexpected error = %v", err)
+	}
+	if len(byEvent) != 1 || len(byEvent[eventID]) != 2 {
+		t.Errorf("GetEventAttachments() got = %+v, want both attachments of the event", byEvent)
+	}
+
+	got, content, err := a.OpenAttachment(ctx, "elm", eventID, invoice.ID)
+	if err != nil {
+		t.Fatalf("OpenAttachment() unexpected error = %v", err)
+	}
+	data, _ := io.ReadAll(content)
+	content.Close()
+	if got.SHA256 != invoice.SHA256 || !bytes.Equal(data, testPDF) {
+		t.Errorf("OpenAttachment() got = %+v with %d bytes, want the invoice", got, len(data))
+	}
+	if _, _, err := a.OpenAttachment(ctx, "elm", "other", invoice.ID); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("OpenAttachment() of another event error = %v, want %v", err, ErrAttachmentNotFound)
+	}
+
+	if err := a.DeleteAttachment(ctx, "elm", eventID, invoice.ID); err != nil {
+		t.Fatalf("DeleteAttachment() unexpected error = %v", err)
+	}
+	if _, ok := blobs.blobs["elm/"+invoice.ID]; ok {
+		t.Errorf("DeleteAttachment() kept the content of the attachment")
+	}
+	if err := a.DeleteAttachment(ctx, "elm", eventID, invoice.ID); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("DeleteAttachment() error = %v, want %v", err, ErrAttachmentNotFound)
+	}
+
+	// an attachment whose content is missing cannot be downloaded
+	delete(blobs.blobs, "elm/"+receipt.ID)
+	if _, _, err := a.OpenAttachment(ctx, "elm", eventID, receipt.ID); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("OpenAttachment() error = %v, want %v", err, ErrAttachmentNotFound)
+	}
+}
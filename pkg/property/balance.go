@@ -6,32 +6,36 @@ import (
 	"time"
 )
 
-func (h *Handler) GetBalance(ctx context.Context, PropertyID string) (float64, error) {
+// GetBalance returns the current balance of the property in each currency, and their total
+// in the base currency at the current exchange rates.
+func (h *Handler) GetBalance(ctx context.Context, PropertyID string) (*Balance, error) {
 	if PropertyID == "" {
-		return 0, fmt.Errorf("empty property ID")
+		return nil, fmt.Errorf("empty property ID")
 	}
 
-	state, exists, err := h.store.GetMostRecentEventForFilter(ctx, &EventFilter{PropertyID: PropertyID})
-	if err != nil {
-		return 0, fmt.Errorf("get events for filter: %v", err)
-	}
-	if !exists {
-		return 0, nil
-	}
-	return state.PostEventBalance, nil
+	return h.getBalance(ctx, &EventFilter{PropertyID: PropertyID}, time.Now())
 }
 
-func (h *Handler) getBalanceForDate(ctx context.Context, PropertyID string, date time.Time) (float64, error) {
-	startingBalanceFilter := &EventFilter{
-		PropertyID: PropertyID,
-		BeforeTime: date.Add(-1 * time.Millisecond),
+// GetBalanceAsOf returns the balance of the property in each currency after every event dated
+// at or before asOf, and their total in the base currency at the exchange rates of asOf.
+func (h *Handler) GetBalanceAsOf(ctx context.Context, PropertyID string, asOf time.Time) (*Balance, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
 	}
-	startingBalance, exist, err := h.store.GetMostRecentEventForFilter(ctx, startingBalanceFilter)
+
+	return h.getBalance(ctx, &EventFilter{PropertyID: PropertyID, BeforeTime: asOf}, asOf)
+}
+
+// getBalanceBefore returns the balance of the property before the events dated at date, such as
+// the starting balance of a report beginning at date.
+func (h *Handler) getBalanceBefore(ctx context.Context, PropertyID string, date time.Time) (*Balance, error) {
+	return h.getBalance(ctx, &EventFilter{PropertyID: PropertyID, EndTime: date}, date)
+}
+
+func (h *Handler) getBalance(ctx context.Context, filter *EventFilter, date time.Time) (*Balance, error) {
+	mostRecent, err := h.store.GetMostRecentEventPerCurrency(ctx, filter)
 	if err != nil {
-		return 0, fmt.Errorf("get events for filter: %v", err)
-	}
-	if !exist {
-		return 0, nil
+		return nil, fmt.Errorf("get events for filter: %v", err)
 	}
-	return startingBalance.PostEventBalance, nil
+	return h.newBalance(ctx, mostRecent, date)
 }
@@ -2,43 +2,288 @@ package property
 
 import (
 	"context"
+	"errors"
 	"github.com/brianvoe/gofakeit/v7"
+	"github.com/samber/lo"
+	"math/big"
+	"reflect"
+	"slices"
 	"testing"
 	"time"
 )
 
 type MockEventStore struct {
-	events []*Event
-	err    bool
+	events    []*Event
+	revisions []*EventRevision
+	err       bool
+	conflicts int
 }
 
-func (m MockEventStore) SaveEvent(ctx context.Context, event *Event) error {
+func (m *MockEventStore) WriteLedger(ctx context.Context, propertyID string, write *LedgerWrite) error {
+	return m.WriteLedgers(ctx, map[string]*LedgerWrite{propertyID: write})
+}
+
+func (m *MockEventStore) WriteLedgers(ctx context.Context, writes map[string]*LedgerWrite) error {
 	if m.err {
 		return gofakeit.Error()
 	}
-	m.events = append(m.events, event)
+	if m.conflicts > 0 {
+		m.conflicts--
+		return ErrConflict
+	}
+	for propertyID, write := range writes {
+		for _, event := range write.Insert {
+			if event.IdempotencyKey == "" {
+				continue
+			}
+			if lo.ContainsBy(m.events, func(e *Event) bool {
+				return e.PropertyID == propertyID && e.IdempotencyKey == event.IdempotencyKey
+			}) {
+				return ErrConflict
+			}
+		}
+	}
+
+	for _, write := range writes {
+		m.applyLedgerWrite(write)
+	}
 	return nil
 }
 
-func (m MockEventStore) GetEventsForFilter(ctx context.Context, filter *EventFilter, limit int, offset int) ([]*Event, error) {
+func (m *MockEventStore) applyLedgerWrite(write *LedgerWrite) {
+	sequence := int64(0)
+	if write.Head != nil {
+		sequence = write.Head.Sequence
+	}
+	for _, event := range write.Insert {
+		sequence++
+		event.Sequence = sequence
+		event.ID = gofakeit.UUID()
+		m.events = append(m.events, event)
+	}
+	for _, event := range write.Update {
+		for i, existing := range m.events {
+			if existing.ID == event.ID {
+				m.events[i] = event
+			}
+		}
+	}
+	m.revisions = append(m.revisions, write.Revisions...)
+}
+
+func (m *MockEventStore) GetLedgerHead(ctx context.Context, propertyID string) (*Event, bool, error) {
+	if m.err {
+		return nil, false, gofakeit.Error()
+	}
+	events := m.filter(&EventFilter{PropertyID: propertyID})
+	if len(events) == 0 {
+		return nil, false, nil
+	}
+	return lo.MaxBy(events, func(a, b *Event) bool {
+		return a.Sequence > b.Sequence
+	}), true, nil
+}
+
+func (m *MockEventStore) GetEventsForFilter(ctx context.Context, filter *EventFilter, sortOrder SortOrder, limit int, offset int) ([]*Event, error) {
 	if m.err {
 		return nil, gofakeit.Error()
 	}
-	if limit > 0 {
+	events := m.filter(filter)
+	sortByOrder(events, sortOrder)
+	events = lo.Drop(events, offset)
+	if limit > 0 && limit < len(events) {
+		return events[:limit], nil
+	}
+	return events, nil
+}
 
-		return m.events[:limit], nil
+func (m *MockEventStore) IterateEvents(ctx context.Context, filter *EventFilter, sortOrder SortOrder, fn func(*Event) error) error {
+	if m.err {
+		return gofakeit.Error()
 	}
-	return m.events, nil
+	events := m.filter(filter)
+	sortByOrder(events, sortOrder)
+	for _, e := range events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (m MockEventStore) GetMostRecentEventForFilter(ctx context.Context, filter *EventFilter) (*Event, bool, error) {
+func (m *MockEventStore) GetMostRecentEventForFilter(ctx context.Context, filter *EventFilter) (*Event, bool, error) {
 	if m.err {
 		return nil, false, gofakeit.Error()
 	}
-	if len(m.events) == 0 {
+	events := m.filter(filter)
+	if len(events) == 0 {
 		return nil, false, nil
 	}
-	return m.events[0], true, nil
+	return lo.MaxBy(events, func(a, b *Event) bool {
+		return compareLedgerOrder(a, b) > 0
+	}), true, nil
+}
+
+func (m *MockEventStore) GetMostRecentEventPerCurrency(ctx context.Context, filter *EventFilter) ([]*Event, error) {
+	if m.err {
+		return nil, gofakeit.Error()
+	}
+	events := m.filter(filter)
+	byCurrency := lo.GroupBy(events, func(e *Event) string {
+		return e.Currency
+	})
+	return lo.MapToSlice(byCurrency, func(_ string, events []*Event) *Event {
+		return lo.MaxBy(events, func(a, b *Event) bool {
+			return compareLedgerOrder(a, b) > 0
+		})
+	}), nil
+}
+
+func (m *MockEventStore) GetCategoryTotals(ctx context.Context, filter *EventFilter) ([]*CategoryTotal, error) {
+	if m.err {
+		return nil, gofakeit.Error()
+	}
+	totals := map[[2]string]*CategoryTotal{}
+	for _, e := range m.events {
+		if !matchesFilter(e, filter) {
+			continue
+		}
+		key := [2]string{string(e.Category), e.Currency}
+		if totals[key] == nil {
+			totals[key] = &CategoryTotal{Category: e.Category, Currency: e.Currency}
+		}
+		total := totals[key]
+		if e.EventAmount.IsPositive() {
+			total.Income, _ = total.Income.Add(e.EventAmount)
+		} else {
+			total.Expense, _ = total.Expense.Add(e.EventAmount)
+		}
+		total.EventCount++
+	}
+	return lo.Values(totals), nil
+}
+
+func (m *MockEventStore) GetLedgerSummaries(ctx context.Context, propertyIDs []string, monthStart time.Time, monthEnd time.Time) ([]*LedgerSummary, error) {
+	if m.err {
+		return nil, gofakeit.Error()
+	}
+	summaries := map[[2]string]*LedgerSummary{}
+	for _, e := range m.events {
+		if !lo.Contains(propertyIDs, e.PropertyID) {
+			continue
+		}
+		key := [2]string{e.PropertyID, e.Currency}
+		summary := summaries[key]
+		if summary == nil {
+			summary = &LedgerSummary{PropertyID: e.PropertyID, Currency: e.Currency}
+			summaries[key] = summary
+		}
+		if !e.Date.Before(summary.LastEventDate) {
+			summary.Balance, summary.LastEventDate = e.PostEventBalance, e.Date
+		}
+		if !e.Date.Before(monthStart) && e.Date.Before(monthEnd) {
+			summary.MonthlyNet, _ = summary.MonthlyNet.Add(e.EventAmount)
+		}
+	}
+	return lo.Values(summaries), nil
+}
+
+func (m *MockEventStore) GetEventRevisions(ctx context.Context, propertyID string, eventID string) ([]*EventRevision, error) {
+	if m.err {
+		return nil, gofakeit.Error()
+	}
+	return lo.Filter(m.revisions, func(r *EventRevision, _ int) bool {
+		return r.PropertyID == propertyID && r.EventID == eventID
+	}), nil
+}
+
+// filter returns copies of the events matching filter, so like a real store, changes to them
+// are only saved through WriteLedger.
+func (m *MockEventStore) filter(filter *EventFilter) []*Event {
+	return lo.FilterMap(m.events, func(e *Event, _ int) (*Event, bool) {
+		c := *e
+		return &c, matchesFilter(e, filter)
+	})
+}
+
+func sortByOrder(events []*Event, sortOrder SortOrder) {
+	slices.SortFunc(events, func(a, b *Event) int {
+		if sortOrder == Descending {
+			return compareLedgerOrder(b, a)
+		}
+		return compareLedgerOrder(a, b)
+	})
+}
+
+func matchesFilter(e *Event, filter *EventFilter) bool {
+	if filter.ID != "" && e.ID != filter.ID {
+		return false
+	}
+	if len(filter.IDs) > 0 && !lo.Contains(filter.IDs, e.ID) {
+		return false
+	}
+	if filter.PropertyID != "" && e.PropertyID != filter.PropertyID {
+		return false
+	}
+	if len(filter.PropertyIDs) > 0 && !lo.Contains(filter.PropertyIDs, e.PropertyID) {
+		return false
+	}
+	if !filter.AfterTime.IsZero() && e.Date.Before(filter.AfterTime) {
+		return false
+	}
+	if !filter.BeforeTime.IsZero() && e.Date.After(filter.BeforeTime) {
+		return false
+	}
+	if !filter.EndTime.IsZero() && !e.Date.Before(filter.EndTime) {
+		return false
+	}
+	if filter.AmountType == Expense && !e.EventAmount.IsNegative() {
+		return false
+	}
+	if filter.AmountType == Income && !e.EventAmount.IsPositive() {
+		return false
+	}
+	if filter.Currency != "" && e.Currency != filter.Currency {
+		return false
+	}
+	if filter.Category != Uncategorized && e.Category != filter.Category {
+		return false
+	}
+	if !lo.Every(e.Tags, filter.Tags) {
+		return false
+	}
+	if filter.IdempotencyKey != "" && e.IdempotencyKey != filter.IdempotencyKey {
+		return false
+	}
+	if filter.ExcludeReversed && (e.Reversed || e.ReversalOf != "") {
+		return false
+	}
+	if filter.ExcludeTransfers && e.TransferID != "" {
+		return false
+	}
+	return true
+}
+
+type MockRateProvider map[string]*big.Rat
+
+func (m MockRateProvider) GetRate(ctx context.Context, from string, to string, date time.Time) (*big.Rat, error) {
+	rate, ok := m[from+to]
+	if !ok {
+		return nil, ErrNoRate
+	}
+	return rate, nil
+}
+
+func mustMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func fakeMoney() Money {
+	return MoneyFromUnits(int64(gofakeit.IntRange(-1e12, 1e12)))
 }
 
 func NewMockEventStore(events []*Event, shouldThrowErr bool) *MockEventStore {
@@ -60,7 +305,7 @@ func TestHandler_GetBalance(t *testing.T) {
 		name    string
 		fields  fields
 		args    args
-		want    float64
+		want    Money
 		wantErr bool
 	}{
 		{
@@ -71,7 +316,7 @@ func TestHandler_GetBalance(t *testing.T) {
 			args: args{
 				ctx: context.TODO(),
 			},
-			want:    0,
+			want:    Money{},
 			wantErr: true,
 		},
 		{
@@ -83,7 +328,7 @@ func TestHandler_GetBalance(t *testing.T) {
 				ctx:        context.TODO(),
 				PropertyID: gofakeit.Address().Address,
 			},
-			want:    0,
+			want:    Money{},
 			wantErr: false,
 		},
 		{
@@ -95,7 +340,7 @@ func TestHandler_GetBalance(t *testing.T) {
 				ctx:        context.TODO(),
 				PropertyID: gofakeit.Address().Address,
 			},
-			want:    0,
+			want:    Money{},
 			wantErr: true,
 		},
 		{
@@ -103,8 +348,8 @@ func TestHandler_GetBalance(t *testing.T) {
 			fields: fields{
 				store: NewMockEventStore([]*Event{{
 					PropertyID:       "propID",
-					EventAmount:      142228,
-					PostEventBalance: 142229,
+					EventAmount:      mustMoney("142228"),
+					PostEventBalance: mustMoney("142229.0001"),
 					Date:             time.Now(),
 				}}, false),
 			},
@@ -112,7 +357,7 @@ func TestHandler_GetBalance(t *testing.T) {
 				ctx:        context.TODO(),
 				PropertyID: "propID",
 			},
-			want:    142229,
+			want:    mustMoney("142229.0001"),
 			wantErr: false,
 		},
 	}
@@ -126,9 +371,121 @@ func TestHandler_GetBalance(t *testing.T) {
 				t.Errorf("GetBalance() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if got != tt.want {
-				t.Errorf("GetBalance() got = %v, want %v", got, tt.want)
+			gotTotal := Money{}
+			if got != nil {
+				gotTotal = got.Total
+			}
+			if gotTotal != tt.want {
+				t.Errorf("GetBalance() got = %v, want %v", gotTotal, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_GetBalance_MultiCurrency(t *testing.T) {
+	propertyID := gofakeit.Address().Address
+	store := NewMockEventStore(nil, false)
+	h := NewHandler(store, nil, MockRateProvider{"EURUSD": big.NewRat(11, 10)}, "USD")
+
+	saves := []struct {
+		amount   string
+		currency string
+	}{
+		{amount: "100", currency: "usd"},
+		{amount: "200", currency: "EUR"},
+		{amount: "-50", currency: ""},
+	}
+	for _, save := range saves {
+		if _, err := h.SaveEvent(context.TODO(), &Event{
+			PropertyID:  propertyID,
+			EventAmount: mustMoney(save.amount),
+			Currency:    save.currency,
+			Date:        time.Now(),
+		}); err != nil {
+			t.Fatalf("SaveEvent() unexpected error = %v", err)
+		}
+	}
+
+	got, err := h.GetBalance(context.TODO(), propertyID)
+	if err != nil {
+		t.Fatalf("GetBalance() unexpected error = %v", err)
+	}
+	want := map[string]Money{"USD": mustMoney("50"), "EUR": mustMoney("200")}
+	if !reflect.DeepEqual(got.ByCurrency, want) {
+		t.Errorf("GetBalance() by currency got = %v, want %v", got.ByCurrency, want)
+	}
+	if got.Total != mustMoney("270") {
+		t.Errorf("GetBalance() total got = %v, want %v", got.Total, "270.00")
+	}
+
+	if _, err := h.SaveEvent(context.TODO(), &Event{
+		PropertyID:  propertyID,
+		EventAmount: mustMoney("1"),
+		Currency:    "GBP",
+		Date:        time.Now(),
+	}); err != nil {
+		t.Fatalf("SaveEvent() unexpected error = %v", err)
+	}
+	if _, err := h.GetBalance(context.TODO(), propertyID); !errors.Is(err, ErrNoRate) {
+		t.Errorf("GetBalance() error = %v, want %v", err, ErrNoRate)
+	}
+}
+
+func TestHandler_GetBalanceAsOf(t *testing.T) {
+	yearEnd := time.Date(2023, time.December, 31, 23, 59, 59, 999999999, time.UTC)
+	store := NewMockEventStore([]*Event{
+		{ID: "1", PropertyID: "propID", EventAmount: mustMoney("100"), PostEventBalance: mustMoney("100"), Currency: "USD", Date: yearEnd.Add(-time.Hour), Sequence: 1},
+		{ID: "2", PropertyID: "propID", EventAmount: mustMoney("50"), PostEventBalance: mustMoney("150"), Currency: "USD", Date: yearEnd, Sequence: 2},
+		{ID: "3", PropertyID: "propID", EventAmount: mustMoney("25"), PostEventBalance: mustMoney("175"), Currency: "USD", Date: yearEnd.Add(time.Nanosecond), Sequence: 3},
+	}, false)
+	h := NewHandler(store, nil, nil, "USD")
+
+	tests := []struct {
+		name       string
+		asOf       time.Time
+		want       Money
+		wantSource string
+	}{
+		{
+			name:       "includes an event dated at asOf",
+			asOf:       yearEnd,
+			want:       mustMoney("150"),
+			wantSource: "2",
+		},
+		{
+			name:       "a nanosecond earlier",
+			asOf:       yearEnd.Add(-time.Nanosecond),
+			want:       mustMoney("100"),
+			wantSource: "1",
+		},
+		{
+			name: "before any event",
+			asOf: yearEnd.AddDate(-1, 0, 0),
+			want: Money{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.GetBalanceAsOf(context.TODO(), "propID", tt.asOf)
+			if err != nil {
+				t.Fatalf("GetBalanceAsOf() unexpected error = %v", err)
+			}
+			if got.Total != tt.want || !got.AsOf.Equal(tt.asOf) {
+				t.Errorf("GetBalanceAsOf() got = %v as of %v, want %v as of %v", got.Total, got.AsOf, tt.want, tt.asOf)
+			}
+			if tt.wantSource == "" {
+				if len(got.Sources) != 0 {
+					t.Errorf("GetBalanceAsOf() got sources %v, want none", got.Sources)
+				}
+				return
+			}
+			if source := got.Sources["USD"]; source == nil || source.EventID != tt.wantSource {
+				t.Errorf("GetBalanceAsOf() got source %+v, want event %v", source, tt.wantSource)
 			}
 		})
 	}
+
+	if _, err := h.GetBalanceAsOf(context.TODO(), "", yearEnd); err == nil {
+		t.Errorf("GetBalanceAsOf() expected an error for an empty property ID")
+	}
 }
@@ -0,0 +1,319 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrBudgetNotFound is returned when a budget ID does not match any budget of the property.
+	ErrBudgetNotFound = errors.New("budget not found")
+	// ErrBudgetExists is returned when a property already has a budget for the year, category and currency.
+	ErrBudgetExists = errors.New("budget already exists")
+)
+
+// Budget is the planned net amount of a category of a property over a fiscal year.
+type Budget struct {
+	// ID is assigned by the BudgetStore when the budget is created.
+	ID         string `json:"id" bson:"_id"`
+	PropertyID string `json:"property_id" bson:"property_id"`
+	// Year is the fiscal year of the property the budget covers.
+	Year     int      `json:"year" bson:"year"`
+	Category Category `json:"category" bson:"category"`
+	// Amount is signed like event amounts: positive for planned income, negative for planned expense.
+	Amount   Money  `json:"amount" bson:"amount"`
+	Currency string `json:"currency" bson:"currency"`
+}
+
+type BudgetStore interface {
+	// CreateBudget saves a new budget, assigning it an ID.
+	// It returns ErrBudgetExists if the property already has a budget for the year, category and currency.
+	CreateBudget(ctx context.Context, budget *Budget) error
+	// UpdateBudget replaces the amount of a budget, and reports whether it exists.
+	UpdateBudget(ctx context.Context, budget *Budget) (bool, error)
+	GetBudget(ctx context.Context, propertyID string, budgetID string) (*Budget, bool, error)
+	// GetBudgets returns the budgets of the property for year, or for every year if year is 0.
+	GetBudgets(ctx context.Context, propertyID string, year int) ([]*Budget, error)
+	// DeleteBudget reports whether the budget existed.
+	DeleteBudget(ctx context.Context, propertyID string, budgetID string) (bool, error)
+}
+
+// BudgetVariance compares the actual net amount of a category against its budget.
+type BudgetVariance struct {
+	Category Category
+	Currency string
+	Budgeted Money
+	Actual   Money
+	// Variance is Actual less Budgeted, so it is positive when income beats its budget or expense
+	// stays under it, and negative otherwise.
+	Variance Money
+	// VariancePercent is Variance as a percentage of the size of Budgeted, or 0 for a zero budget.
+	VariancePercent float64
+}
+
+// MonthVariance is the variance of every budget over a month of the fiscal year.
+type MonthVariance struct {
+	From      time.Time
+	To        time.Time
+	Variances []*BudgetVariance
+}
+
+// VarianceReport compares the actual income and expense of a property against its budgets over
+// a fiscal year.
+type VarianceReport struct {
+	PropertyID string
+	Year       int
+	// Months are the twelve months of the fiscal year, each budget spread evenly across them.
+	Months []*MonthVariance
+	// YearToDate sums the months started by the time of the report.
+	YearToDate []*BudgetVariance
+}
+
+// Budgets manages the budgets of properties, and compares them to their ledgers.
+type Budgets struct {
+	handler *Handler
+	store   BudgetStore
+	clock   Clock
+}
+
+func NewBudgets(handler *Handler, store BudgetStore, clock Clock) *Budgets {
+	return &Budgets{
+		handler: handler,
+		store:   store,
+		clock:   clock,
+	}
+}
+
+// CreateBudget saves a new budget of a registered property, in the property currency if it has none.
+// The saved budget, including its ID, is written back to budget.
+func (b *Budgets) CreateBudget(ctx context.Context, budget *Budget) error {
+	property, err := b.handler.getRegisteredProperty(ctx, budget.PropertyID)
+	if err != nil {
+		return err
+	}
+	if budget.Currency == "" && property != nil {
+		budget.Currency = property.Currency
+	}
+
+	if err := b.validateBudget(budget); err != nil {
+		return err
+	}
+
+	if err := b.store.CreateBudget(ctx, budget); err != nil {
+		if errors.Is(err, ErrBudgetExists) {
+			return fmt.Errorf("%s budget for %d: %w", budget.Category, budget.Year, err)
+		}
+		return fmt.Errorf("create budget: %v", err)
+	}
+	return nil
+}
+
+// UpdateBudget changes the amount of a budget. Its year, category and currency cannot be changed.
+// The updated budget is written back to budget.
+func (b *Budgets) UpdateBudget(ctx context.Context, budget *Budget) error {
+	existing, err := b.GetBudget(ctx, budget.PropertyID, budget.ID)
+	if err != nil {
+		return err
+	}
+	existing.Amount = budget.Amount
+	if err := b.validateBudget(existing); err != nil {
+		return err
+	}
+
+	exists, err := b.store.UpdateBudget(ctx, existing)
+	if err != nil {
+		return fmt.Errorf("update budget: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("budget %s: %w", budget.ID, ErrBudgetNotFound)
+	}
+	*budget = *existing
+	return nil
+}
+
+func (b *Budgets) GetBudget(ctx context.Context, PropertyID string, budgetID string) (*Budget, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	} else if budgetID == "" {
+		return nil, fmt.Errorf("empty budget ID")
+	}
+
+	budget, exists, err := b.store.GetBudget(ctx, PropertyID, budgetID)
+	if err != nil {
+		return nil, fmt.Errorf("get budget: %v", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("budget %s: %w", budgetID, ErrBudgetNotFound)
+	}
+	return budget, nil
+}
+
+// GetBudgets returns the budgets of the property for year, or for every year if year is 0.
+func (b *Budgets) GetBudgets(ctx context.Context, PropertyID string, year int) ([]*Budget, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	}
+
+	budgets, err := b.store.GetBudgets(ctx, PropertyID, year)
+	if err != nil {
+		return nil, fmt.Errorf("get budgets: %v", err)
+	}
+	return budgets, nil
+}
+
+func (b *Budgets) DeleteBudget(ctx context.Context, PropertyID string, budgetID string) error {
+	if PropertyID == "" {
+		return fmt.Errorf("empty property ID")
+	} else if budgetID == "" {
+		return fmt.Errorf("empty budget ID")
+	}
+
+	exists, err := b.store.DeleteBudget(ctx, PropertyID, budgetID)
+	if err != nil {
+		return fmt.Errorf("delete budget: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("budget %s: %w", budgetID, ErrBudgetNotFound)
+	}
+	return nil
+}
+
+// validateBudget checks the definition of budget, normalizing its currency.
+func (b *Budgets) validateBudget(budget *Budget) error {
+	if budget.PropertyID == "" {
+		return fmt.Errorf("empty property ID")
+	} else if budget.Year < 1970 {
+		return fmt.Errorf("invalid year %d", budget.Year)
+	} else if budget.Category == Uncategorized || !budget.Category.IsValid() {
+		return fmt.Errorf("invalid category %q", budget.Category)
+	} else if budget.Category == Transfer {
+		return fmt.Errorf("category %q is reserved for transfers", Transfer)
+	}
+
+	if budget.Currency == "" {
+		budget.Currency = b.handler.baseCurrency
+	} else {
+		var err error
+		if budget.Currency, err = NormalizeCurrency(budget.Currency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetVarianceReport compares the actual net amount of every budgeted category of the property
+// against its budget for each month of fiscal year year, in the time zone of the property.
+// The year-to-date rollup covers the months started by now, according to the clock, each with
+// its full share of the budget.
+func (b *Budgets) GetVarianceReport(ctx context.Context, PropertyID string, year int) (*VarianceReport, error) {
+	budgets, err := b.GetBudgets(ctx, PropertyID, year)
+	if err != nil {
+		return nil, err
+	}
+	calendar, err := b.handler.GetCalendar(ctx, PropertyID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([][]Money, len(budgets))
+	for i, budget := range budgets {
+		if shares[i], err = budget.Amount.Split(12); err != nil {
+			return nil, err
+		}
+	}
+	now := b.clock.Now()
+	report := &VarianceReport{
+		PropertyID: PropertyID,
+		Year:       year,
+		Months:     make([]*MonthVariance, 0, 12),
+		YearToDate: make([]*BudgetVariance, len(budgets)),
+	}
+	for i, budget := range budgets {
+		report.YearToDate[i] = &BudgetVariance{Category: budget.Category, Currency: budget.Currency}
+	}
+
+	from, _ := calendar.Year(year)
+	for m := 0; m < 12; m++ {
+		month := &MonthVariance{}
+		month.From, month.To = calendar.Month(from.Month()+time.Month(m), from.Year())
+
+		actuals, err := b.getActuals(ctx, PropertyID, month.From, month.To)
+		if err != nil {
+			return nil, err
+		}
+		for i, budget := range budgets {
+			variance := &BudgetVariance{
+				Category: budget.Category,
+				Currency: budget.Currency,
+				Budgeted: shares[i][m],
+				Actual:   actuals[[2]string{string(budget.Category), budget.Currency}],
+			}
+			if err := variance.compute(); err != nil {
+				return nil, err
+			}
+			month.Variances = append(month.Variances, variance)
+
+			if !month.From.After(now) {
+				if err := report.YearToDate[i].add(variance); err != nil {
+					return nil, err
+				}
+			}
+		}
+		report.Months = append(report.Months, month)
+	}
+	for _, variance := range report.YearToDate {
+		if err := variance.compute(); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// getActuals returns the net amount of the events of the property in [from, to) per category and currency.
+func (b *Budgets) getActuals(ctx context.Context, PropertyID string, from time.Time, to time.Time) (map[[2]string]Money, error) {
+	totals, err := b.handler.store.GetCategoryTotals(ctx, &EventFilter{
+		PropertyID: PropertyID,
+		AfterTime:  from,
+		EndTime:    to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get category totals: %v", err)
+	}
+
+	actuals := make(map[[2]string]Money, len(totals))
+	for _, total := range totals {
+		net, err := total.Income.Add(total.Expense)
+		if err != nil {
+			return nil, fmt.Errorf("sum actuals: %w", err)
+		}
+		actuals[[2]string{string(total.Category), total.Currency}] = net
+	}
+	return actuals, nil
+}
+
+// add adds the budgeted and actual amounts of o to v.
+func (v *BudgetVariance) add(o *BudgetVariance) error {
+	var err error
+	if v.Budgeted, err = v.Budgeted.Add(o.Budgeted); err != nil {
+		return fmt.Errorf("sum budgets: %w", err)
+	}
+	if v.Actual, err = v.Actual.Add(o.Actual); err != nil {
+		return fmt.Errorf("sum actuals: %w", err)
+	}
+	return nil
+}
+
+// compute sets the variance of v from its budgeted and actual amounts.
+func (v *BudgetVariance) compute() error {
+	var err error
+	if v.Variance, err = v.Actual.Sub(v.Budgeted); err != nil {
+		return fmt.Errorf("compute variance: %w", err)
+	}
+	v.VariancePercent = v.Variance.Percent(v.Budgeted)
+	if v.Budgeted.IsNegative() {
+		v.VariancePercent = -v.VariancePercent
+	}
+	return nil
+}
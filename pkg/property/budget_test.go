@@ -0,0 +1,210 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/samber/lo"
+)
+
+type MockBudgetStore struct {
+	budgets map[string]*Budget
+}
+
+func NewMockBudgetStore() *MockBudgetStore {
+	return &MockBudgetStore{budgets: map[string]*Budget{}}
+}
+
+func (m *MockBudgetStore) CreateBudget(ctx context.Context, budget *Budget) error {
+	for _, b := range m.budgets {
+		if b.PropertyID == budget.PropertyID && b.Year == budget.Year && b.Category == budget.Category && b.Currency == budget.Currency {
+			return ErrBudgetExists
+		}
+	}
+	budget.ID = gofakeit.UUID()
+	c := *budget
+	m.budgets[budget.ID] = &c
+	return nil
+}
+
+func (m *MockBudgetStore) UpdateBudget(ctx context.Context, budget *Budget) (bool, error) {
+	existing, ok := m.budgets[budget.ID]
+	if !ok || existing.PropertyID != budget.PropertyID {
+		return false, nil
+	}
+	existing.Amount = budget.Amount
+	return true, nil
+}
+
+func (m *MockBudgetStore) GetBudget(ctx context.Context, propertyID string, budgetID string) (*Budget, bool, error) {
+	b, ok := m.budgets[budgetID]
+	if !ok || b.PropertyID != propertyID {
+		return nil, false, nil
+	}
+	c := *b
+	return &c, true, nil
+}
+
+func (m *MockBudgetStore) GetBudgets(ctx context.Context, propertyID string, year int) ([]*Budget, error) {
+	budgets := lo.Filter(lo.Values(m.budgets), func(b *Budget, _ int) bool {
+		return b.PropertyID == propertyID && (year == 0 || b.Year == year)
+	})
+	return lo.Map(budgets, func(b *Budget, _ int) *Budget {
+		c := *b
+		return &c
+	}), nil
+}
+
+func (m *MockBudgetStore) DeleteBudget(ctx context.Context, propertyID string, budgetID string) (bool, error) {
+	b, ok := m.budgets[budgetID]
+	if !ok || b.PropertyID != propertyID {
+		return false, nil
+	}
+	delete(m.budgets, budgetID)
+	return true, nil
+}
+
+func TestBudgets_CreateBudget(t *testing.T) {
+	properties := NewMockPropertyStore(&Property{ID: "elm", Name: "Elm Street 13", Currency: "EUR"})
+
+	tests := []struct {
+		name         string
+		budget       *Budget
+		wantCurrency string
+		wantErr      error
+	}{
+		{
+			name:         "in the property currency",
+			budget:       &Budget{PropertyID: "elm", Year: 2026, Category: Maintenance, Amount: mustMoney("-3000")},
+			wantCurrency: "EUR",
+		},
+		{
+			name:    "already budgeted",
+			budget:  &Budget{PropertyID: "elm", Year: 2026, Category: Maintenance, Amount: mustMoney("-100")},
+			wantErr: ErrBudgetExists,
+		},
+		{
+			name:    "unknown property",
+			budget:  &Budget{PropertyID: "nowhere", Year: 2026, Category: Maintenance, Amount: mustMoney("-100")},
+			wantErr: ErrPropertyNotFound,
+		},
+		{
+			name:    "transfers",
+			budget:  &Budget{PropertyID: "elm", Year: 2026, Category: Transfer, Amount: mustMoney("-100")},
+			wantErr: errors.New(`category "transfer" is reserved for transfers`),
+		},
+		{
+			name:    "uncategorized",
+			budget:  &Budget{PropertyID: "elm", Year: 2026, Amount: mustMoney("-100")},
+			wantErr: errors.New(`invalid category ""`),
+		},
+	}
+	b := NewBudgets(NewHandler(NewMockEventStore(nil, false), properties, nil, "USD"), NewMockBudgetStore(), SystemClock{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := b.CreateBudget(context.TODO(), tt.budget)
+			if tt.wantErr != nil {
+				if err == nil || (!errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error()) {
+					t.Fatalf("CreateBudget() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateBudget() unexpected error = %v", err)
+			}
+			if tt.budget.ID == "" || tt.budget.Currency != tt.wantCurrency {
+				t.Errorf("CreateBudget() got = %+v, want an ID and currency %v", tt.budget, tt.wantCurrency)
+			}
+		})
+	}
+}
+
+func TestBudgets_GetVarianceReport(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 12, 0, 0, 0, time.UTC)
+	}
+	properties := NewMockPropertyStore(&Property{ID: "elm", Name: "Elm Street 13", Currency: "USD", Timezone: "UTC", FiscalYearStart: time.January})
+	events := NewMockEventStore([]*Event{
+		{ID: "1", PropertyID: "elm", EventAmount: mustMoney("-400"), Currency: "USD", Category: Maintenance, Date: day(time.January, 10), Sequence: 1},
+		{ID: "2", PropertyID: "elm", EventAmount: mustMoney("-100"), Currency: "USD", Category: Maintenance, Date: day(time.February, 10), Sequence: 2},
+		{ID: "3", PropertyID: "elm", EventAmount: mustMoney("1200"), Currency: "USD", Category: Rent, Date: day(time.January, 1), Sequence: 3},
+		{ID: "4", PropertyID: "elm", EventAmount: mustMoney("-900"), Currency: "USD", Category: Maintenance, Date: day(time.June, 10), Sequence: 4},
+	}, false)
+	clock := &MockClock{now: day(time.February, 20)}
+	b := NewBudgets(NewHandler(events, properties, nil, "USD"), NewMockBudgetStore(), clock)
+	for _, budget := range []*Budget{
+		{PropertyID: "elm", Year: 2026, Category: Maintenance, Amount: mustMoney("-3000")},
+		{PropertyID: "elm", Year: 2026, Category: Rent, Amount: mustMoney("12000")},
+	} {
+		if err := b.CreateBudget(context.TODO(), budget); err != nil {
+			t.Fatalf("CreateBudget() unexpected error = %v", err)
+		}
+	}
+
+	report, err := b.GetVarianceReport(context.TODO(), "elm", 2026)
+	if err != nil {
+		t.Fatalf("GetVarianceReport() unexpected error = %v", err)
+	}
+	if len(report.Months) != 12 {
+		t.Fatalf("GetVarianceReport() got %d months, want 12", len(report.Months))
+	}
+
+	tests := []struct {
+		name        string
+		variances   []*BudgetVariance
+		category    Category
+		wantActual  Money
+		wantVar     Money
+		wantPercent float64
+	}{
+		{
+			name:        "maintenance over budget in January",
+			variances:   report.Months[0].Variances,
+			category:    Maintenance,
+			wantActual:  mustMoney("-400"),
+			wantVar:     mustMoney("-150"),
+			wantPercent: -60,
+		},
+		{
+			name:        "rent above budget in January",
+			variances:   report.Months[0].Variances,
+			category:    Rent,
+			wantActual:  mustMoney("1200"),
+			wantVar:     mustMoney("200"),
+			wantPercent: 20,
+		},
+		{
+			name:        "maintenance year to date",
+			variances:   report.YearToDate,
+			category:    Maintenance,
+			wantActual:  mustMoney("-500"),
+			wantVar:     mustMoney("0"),
+			wantPercent: 0,
+		},
+		{
+			name:        "rent year to date",
+			variances:   report.YearToDate,
+			category:    Rent,
+			wantActual:  mustMoney("1200"),
+			wantVar:     mustMoney("-800"),
+			wantPercent: -40,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := lo.Find(tt.variances, func(v *BudgetVariance) bool {
+				return v.Category == tt.category
+			})
+			if !ok {
+				t.Fatalf("GetVarianceReport() has no %s variance", tt.category)
+			}
+			if got.Actual != tt.wantActual || got.Variance != tt.wantVar || got.VariancePercent != tt.wantPercent {
+				t.Errorf("GetVarianceReport() got actual %v, variance %v (%v%%), want %v, %v (%v%%)",
+					got.Actual, got.Variance, got.VariancePercent, tt.wantActual, tt.wantVar, tt.wantPercent)
+			}
+		})
+	}
+}
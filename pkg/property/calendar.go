@@ -0,0 +1,88 @@
+package property
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Calendar places the boundaries of report periods in a time zone.
+type Calendar struct {
+	Location *time.Location
+	// FiscalYearStart is the month fiscal years start in. Fiscal year Y starts on the first
+	// of FiscalYearStart in calendar year Y, and its quarters follow from there.
+	FiscalYearStart time.Month
+}
+
+// UTCCalendar is the calendar of properties without a time zone or fiscal year of their own.
+var UTCCalendar = &Calendar{Location: time.UTC, FiscalYearStart: time.January}
+
+// Day returns the bounds of a calendar day.
+func (c *Calendar) Day(day int, month time.Month, year int) (time.Time, time.Time) {
+	from := time.Date(year, month, day, 0, 0, 0, 0, c.Location)
+	return from, from.AddDate(0, 0, 1)
+}
+
+// Month returns the bounds of a calendar month.
+func (c *Calendar) Month(month time.Month, year int) (time.Time, time.Time) {
+	from := time.Date(year, month, 1, 0, 0, 0, 0, c.Location)
+	return from, from.AddDate(0, 1, 0)
+}
+
+// Quarter returns the bounds of a quarter, numbered from 1, of fiscal year year.
+func (c *Calendar) Quarter(quarter int, year int) (time.Time, time.Time, error) {
+	if quarter < 1 || quarter > 4 {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid quarter %d", quarter)
+	}
+	from := time.Date(year, c.FiscalYearStart+time.Month(3*(quarter-1)), 1, 0, 0, 0, 0, c.Location)
+	return from, from.AddDate(0, 3, 0), nil
+}
+
+// Year returns the bounds of fiscal year year.
+func (c *Calendar) Year(year int) (time.Time, time.Time) {
+	from := time.Date(year, c.FiscalYearStart, 1, 0, 0, 0, 0, c.Location)
+	return from, from.AddDate(1, 0, 0)
+}
+
+// daysBetween returns how many calendar days apart a and b are dated.
+func (c *Calendar) daysBetween(a time.Time, b time.Time) int {
+	ay, am, ad := a.In(c.Location).Date()
+	by, bm, bd := b.In(c.Location).Date()
+	days := int(time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC).Sub(time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	if days < 0 {
+		return -days
+	}
+	return days
+}
+
+// GetCalendar returns the calendar reports of the property are given in: in timezone if it is
+// set, and otherwise in the time zone of the property, with the fiscal year of the property.
+// Without a property registry the calendar is UTCCalendar, in timezone if it is set.
+func (h *Handler) GetCalendar(ctx context.Context, PropertyID string, timezone string) (*Calendar, error) {
+	property, err := h.getRegisteredProperty(ctx, PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	return propertyCalendar(property, timezone)
+}
+
+// propertyCalendar returns the calendar of GetCalendar for property, which may be nil.
+func propertyCalendar(property *Property, timezone string) (*Calendar, error) {
+	calendar := *UTCCalendar
+	if property != nil {
+		if timezone == "" {
+			timezone = property.Timezone
+		}
+		if property.FiscalYearStart != 0 {
+			calendar.FiscalYearStart = property.FiscalYearStart
+		}
+	}
+
+	if timezone != "" {
+		var err error
+		if calendar.Location, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q", timezone)
+		}
+	}
+	return &calendar, nil
+}
@@ -0,0 +1,84 @@
+package property
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Category is what an event was for, such as rent income or a maintenance expense.
+type Category string
+
+const (
+	Uncategorized Category = ""
+	Rent          Category = "rent"
+	Maintenance   Category = "maintenance"
+	Tax           Category = "tax"
+	Insurance     Category = "insurance"
+	Mortgage      Category = "mortgage"
+	Utilities     Category = "utilities"
+	Other         Category = "other"
+	// Transfer is the category of both legs of a transfer, and of no other event.
+	Transfer Category = "transfer"
+)
+
+// Categories lists every valid category.
+var Categories = []Category{Uncategorized, Rent, Maintenance, Tax, Insurance, Mortgage, Utilities, Other, Transfer}
+
+func (c Category) IsValid() bool {
+	return slices.Contains(Categories, c)
+}
+
+// CategoryTotal is the income and expense of a category in a single currency.
+type CategoryTotal struct {
+	Category Category `json:"category"`
+	Currency string   `json:"currency"`
+	Income   Money    `json:"income"`
+	// Expense is the sum of the expense amounts, and so is zero or negative.
+	Expense    Money `json:"expense"`
+	EventCount int64 `json:"event_count"`
+}
+
+// GetCategoryReport returns the income and expense totals of the property per category and
+// currency, for events dated in [dateFrom, dateTo].
+func (h *Handler) GetCategoryReport(ctx context.Context, PropertyID string, dateFrom time.Time, dateTo time.Time) ([]*CategoryTotal, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	} else if dateFrom.After(dateTo) {
+		return nil, fmt.Errorf("dateFrom must be before dateTo")
+	}
+
+	totals, err := h.store.GetCategoryTotals(ctx, &EventFilter{
+		PropertyID: PropertyID,
+		AfterTime:  dateFrom,
+		BeforeTime: dateTo,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get category totals: %v", err)
+	}
+
+	slices.SortFunc(totals, func(a, b *CategoryTotal) int {
+		if c := strings.Compare(string(a.Category), string(b.Category)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Currency, b.Currency)
+	})
+	return totals, nil
+}
+
+// normalizeTags trims the tags, and drops empty and repeated ones.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
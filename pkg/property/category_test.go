@@ -0,0 +1,113 @@
+package property
+
+import (
+	"context"
+	"github.com/brianvoe/gofakeit/v7"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func seedCategorizedHandler(t *testing.T) (string, *Handler) {
+	propertyID := gofakeit.Address().Address
+	h := NewHandler(NewMockEventStore(nil, false), nil, nil, "USD")
+
+	events := []*Event{
+		{EventAmount: mustMoney("2000"), Category: Rent, Tags: []string{"unit-1"}},
+		{EventAmount: mustMoney("1500"), Category: Rent, Tags: []string{"unit-2"}},
+		{EventAmount: mustMoney("-300.25"), Category: Maintenance, Tags: []string{" plumbing ", "unit-1", "plumbing"}},
+		{EventAmount: mustMoney("-120"), Category: Maintenance, Tags: []string{"unit-2"}},
+		{EventAmount: mustMoney("50"), Category: Maintenance, Tags: []string{"refund"}},
+		{EventAmount: mustMoney("-900"), Category: Tax},
+		{EventAmount: mustMoney("-10")},
+	}
+	for i, e := range events {
+		e.PropertyID = propertyID
+		e.Date = time.Date(2025, time.April, i+1, 0, 0, 0, 0, time.UTC)
+		if _, err := h.SaveEvent(context.TODO(), e); err != nil {
+			t.Fatalf("SaveEvent() unexpected error = %v", err)
+		}
+	}
+	return propertyID, h
+}
+
+func TestHandler_SaveEvent_InvalidCategory(t *testing.T) {
+	h := NewHandler(NewMockEventStore(nil, false), nil, nil, "USD")
+	_, err := h.SaveEvent(context.TODO(), &Event{
+		PropertyID:  gofakeit.Address().Address,
+		EventAmount: mustMoney("10"),
+		Date:        time.Now(),
+		Category:    "groceries",
+	})
+	if err == nil {
+		t.Errorf("SaveEvent() expected error for invalid category")
+	}
+}
+
+func TestHandler_GetPropertyEvents_CategoryAndTags(t *testing.T) {
+	propertyID, h := seedCategorizedHandler(t)
+
+	tests := []struct {
+		name   string
+		filter *EventFilter
+		want   []string
+	}{
+		{
+			name:   "by category",
+			filter: &EventFilter{PropertyID: propertyID, Category: Maintenance},
+			want:   []string{"-300.25", "-120.00", "50.00"},
+		},
+		{
+			name:   "by tag",
+			filter: &EventFilter{PropertyID: propertyID, Tags: []string{"unit-1"}},
+			want:   []string{"2000.00", "-300.25"},
+		},
+		{
+			name:   "by every tag",
+			filter: &EventFilter{PropertyID: propertyID, Tags: []string{"unit-1", "plumbing"}},
+			want:   []string{"-300.25"},
+		},
+		{
+			name:   "by category and amount type",
+			filter: &EventFilter{PropertyID: propertyID, Category: Maintenance, AmountType: Expense},
+			want:   []string{"-300.25", "-120.00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := h.GetPropertyEvents(context.TODO(), tt.filter, Ascending, 0, 0)
+			if err != nil {
+				t.Errorf("GetPropertyEvents() unexpected error = %v", err)
+				return
+			}
+			got := make([]string, 0, len(events))
+			for _, e := range events {
+				got = append(got, e.EventAmount.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetPropertyEvents() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_GetCategoryReport(t *testing.T) {
+	propertyID, h := seedCategorizedHandler(t)
+
+	got, err := h.GetCategoryReport(context.TODO(), propertyID,
+		time.Date(2025, time.April, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, time.April, 30, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetCategoryReport() unexpected error = %v", err)
+	}
+
+	want := []*CategoryTotal{
+		{Category: Uncategorized, Currency: "USD", Expense: mustMoney("-10"), EventCount: 1},
+		{Category: Maintenance, Currency: "USD", Income: mustMoney("50"), Expense: mustMoney("-420.25"), EventCount: 3},
+		{Category: Rent, Currency: "USD", Income: mustMoney("1500"), EventCount: 1},
+		{Category: Tax, Currency: "USD", Expense: mustMoney("-900"), EventCount: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetCategoryReport() got = %v, want %v", got, want)
+	}
+}
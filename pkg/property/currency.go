@@ -0,0 +1,109 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrNoRate is returned by a RateProvider that has no rate for a currency pair and date.
+var ErrNoRate = errors.New("no exchange rate")
+
+type RateProvider interface {
+	// GetRate returns how many units of the to currency one unit of the from currency
+	// was worth at date.
+	GetRate(ctx context.Context, from string, to string, date time.Time) (*big.Rat, error)
+}
+
+// Balance is a property balance at a point in time, kept separately per currency.
+type Balance struct {
+	// ByCurrency maps an ISO-4217 currency code to the balance held in that currency.
+	ByCurrency map[string]Money `json:"by_currency"`
+	// Total is the sum of ByCurrency converted to BaseCurrency at the rates of AsOf.
+	Total        Money     `json:"total"`
+	BaseCurrency string    `json:"base_currency"`
+	AsOf         time.Time `json:"as_of"`
+	// Sources maps each currency of ByCurrency to the event its balance is the running balance of.
+	Sources map[string]*BalanceSource `json:"sources,omitempty"`
+}
+
+// BalanceSource identifies the event a balance was taken from.
+type BalanceSource struct {
+	EventID string    `json:"event_id"`
+	Date    time.Time `json:"date"`
+}
+
+// NormalizeCurrency upper-cases an ISO-4217 currency code, and fails if code is not
+// made of three letters.
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("invalid currency code %q", code)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("invalid currency code %q", code)
+		}
+	}
+	return code, nil
+}
+
+// newBalance sums the running balances of the most recent event per currency into a Balance
+// as of date.
+func (h *Handler) newBalance(ctx context.Context, mostRecent []*Event, date time.Time) (*Balance, error) {
+	balance := &Balance{
+		ByCurrency:   make(map[string]Money, len(mostRecent)),
+		BaseCurrency: h.baseCurrency,
+		AsOf:         date,
+		Sources:      make(map[string]*BalanceSource, len(mostRecent)),
+	}
+	for _, event := range mostRecent {
+		balance.ByCurrency[event.Currency] = event.PostEventBalance
+		balance.Sources[event.Currency] = &BalanceSource{EventID: event.ID, Date: event.Date}
+	}
+
+	total, err := h.convertTotal(ctx, balance.ByCurrency, date)
+	if err != nil {
+		return nil, err
+	}
+	balance.Total = total
+	return balance, nil
+}
+
+// convertTotal converts every amount to the base currency at the rates of date, and sums them.
+func (h *Handler) convertTotal(ctx context.Context, amounts map[string]Money, date time.Time) (Money, error) {
+	total := Money{}
+	for currency, amount := range amounts {
+		converted, err := h.convert(ctx, amount, currency, date)
+		if err != nil {
+			return Money{}, err
+		}
+		if total, err = total.Add(converted); err != nil {
+			return Money{}, fmt.Errorf("sum balances: %w", err)
+		}
+	}
+	return total, nil
+}
+
+// convert converts amount from currency to the base currency at the rate of date.
+func (h *Handler) convert(ctx context.Context, amount Money, currency string, date time.Time) (Money, error) {
+	if currency == h.baseCurrency || amount.IsZero() {
+		return amount, nil
+	}
+	if h.rates == nil {
+		return Money{}, fmt.Errorf("convert %s to %s: %w", currency, h.baseCurrency, ErrNoRate)
+	}
+
+	rate, err := h.rates.GetRate(ctx, currency, h.baseCurrency, date)
+	if err != nil {
+		return Money{}, fmt.Errorf("get rate %s to %s: %w", currency, h.baseCurrency, err)
+	}
+	converted, err := amount.Convert(rate)
+	if err != nil {
+		return Money{}, fmt.Errorf("convert %s to %s: %w", currency, h.baseCurrency, err)
+	}
+	return converted, nil
+}
@@ -1,105 +1,320 @@
 package property
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/samber/lo"
 	"slices"
 	"time"
 )
 
 type Event struct {
+	// ID is assigned by the EventStore when the event is saved.
+	ID               string    `json:"id" bson:"_id"`
 	PropertyID       string    `json:"property_id,omitempty" bson:"property_id"`
-	EventAmount      float64   `json:"event_amount" bson:"event_amount"`
-	PostEventBalance float64   `json:"post_event_balance" bson:"post_event_balance"`
+	EventAmount      Money     `json:"event_amount" bson:"event_amount"`
+	PostEventBalance Money     `json:"post_event_balance" bson:"post_event_balance"`
 	Date             time.Time `json:"date" bson:"date"`
+	// Currency is the ISO-4217 code of the currency the event is in.
+	// PostEventBalance is the running balance of the property in that currency only.
+	Currency string `json:"currency" bson:"currency"`
+	// Sequence is the order in which the event was saved to the property ledger,
+	// starting at 1. It is assigned by the EventStore when the event is saved.
+	Sequence    int64    `json:"sequence" bson:"sequence"`
+	Description string   `json:"description,omitempty" bson:"description,omitempty"`
+	Category    Category `json:"category,omitempty" bson:"category,omitempty"`
+	Tags        []string `json:"tags,omitempty" bson:"tags,omitempty"`
+	// IdempotencyKey, when set, is unique per property, so replaying a save with the same key
+	// returns the event saved the first time instead of saving another one.
+	IdempotencyKey string `json:"idempotency_key,omitempty" bson:"idempotency_key,omitempty"`
+	// Reversed marks an event voided by a compensating event.
+	Reversed bool `json:"reversed,omitempty" bson:"reversed,omitempty"`
+	// ReversalOf is the ID of the event a compensating event voids.
+	ReversalOf     string `json:"reversal_of,omitempty" bson:"reversal_of,omitempty"`
+	ReversalReason string `json:"reversal_reason,omitempty" bson:"reversal_reason,omitempty"`
+	// Revision counts the amendments made to the event, its prior versions being kept
+	// as EventRevisions.
+	Revision int `json:"revision,omitempty" bson:"revision,omitempty"`
+	// TransferID links the two legs of a transfer between properties.
+	TransferID string `json:"transfer_id,omitempty" bson:"transfer_id,omitempty"`
+	// Overdrawn marks an event saved below the minimum balance of a property whose overdraft
+	// policy is to warn.
+	Overdrawn bool `json:"overdrawn,omitempty" bson:"overdrawn,omitempty"`
 }
 
 type EventFilter struct {
+	ID string
+	// IDs matches the events with any of the IDs, in place of ID.
+	IDs        []string
 	PropertyID string
-	AfterTime  time.Time
-	BeforeTime time.Time
+	// PropertyIDs matches the events of any of the properties, in place of PropertyID.
+	PropertyIDs []string
+	AfterTime   time.Time
+	BeforeTime  time.Time
+	// EndTime matches events dated strictly before it, where BeforeTime includes the events dated at it.
+	EndTime    time.Time
 	AmountType AmountType
+	Currency   string
+	Category   Category
+	// Tags matches events carrying every one of the tags.
+	Tags           []string
+	IdempotencyKey string
+	// ExcludeReversed leaves out reversed events and the events compensating them.
+	ExcludeReversed bool
+	// ExcludeTransfers leaves out the legs of transfers between properties.
+	ExcludeTransfers bool
 }
 
-func (h *Handler) SaveEvent(ctx context.Context, PropertyID string, amount float64, date time.Time) (float64, error) {
-	if PropertyID == "" {
-		return 0, fmt.Errorf("empty property ID")
-	} else if amount == 0 {
-		return 0, nil
-	} else if date.IsZero() {
-		return 0, fmt.Errorf("invalid date")
+// ErrIdempotencyKeyReused is returned when an idempotency key is replayed with a different
+// amount or currency than the event it was first used for.
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused for a different event")
+
+// maxIdempotencyKeyLength bounds the idempotency keys clients may send.
+const maxIdempotencyKeyLength = 255
+
+// SaveEvent saves event to the ledger of its registered property, in the property currency if
+// it has none, and returns the property balance in the event currency after the event.
+// The saved event, including its ID, is written back to event. If the property already has an
+// event with the same IdempotencyKey, nothing is saved and event is filled with that one instead.
+// An expense taking the balance below the minimum of the property is handled according to its
+// overdraft policy, and fails with an OverdraftError if the policy is to reject it.
+func (h *Handler) SaveEvent(ctx context.Context, event *Event) (Money, error) {
+	if event.PropertyID == "" {
+		return Money{}, fmt.Errorf("empty property ID")
+	} else if event.EventAmount.IsZero() {
+		return Money{}, nil
+	} else if event.Date.IsZero() {
+		return Money{}, fmt.Errorf("invalid date")
 	}
 
-	curBalance, err := h.GetBalance(ctx, PropertyID)
+	property, err := h.getRegisteredProperty(ctx, event.PropertyID)
 	if err != nil {
-		return 0, fmt.Errorf("get balance: %v", err)
+		return Money{}, err
 	}
 
-	event := &Event{
-		PropertyID:       PropertyID,
-		EventAmount:      amount,
-		PostEventBalance: curBalance + amount,
-		Date:             date,
+	if event.Currency == "" {
+		event.Currency = h.baseCurrency
+		if property != nil {
+			event.Currency = property.Currency
+		}
+	} else if event.Currency, err = NormalizeCurrency(event.Currency); err != nil {
+		return Money{}, err
 	}
-	if err := h.store.SaveEvent(ctx, event); err != nil {
-		return 0, fmt.Errorf("save event: %v", err)
+	if !event.Category.IsValid() {
+		return Money{}, fmt.Errorf("invalid category %q", event.Category)
+	} else if event.Category == Transfer {
+		return Money{}, fmt.Errorf("category %q is reserved for transfers", Transfer)
+	}
+	event.Tags = normalizeTags(event.Tags)
+	if len(event.IdempotencyKey) > maxIdempotencyKeyLength {
+		return Money{}, fmt.Errorf("idempotency key longer than %d characters", maxIdempotencyKeyLength)
+	}
+
+	replayed, err := h.insertEvent(ctx, event)
+	if err != nil {
+		return Money{}, fmt.Errorf("save event: %w", err)
+	}
+	if !replayed {
+		h.notify(ctx, event)
 	}
 	return event.PostEventBalance, nil
 }
 
-func (h *Handler) GetPropertyEvents(ctx context.Context, PropertyID string, dateFrom time.Time, dateTo time.Time, sortOrder SortOrder, amountType AmountType, offset int, limit int) ([]*Event, error) {
-	if PropertyID == "" {
-		return nil, fmt.Errorf("empty property ID")
-	} else if dateFrom.After(dateTo) {
-		return nil, fmt.Errorf("dateFrom must be before dateTo")
+// insertEvent saves event at its date in the property ledger, after any event with the
+// same date, and shifts the running balance of every later event in its currency by its amount.
+// An event whose idempotency key was already used is replaced by the event saved with it,
+// which insertEvent reports as replayed.
+func (h *Handler) insertEvent(ctx context.Context, event *Event) (bool, error) {
+	replayed := false
+	err := retryOnConflict(ctx, func() error {
+		var err error
+		if replayed, err = h.replayIdempotent(ctx, event); err != nil || replayed {
+			return err
+		}
+
+		write, err := h.planInsert(ctx, event)
+		if err != nil {
+			return err
+		}
+		return h.writeLedger(ctx, event.PropertyID, write)
+	})
+	return replayed, err
+}
+
+// writeLedger applies the overdraft policy of the property to write, and applies it to the
+// property ledger. A ledger saved before properties were registered has no policy.
+func (h *Handler) writeLedger(ctx context.Context, PropertyID string, write *LedgerWrite) error {
+	if err := h.applyOverdraftPolicy(ctx, PropertyID, write); err != nil {
+		return err
 	}
+	return h.store.WriteLedger(ctx, PropertyID, write)
+}
 
-	filter := &EventFilter{
-		PropertyID: PropertyID,
-		AfterTime:  dateFrom,
-		BeforeTime: dateTo,
-		AmountType: amountType,
+// writeLedgers is writeLedger for writes to several property ledgers, keyed by property ID,
+// which are applied all or none of them.
+func (h *Handler) writeLedgers(ctx context.Context, writes map[string]*LedgerWrite) error {
+	for PropertyID, write := range writes {
+		if err := h.applyOverdraftPolicy(ctx, PropertyID, write); err != nil {
+			return err
+		}
 	}
-	events, err := h.store.GetEventsForFilter(ctx, filter, limit, offset)
+	return h.store.WriteLedgers(ctx, writes)
+}
+
+// applyOverdraftPolicy applies the overdraft policy of the property to write.
+func (h *Handler) applyOverdraftPolicy(ctx context.Context, PropertyID string, write *LedgerWrite) error {
+	property, err := h.getRegisteredProperty(ctx, PropertyID)
+	if err != nil && !errors.Is(err, ErrPropertyNotFound) {
+		return err
+	}
+	return checkOverdraft(property, write)
+}
+
+// planInsert computes the ledger write inserting event at its date: the running balance of
+// event, and of every later event in its currency.
+func (h *Handler) planInsert(ctx context.Context, event *Event) (*LedgerWrite, error) {
+	head, exists, err := h.store.GetLedgerHead(ctx, event.PropertyID)
+	if err != nil {
+		return nil, fmt.Errorf("get ledger head: %v", err)
+	}
+	if !exists {
+		head = nil
+	}
+
+	previousBalance := Money{}
+	previous, exists, err := h.store.GetMostRecentEventForFilter(ctx, &EventFilter{
+		PropertyID: event.PropertyID,
+		BeforeTime: event.Date,
+		Currency:   event.Currency,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("get events for filter: %v", err)
 	}
+	if exists {
+		previousBalance = previous.PostEventBalance
+	}
+	if event.PostEventBalance, err = previousBalance.Add(event.EventAmount); err != nil {
+		return nil, fmt.Errorf("compute balance: %w", err)
+	}
 
-	sortByOrder(events, sortOrder)
+	later, err := h.getEventsAfter(ctx, event.PropertyID, event.Currency, event.Date)
+	if err != nil {
+		return nil, fmt.Errorf("get later events: %v", err)
+	}
+	for _, e := range later {
+		if e.PostEventBalance, err = e.PostEventBalance.Add(event.EventAmount); err != nil {
+			return nil, fmt.Errorf("recompute later balance: %w", err)
+		}
+	}
 
+	write := &LedgerWrite{
+		Head:   head,
+		Insert: []*Event{event},
+		Update: later,
+		event:  event,
+	}
+	if event.EventAmount.IsNegative() {
+		write.lowered = append([]*Event{event}, later...)
+	}
+	return write, nil
+}
+
+// replayIdempotent fills event with the event previously saved with its idempotency key,
+// and reports whether there was one.
+func (h *Handler) replayIdempotent(ctx context.Context, event *Event) (bool, error) {
+	if event.IdempotencyKey == "" {
+		return false, nil
+	}
+
+	original, exists, err := h.store.GetMostRecentEventForFilter(ctx, &EventFilter{
+		PropertyID:     event.PropertyID,
+		IdempotencyKey: event.IdempotencyKey,
+	})
+	if err != nil {
+		return false, fmt.Errorf("get events for filter: %v", err)
+	}
+	if !exists {
+		return false, nil
+	}
+	if original.EventAmount != event.EventAmount || original.Currency != event.Currency {
+		return false, ErrIdempotencyKeyReused
+	}
+	*event = *original
+	return true, nil
+}
+
+// getEventsAfter returns every event of the property in currency dated strictly after date.
+func (h *Handler) getEventsAfter(ctx context.Context, PropertyID string, currency string, date time.Time) ([]*Event, error) {
+	events, err := h.store.GetEventsForFilter(ctx, &EventFilter{
+		PropertyID: PropertyID,
+		AfterTime:  date,
+		Currency:   currency,
+	}, Ascending, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get events for filter: %v", err)
+	}
+	return lo.Filter(events, func(e *Event, _ int) bool {
+		return e.Date.After(date)
+	}), nil
+}
+
+// GetPropertyEvents returns the events matching filter, which must name a property or several.
+func (h *Handler) GetPropertyEvents(ctx context.Context, filter *EventFilter, sortOrder SortOrder, offset int, limit int) ([]*Event, error) {
+	if err := validateEventFilter(filter); err != nil {
+		return nil, err
+	}
+
+	if sortOrder != Ascending {
+		sortOrder = Descending
+	}
+
+	events, err := h.store.GetEventsForFilter(ctx, filter, sortOrder, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("get events for filter: %v", err)
+	}
 	return events, nil
 }
 
-func sortByOrder(events []*Event, sortOrder SortOrder) {
-	switch sortOrder {
-	case Ascending:
-		sortByDateAsc(events)
-	case Descending:
-		sortByDateDesc(events)
-	default:
-		sortByDateDesc(events)
+// EachEvent calls fn with every event matching filter in sortOrder, reading them from the store
+// as they are needed rather than all at once, and stops at the first error fn returns.
+func (h *Handler) EachEvent(ctx context.Context, filter *EventFilter, sortOrder SortOrder, fn func(*Event) error) error {
+	if err := validateEventFilter(filter); err != nil {
+		return err
 	}
+	if sortOrder != Ascending {
+		sortOrder = Descending
+	}
+
+	return h.store.IterateEvents(ctx, filter, sortOrder, fn)
+}
+
+func validateEventFilter(filter *EventFilter) error {
+	if filter.PropertyID == "" && len(filter.PropertyIDs) == 0 {
+		return fmt.Errorf("empty property ID")
+	} else if !filter.BeforeTime.IsZero() && filter.AfterTime.After(filter.BeforeTime) {
+		return fmt.Errorf("dateFrom must be before dateTo")
+	} else if !filter.EndTime.IsZero() && !filter.AfterTime.Before(filter.EndTime) {
+		return fmt.Errorf("dateFrom must be before dateTo")
+	} else if !filter.Category.IsValid() {
+		return fmt.Errorf("invalid category %q", filter.Category)
+	}
+	return nil
 }
 
 func sortByDateAsc(events []*Event) {
-	slices.SortFunc(events, func(a, b *Event) int {
-		if a.Date.Before(b.Date) {
-			return -1
-		} else if a.Date.After(b.Date) {
-			return 1
-		}
-		return 0
-	})
+	slices.SortFunc(events, compareLedgerOrder)
 }
 
-func sortByDateDesc(events []*Event) {
-	slices.SortFunc(events, func(a, b *Event) int {
-		if a.Date.After(b.Date) {
-			return -1
-		} else if a.Date.Before(b.Date) {
-			return 1
-		}
-		return 0
-	})
+// compareLedgerOrder orders events by date, and events sharing a date by the order
+// they were saved in, then by ID as the events of several properties can share a sequence.
+func compareLedgerOrder(a, b *Event) int {
+	if a.Date.Before(b.Date) {
+		return -1
+	} else if a.Date.After(b.Date) {
+		return 1
+	}
+	return cmp.Or(cmp.Compare(a.Sequence, b.Sequence), cmp.Compare(a.ID, b.ID))
 }
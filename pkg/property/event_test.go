@@ -2,6 +2,7 @@ package property
 
 import (
 	"context"
+	"errors"
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/samber/lo"
 	"reflect"
@@ -16,14 +17,14 @@ func TestHandler_SaveEvent(t *testing.T) {
 	type args struct {
 		ctx        context.Context
 		PropertyID string
-		amount     float64
+		amount     Money
 		date       time.Time
 	}
 	tests := []struct {
 		name    string
 		fields  fields
 		args    args
-		want    float64
+		want    Money
 		wantErr bool
 	}{
 		{
@@ -32,10 +33,10 @@ func TestHandler_SaveEvent(t *testing.T) {
 			args: args{
 				ctx:        context.TODO(),
 				PropertyID: "",
-				amount:     100,
+				amount:     mustMoney("100"),
 				date:       time.Now(),
 			},
-			want:    0,
+			want:    Money{},
 			wantErr: true,
 		},
 		{
@@ -44,10 +45,10 @@ func TestHandler_SaveEvent(t *testing.T) {
 			args: args{
 				ctx:        context.TODO(),
 				PropertyID: gofakeit.Address().Address,
-				amount:     0,
+				amount:     Money{},
 				date:       time.Now(),
 			},
-			want:    0,
+			want:    Money{},
 			wantErr: false,
 		},
 		{
@@ -56,10 +57,10 @@ func TestHandler_SaveEvent(t *testing.T) {
 			args: args{
 				ctx:        context.TODO(),
 				PropertyID: gofakeit.Address().Address,
-				amount:     100,
+				amount:     mustMoney("100"),
 				date:       time.Time{},
 			},
-			want:    0,
+			want:    Money{},
 			wantErr: true,
 		},
 		{
@@ -68,10 +69,10 @@ func TestHandler_SaveEvent(t *testing.T) {
 			args: args{
 				ctx:        context.TODO(),
 				PropertyID: gofakeit.Address().Address,
-				amount:     100,
+				amount:     mustMoney("100"),
 				date:       time.Now(),
 			},
-			want:    0,
+			want:    Money{},
 			wantErr: true,
 		},
 	}
@@ -80,7 +81,11 @@ func TestHandler_SaveEvent(t *testing.T) {
 			h := &Handler{
 				store: tt.fields.store,
 			}
-			got, err := h.SaveEvent(tt.args.ctx, tt.args.PropertyID, tt.args.amount, tt.args.date)
+			got, err := h.SaveEvent(tt.args.ctx, &Event{
+				PropertyID:  tt.args.PropertyID,
+				EventAmount: tt.args.amount,
+				Date:        tt.args.date,
+			})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("SaveEvent() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -94,7 +99,7 @@ func TestHandler_SaveEvent(t *testing.T) {
 
 func TestHandler_SaveEvent_ValidInput(t *testing.T) {
 	propertyID := gofakeit.Address().Address
-	oldBalance := gofakeit.Float64()
+	oldBalance := fakeMoney()
 	// Create a mock store with successful event saving
 	store := NewMockEventStore([]*Event{
 		{
@@ -106,7 +111,7 @@ func TestHandler_SaveEvent_ValidInput(t *testing.T) {
 	// Create a valid property ID
 
 	// Set up test amount and date
-	amount := gofakeit.Float64()
+	amount := fakeMoney()
 	date := time.Now()
 
 	// Create handler with mock store
@@ -115,7 +120,11 @@ func TestHandler_SaveEvent_ValidInput(t *testing.T) {
 	}
 
 	// Call SaveEvent with valid inputs
-	balance, err := h.SaveEvent(context.TODO(), propertyID, amount, date)
+	balance, err := h.SaveEvent(context.TODO(), &Event{
+		PropertyID:  propertyID,
+		EventAmount: amount,
+		Date:        date,
+	})
 
 	// Verify no error occurred
 	if err != nil {
@@ -123,8 +132,176 @@ func TestHandler_SaveEvent_ValidInput(t *testing.T) {
 		return
 	}
 	// Verify balance is correct
-	if balance != amount+oldBalance {
-		t.Errorf("SaveEvent() got = %v, want %v", balance, amount+oldBalance)
+	want, _ := oldBalance.Add(amount)
+	if balance != want {
+		t.Errorf("SaveEvent() got = %v, want %v", balance, want)
+	}
+}
+
+func TestHandler_SaveEvent_RetriesOnConflict(t *testing.T) {
+	propertyID := gofakeit.Address().Address
+	store := NewMockEventStore([]*Event{
+		{
+			PropertyID:       propertyID,
+			PostEventBalance: mustMoney("100"),
+			Sequence:         7,
+		},
+	}, false)
+	store.conflicts = maxConflictRetries - 1
+	h := &Handler{
+		store: store,
+	}
+
+	balance, err := h.SaveEvent(context.TODO(), &Event{
+		PropertyID:  propertyID,
+		EventAmount: mustMoney("50"),
+		Date:        time.Now(),
+	})
+	if err != nil {
+		t.Errorf("SaveEvent() unexpected error = %v", err)
+		return
+	}
+	if balance != mustMoney("150") {
+		t.Errorf("SaveEvent() got = %v, want %v", balance, "150")
+	}
+	if saved := store.events[len(store.events)-1]; saved.Sequence != 8 {
+		t.Errorf("SaveEvent() saved sequence = %v, want %v", saved.Sequence, 8)
+	}
+}
+
+func TestHandler_SaveEvent_Backdated(t *testing.T) {
+	propertyID := gofakeit.Address().Address
+	day := func(d int) time.Time {
+		return time.Date(2025, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+	store := NewMockEventStore([]*Event{
+		{ID: "1", PropertyID: propertyID, EventAmount: mustMoney("100"), PostEventBalance: mustMoney("100"), Date: day(1), Sequence: 1},
+		{ID: "2", PropertyID: propertyID, EventAmount: mustMoney("50"), PostEventBalance: mustMoney("150"), Date: day(3), Sequence: 2},
+		{ID: "3", PropertyID: propertyID, EventAmount: mustMoney("-20"), PostEventBalance: mustMoney("130"), Date: day(5), Sequence: 3},
+	}, false)
+	h := &Handler{
+		store: store,
+	}
+
+	balance, err := h.SaveEvent(context.TODO(), &Event{
+		PropertyID:  propertyID,
+		EventAmount: mustMoney("-30"),
+		Date:        day(2),
+	})
+	if err != nil {
+		t.Errorf("SaveEvent() unexpected error = %v", err)
+		return
+	}
+	if balance != mustMoney("70") {
+		t.Errorf("SaveEvent() got = %v, want %v", balance, "70")
+	}
+
+	events, err := h.GetPropertyEvents(context.TODO(), &EventFilter{PropertyID: propertyID}, Ascending, 0, 0)
+	if err != nil {
+		t.Errorf("GetPropertyEvents() unexpected error = %v", err)
+		return
+	}
+	wantBalances := []string{"100.00", "70.00", "120.00", "100.00"}
+	gotBalances := lo.Map(events, func(e *Event, _ int) string {
+		return e.PostEventBalance.String()
+	})
+	if !reflect.DeepEqual(gotBalances, wantBalances) {
+		t.Errorf("running balances got = %v, want %v", gotBalances, wantBalances)
+	}
+	if events[1].Sequence != 4 {
+		t.Errorf("backdated event sequence = %v, want %v", events[1].Sequence, 4)
+	}
+}
+
+func TestHandler_SaveEvent_IdempotencyKey(t *testing.T) {
+	propertyID := gofakeit.Address().Address
+	store := NewMockEventStore(nil, false)
+	h := NewHandler(store, nil, nil, "USD")
+	newEvent := func(amount string) *Event {
+		return &Event{
+			PropertyID:     propertyID,
+			EventAmount:    mustMoney(amount),
+			Date:           time.Now(),
+			IdempotencyKey: "key-1",
+		}
+	}
+
+	first := newEvent("100")
+	if _, err := h.SaveEvent(context.TODO(), first); err != nil {
+		t.Fatalf("SaveEvent() unexpected error = %v", err)
+	}
+	if first.ID == "" {
+		t.Errorf("SaveEvent() did not assign an event ID")
+	}
+
+	replayed := newEvent("100")
+	balance, err := h.SaveEvent(context.TODO(), replayed)
+	if err != nil {
+		t.Fatalf("SaveEvent() unexpected error = %v", err)
+	}
+	if replayed.ID != first.ID || balance != mustMoney("100") {
+		t.Errorf("SaveEvent() replay got id %v balance %v, want id %v balance %v", replayed.ID, balance, first.ID, "100.00")
+	}
+	if len(store.events) != 1 {
+		t.Errorf("SaveEvent() replay saved %d events, want 1", len(store.events))
+	}
+
+	if _, err := h.SaveEvent(context.TODO(), newEvent("200")); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("SaveEvent() error = %v, want %v", err, ErrIdempotencyKeyReused)
+	}
+}
+
+// racingEventStore misses the first idempotency key lookup, as if the original event was
+// saved concurrently, right after the lookup.
+type racingEventStore struct {
+	*MockEventStore
+	missed bool
+}
+
+func (r *racingEventStore) GetMostRecentEventForFilter(ctx context.Context, filter *EventFilter) (*Event, bool, error) {
+	if filter.IdempotencyKey != "" && !r.missed {
+		r.missed = true
+		return nil, false, nil
+	}
+	return r.MockEventStore.GetMostRecentEventForFilter(ctx, filter)
+}
+
+func TestHandler_SaveEvent_IdempotencyKeyRace(t *testing.T) {
+	propertyID := gofakeit.Address().Address
+	store := NewMockEventStore([]*Event{{
+		ID:               "original",
+		PropertyID:       propertyID,
+		EventAmount:      mustMoney("100"),
+		PostEventBalance: mustMoney("100"),
+		Currency:         "USD",
+		Sequence:         1,
+		IdempotencyKey:   "key-1",
+	}}, false)
+	h := NewHandler(&racingEventStore{MockEventStore: store}, nil, nil, "USD")
+
+	event := &Event{PropertyID: propertyID, EventAmount: mustMoney("100"), Date: time.Now(), IdempotencyKey: "key-1"}
+	if _, err := h.SaveEvent(context.TODO(), event); err != nil {
+		t.Fatalf("SaveEvent() unexpected error = %v", err)
+	}
+	if event.ID != "original" || len(store.events) != 1 {
+		t.Errorf("SaveEvent() got id %v with %d events, want the original event only", event.ID, len(store.events))
+	}
+}
+
+func TestHandler_SaveEvent_PersistentConflict(t *testing.T) {
+	store := NewMockEventStore(nil, false)
+	store.conflicts = maxConflictRetries
+	h := &Handler{
+		store: store,
+	}
+
+	_, err := h.SaveEvent(context.TODO(), &Event{
+		PropertyID:  gofakeit.Address().Address,
+		EventAmount: mustMoney("50"),
+		Date:        time.Now(),
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("SaveEvent() error = %v, want %v", err, ErrConflict)
 	}
 }
 
@@ -194,7 +371,12 @@ func TestHandler_GetPropertyEvents(t *testing.T) {
 			h := &Handler{
 				store: tt.fields.store,
 			}
-			got, err := h.GetPropertyEvents(tt.args.ctx, tt.args.PropertyID, tt.args.dateFrom, tt.args.dateTo, tt.args.sortOrder, tt.args.amountType, tt.args.offset, tt.args.limit)
+			got, err := h.GetPropertyEvents(tt.args.ctx, &EventFilter{
+				PropertyID: tt.args.PropertyID,
+				AfterTime:  tt.args.dateFrom,
+				BeforeTime: tt.args.dateTo,
+				AmountType: tt.args.amountType,
+			}, tt.args.sortOrder, tt.args.offset, tt.args.limit)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetPropertyEvents() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -215,7 +397,12 @@ func TestHandler_GetPropertyEvents_ValidInput_Ascending(t *testing.T) {
 	sortOrder := Ascending
 
 	// Call GetPropertyEvents with valid inputs
-	gotEvents, err := h.GetPropertyEvents(context.TODO(), seed.propertyID, dateFrom, dateTo, sortOrder, All, 0, 0)
+	gotEvents, err := h.GetPropertyEvents(context.TODO(), &EventFilter{
+		PropertyID: seed.propertyID,
+		AfterTime:  dateFrom,
+		BeforeTime: dateTo,
+		AmountType: All,
+	}, sortOrder, 0, 0)
 
 	// Verify no error occurred
 	if err != nil {
@@ -247,7 +434,12 @@ func TestHandler_GetPropertyEvents_ValidInput_Descending(t *testing.T) {
 	sortOrder := Descending
 
 	// Call GetPropertyEvents with valid inputs
-	gotEvents, err := h.GetPropertyEvents(context.TODO(), seed.propertyID, dateFrom, dateTo, sortOrder, All, 0, 0)
+	gotEvents, err := h.GetPropertyEvents(context.TODO(), &EventFilter{
+		PropertyID: seed.propertyID,
+		AfterTime:  dateFrom,
+		BeforeTime: dateTo,
+		AmountType: All,
+	}, sortOrder, 0, 0)
 
 	// Verify no error occurred
 	if err != nil {
@@ -278,7 +470,12 @@ func TestHandler_GetPropertyEvents_ValidInput_InvalidSortOrder(t *testing.T) {
 	sortOrder := SortOrder(23)
 
 	// Call GetPropertyEvents with valid inputs
-	gotEvents, err := h.GetPropertyEvents(context.TODO(), seed.propertyID, dateFrom, dateTo, sortOrder, All, 0, 0)
+	gotEvents, err := h.GetPropertyEvents(context.TODO(), &EventFilter{
+		PropertyID: seed.propertyID,
+		AfterTime:  dateFrom,
+		BeforeTime: dateTo,
+		AmountType: All,
+	}, sortOrder, 0, 0)
 
 	// Verify no error occurred
 	if err != nil {
@@ -301,6 +498,87 @@ func TestHandler_GetPropertyEvents_ValidInput_InvalidSortOrder(t *testing.T) {
 	}
 }
 
+func TestHandler_GetPropertyEvents_DescendingPages(t *testing.T) {
+	seed, h := seedTestHandler()
+	filter := &EventFilter{PropertyID: seed.propertyID}
+
+	all, err := h.GetPropertyEvents(context.TODO(), filter, Descending, 0, 0)
+	if err != nil {
+		t.Fatalf("GetPropertyEvents() unexpected error = %v", err)
+	}
+	first, err := h.GetPropertyEvents(context.TODO(), filter, Descending, 0, 10)
+	if err != nil {
+		t.Fatalf("GetPropertyEvents() unexpected error = %v", err)
+	}
+	second, err := h.GetPropertyEvents(context.TODO(), filter, Descending, 10, 10)
+	if err != nil {
+		t.Fatalf("GetPropertyEvents() unexpected error = %v", err)
+	}
+
+	// the first page holds the most recent events, and the second the ones before them
+	got := append(first, second...)
+	if len(got) != 20 {
+		t.Fatalf("GetPropertyEvents() got %d events over two pages, want 20", len(got))
+	}
+	for i, e := range got {
+		if e.ID != all[i].ID {
+			t.Fatalf("GetPropertyEvents() event %d = %s, want %s", i, e.ID, all[i].ID)
+		}
+	}
+	for i := 1; i < len(all); i++ {
+		if compareLedgerOrder(all[i-1], all[i]) < 0 {
+			t.Fatalf("GetPropertyEvents() events not sorted correctly")
+		}
+	}
+}
+
+func TestHandler_EachEvent(t *testing.T) {
+	seed, h := seedTestHandler()
+	stop := errors.New("stop")
+
+	tests := []struct {
+		name       string
+		filter     *EventFilter
+		sortOrder  SortOrder
+		stopAfter  int
+		wantEvents int
+		wantErr    error
+	}{
+		{name: "ascending", filter: &EventFilter{PropertyID: seed.propertyID}, sortOrder: Ascending, wantEvents: seed.eventCount},
+		{name: "descending", filter: &EventFilter{PropertyID: seed.propertyID}, sortOrder: Descending, wantEvents: seed.eventCount},
+		{name: "expenses", filter: &EventFilter{PropertyID: seed.propertyID, AmountType: Expense}, sortOrder: Ascending, wantEvents: seed.expense},
+		{name: "stopped by fn", filter: &EventFilter{PropertyID: seed.propertyID}, sortOrder: Ascending, stopAfter: 2, wantEvents: 2, wantErr: stop},
+		{name: "no property ID", filter: &EventFilter{}, sortOrder: Ascending, wantErr: errors.New("")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []*Event
+			err := h.EachEvent(context.TODO(), tt.filter, tt.sortOrder, func(e *Event) error {
+				got = append(got, e)
+				if len(got) == tt.stopAfter {
+					return stop
+				}
+				return nil
+			})
+			if (err != nil) != (tt.wantErr != nil) || (tt.wantErr == stop && !errors.Is(err, stop)) {
+				t.Fatalf("EachEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.wantEvents {
+				t.Fatalf("EachEvent() got %d events, want %d", len(got), tt.wantEvents)
+			}
+			for i := 1; i < len(got); i++ {
+				order := compareLedgerOrder(got[i-1], got[i])
+				if tt.sortOrder == Descending {
+					order = -order
+				}
+				if order > 0 {
+					t.Errorf("EachEvent() events not sorted correctly")
+				}
+			}
+		})
+	}
+}
+
 func seedTestHandler() (seedInfo, *Handler) {
 	// Create a property ID for testing
 	propertyID := gofakeit.Address().Address
@@ -308,6 +586,9 @@ func seedTestHandler() (seedInfo, *Handler) {
 	var eventArr [100]*Event
 	gofakeit.Slice(&eventArr)
 	events := eventArr[:]
+	for _, e := range events {
+		e.PropertyID = propertyID
+	}
 
 	// Create a mock store with the test events
 	store := NewMockEventStore(events, false)
@@ -321,10 +602,10 @@ func seedTestHandler() (seedInfo, *Handler) {
 		propertyID: propertyID,
 		eventCount: len(events),
 		expense: len(lo.Filter(events, func(item *Event, index int) bool {
-			return item.EventAmount < 0
+			return item.EventAmount.IsNegative()
 		})),
 		income: len(lo.Filter(events, func(item *Event, index int) bool {
-			return item.EventAmount > 0
+			return item.EventAmount.IsPositive()
 		})),
 	}, h
 }
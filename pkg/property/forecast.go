@@ -0,0 +1,259 @@
+package property
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	MinForecastMonths = 3
+	MaxForecastMonths = 24
+	// forecastHistoryMonths is how many complete months before a forecast its averages are taken over.
+	forecastHistoryMonths = 12
+)
+
+// CategoryAverage is the average net amount of a category of a property per month of history.
+type CategoryAverage struct {
+	Category Category
+	Currency string
+	Monthly  Money
+}
+
+// ForecastMonth holds the projected balances of a property per currency at the end of a month.
+// The optimistic and pessimistic balances are one standard deviation of the monthly net of the
+// history above and below the expected balance, the spread growing with the square root of the
+// months projected.
+type ForecastMonth struct {
+	From        time.Time
+	To          time.Time
+	Expected    map[string]Money
+	Optimistic  map[string]Money
+	Pessimistic map[string]Money
+}
+
+// Forecast projects the balance of a property from its monthly averages per category and its
+// events dated in the future.
+type Forecast struct {
+	PropertyID string
+	// From is when the forecast starts, and StartingBalance the balance per currency at From.
+	From            time.Time
+	StartingBalance map[string]Money
+	// HistoryFrom and HistoryTo bound the months the averages were taken over, which start with
+	// the first month of the property ledger if it is more recent than forecastHistoryMonths.
+	// They are zero for a property without history.
+	HistoryFrom time.Time
+	HistoryTo   time.Time
+	Averages    []*CategoryAverage
+	// Months are the month the forecast starts in, then the months following it.
+	Months []*ForecastMonth
+}
+
+// GetForecast projects the balance of the property at the end of each of months months from
+// the one from is in, in the time zone of the property.
+// Each month adds the average net of every category over the complete months of the last year,
+// the part of the first month left after from adding its share, along with the events dated in
+// the month after from. Transfers, being one-offs, are left out of the averages.
+func (h *Handler) GetForecast(ctx context.Context, PropertyID string, from time.Time, months int) (*Forecast, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	} else if months < MinForecastMonths || months > MaxForecastMonths {
+		return nil, fmt.Errorf("months must be between %d and %d", MinForecastMonths, MaxForecastMonths)
+	}
+	calendar, err := h.GetCalendar(ctx, PropertyID, "")
+	if err != nil {
+		return nil, err
+	}
+	local := from.In(calendar.Location)
+
+	forecast := &Forecast{
+		PropertyID:      PropertyID,
+		From:            from,
+		StartingBalance: map[string]Money{},
+	}
+	mostRecent, err := h.store.GetMostRecentEventPerCurrency(ctx, &EventFilter{PropertyID: PropertyID, BeforeTime: from})
+	if err != nil {
+		return nil, fmt.Errorf("get events for filter: %v", err)
+	}
+	for _, e := range mostRecent {
+		forecast.StartingBalance[e.Currency] = e.PostEventBalance
+	}
+
+	history, err := h.getForecastHistory(ctx, PropertyID, calendar, local, forecast)
+	if err != nil {
+		return nil, err
+	}
+
+	future, err := h.store.GetEventsForFilter(ctx, &EventFilter{PropertyID: PropertyID, AfterTime: from}, Ascending, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get events for filter: %v", err)
+	}
+	sortByDateAsc(future)
+
+	currencies := make(map[string]bool)
+	for currency := range forecast.StartingBalance {
+		currencies[currency] = true
+	}
+	for currency := range history {
+		currencies[currency] = true
+	}
+	for _, e := range future {
+		currencies[e.Currency] = true
+	}
+
+	expected := make(map[string]Money, len(forecast.StartingBalance))
+	for currency, balance := range forecast.StartingBalance {
+		expected[currency] = balance
+	}
+	// periods is how many months of averages were projected, counting the part of the first month left
+	periods := 0.0
+	next := 0
+	for m := 0; m < months; m++ {
+		month := &ForecastMonth{
+			Expected:    map[string]Money{},
+			Optimistic:  map[string]Money{},
+			Pessimistic: map[string]Money{},
+		}
+		month.From, month.To = calendar.Month(local.Month()+time.Month(m), local.Year())
+		share := 1.0
+		if m == 0 {
+			share = float64(month.To.Sub(from)) / float64(month.To.Sub(month.From))
+		}
+		periods += share
+
+		for ; next < len(future) && future[next].Date.Before(month.To); next++ {
+			if !future[next].Date.After(from) {
+				continue
+			}
+			if expected[future[next].Currency], err = expected[future[next].Currency].Add(future[next].EventAmount); err != nil {
+				return nil, fmt.Errorf("project balance: %w", err)
+			}
+		}
+
+		for currency := range currencies {
+			stats := history[currency]
+			trend, err := moneyFromFloat(stats.mean * periods)
+			if err != nil {
+				return nil, err
+			}
+			spread, err := moneyFromFloat(stats.deviation * math.Sqrt(periods))
+			if err != nil {
+				return nil, err
+			}
+			if month.Expected[currency], err = expected[currency].Add(trend); err != nil {
+				return nil, fmt.Errorf("project balance: %w", err)
+			}
+			if month.Optimistic[currency], err = month.Expected[currency].Add(spread); err != nil {
+				return nil, fmt.Errorf("project balance: %w", err)
+			}
+			if month.Pessimistic[currency], err = month.Expected[currency].Sub(spread); err != nil {
+				return nil, fmt.Errorf("project balance: %w", err)
+			}
+		}
+		forecast.Months = append(forecast.Months, month)
+	}
+	return forecast, nil
+}
+
+// netStats are the mean and standard deviation of the monthly net of a currency, in Money units.
+type netStats struct {
+	mean      float64
+	deviation float64
+}
+
+// getForecastHistory sets the history bounds and the category averages of forecast, from the
+// complete months before the month of local, and returns the statistics of the monthly net of
+// each currency over them.
+func (h *Handler) getForecastHistory(ctx context.Context, PropertyID string, calendar *Calendar, local time.Time, forecast *Forecast) (map[string]netStats, error) {
+	monthly := make([]map[[2]string]Money, 0, forecastHistoryMonths)
+	var first time.Time
+	for m := forecastHistoryMonths; m > 0; m-- {
+		monthFrom, monthTo := calendar.Month(local.Month()-time.Month(m), local.Year())
+		totals, err := h.store.GetCategoryTotals(ctx, &EventFilter{
+			PropertyID:       PropertyID,
+			AfterTime:        monthFrom,
+			EndTime:          monthTo,
+			ExcludeTransfers: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get category totals: %v", err)
+		}
+		// months before the ledger starts would drag the averages down
+		if len(totals) == 0 && len(monthly) == 0 {
+			continue
+		}
+		if len(monthly) == 0 {
+			first = monthFrom
+		}
+
+		nets := make(map[[2]string]Money, len(totals))
+		for _, total := range totals {
+			if nets[[2]string{string(total.Category), total.Currency}], err = total.Income.Add(total.Expense); err != nil {
+				return nil, fmt.Errorf("sum category totals: %w", err)
+			}
+		}
+		monthly = append(monthly, nets)
+		forecast.HistoryTo = monthTo
+	}
+	if len(monthly) == 0 {
+		return map[string]netStats{}, nil
+	}
+	forecast.HistoryFrom = first
+
+	n := float64(len(monthly))
+	sums := make(map[[2]string]float64)
+	currencyNets := make(map[string][]float64)
+	for i, nets := range monthly {
+		for key, net := range nets {
+			sums[key] += float64(net.Units())
+			if currencyNets[key[1]] == nil {
+				currencyNets[key[1]] = make([]float64, len(monthly))
+			}
+			currencyNets[key[1]][i] += float64(net.Units())
+		}
+	}
+
+	for key, sum := range sums {
+		average, err := moneyFromFloat(sum / n)
+		if err != nil {
+			return nil, err
+		}
+		forecast.Averages = append(forecast.Averages, &CategoryAverage{
+			Category: Category(key[0]),
+			Currency: key[1],
+			Monthly:  average,
+		})
+	}
+	slices.SortFunc(forecast.Averages, func(a, b *CategoryAverage) int {
+		if a.Currency != b.Currency {
+			return cmp.Compare(a.Currency, b.Currency)
+		}
+		return cmp.Compare(string(a.Category), string(b.Category))
+	})
+
+	stats := make(map[string]netStats, len(currencyNets))
+	for currency, nets := range currencyNets {
+		mean := 0.0
+		for _, net := range nets {
+			mean += net / n
+		}
+		variance := 0.0
+		for _, net := range nets {
+			variance += (net - mean) * (net - mean) / n
+		}
+		stats[currency] = netStats{mean: mean, deviation: math.Sqrt(variance)}
+	}
+	return stats, nil
+}
+
+// moneyFromFloat rounds units to the nearest Money.
+func moneyFromFloat(units float64) (Money, error) {
+	rounded := math.Round(units)
+	if rounded >= math.MaxInt64 || rounded <= math.MinInt64 || math.IsNaN(rounded) {
+		return Money{}, ErrOverflow
+	}
+	return MoneyFromUnits(int64(rounded)), nil
+}
@@ -0,0 +1,77 @@
+package property
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHandler_GetForecast(t *testing.T) {
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2024, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	// the monthly nets are 900, 700 and 800, the transfer being left out
+	store := NewMockEventStore([]*Event{
+		{ID: "1", PropertyID: "propID", EventAmount: mustMoney("1000"), PostEventBalance: mustMoney("1000"), Currency: "USD", Category: Rent, Date: day(time.April, 5), Sequence: 1},
+		{ID: "2", PropertyID: "propID", EventAmount: mustMoney("-100"), PostEventBalance: mustMoney("900"), Currency: "USD", Category: Maintenance, Date: day(time.April, 10), Sequence: 2},
+		{ID: "3", PropertyID: "propID", EventAmount: mustMoney("1000"), PostEventBalance: mustMoney("1900"), Currency: "USD", Category: Rent, Date: day(time.May, 5), Sequence: 3},
+		{ID: "4", PropertyID: "propID", EventAmount: mustMoney("-300"), PostEventBalance: mustMoney("1600"), Currency: "USD", Category: Maintenance, Date: day(time.May, 10), Sequence: 4},
+		{ID: "5", PropertyID: "propID", EventAmount: mustMoney("600"), PostEventBalance: mustMoney("2200"), Currency: "USD", Category: Transfer, TransferID: "t", Date: day(time.May, 20), Sequence: 5},
+		{ID: "6", PropertyID: "propID", EventAmount: mustMoney("1000"), PostEventBalance: mustMoney("3200"), Currency: "USD", Category: Rent, Date: day(time.June, 5), Sequence: 6},
+		{ID: "7", PropertyID: "propID", EventAmount: mustMoney("-200"), PostEventBalance: mustMoney("3000"), Currency: "USD", Category: Maintenance, Date: day(time.June, 10), Sequence: 7},
+		{ID: "8", PropertyID: "propID", EventAmount: mustMoney("-500"), PostEventBalance: mustMoney("2500"), Currency: "USD", Category: Tax, Date: day(time.August, 10), Sequence: 8},
+	}, false)
+	h := NewHandler(store, nil, nil, "USD")
+
+	got, err := h.GetForecast(context.TODO(), "propID", day(time.July, 1), 3)
+	if err != nil {
+		t.Fatalf("GetForecast() unexpected error = %v", err)
+	}
+	if got.StartingBalance["USD"] != mustMoney("3000") {
+		t.Errorf("GetForecast() starting balance got = %v, want %v", got.StartingBalance["USD"], "3000")
+	}
+	if !got.HistoryFrom.Equal(day(time.April, 1)) || !got.HistoryTo.Equal(day(time.July, 1)) {
+		t.Errorf("GetForecast() history got = [%v, %v), want the months since the ledger started", got.HistoryFrom, got.HistoryTo)
+	}
+	if len(got.Averages) != 2 || got.Averages[0].Category != Maintenance || got.Averages[0].Monthly != mustMoney("-200") ||
+		got.Averages[1].Category != Rent || got.Averages[1].Monthly != mustMoney("1000") {
+		t.Errorf("GetForecast() got averages %+v, want maintenance -200 and rent 1000", got.Averages)
+	}
+
+	wantExpected := []string{"3800", "4100", "4900"}
+	if len(got.Months) != len(wantExpected) {
+		t.Fatalf("GetForecast() got %d months, want %d", len(got.Months), len(wantExpected))
+	}
+	var previousSpread Money
+	for i, month := range got.Months {
+		if month.Expected["USD"] != mustMoney(wantExpected[i]) {
+			t.Errorf("GetForecast() month %d expected got = %v, want %v", i, month.Expected["USD"], wantExpected[i])
+		}
+		up, _ := month.Optimistic["USD"].Sub(month.Expected["USD"])
+		down, _ := month.Expected["USD"].Sub(month.Pessimistic["USD"])
+		if up != down || up.Cmp(previousSpread) <= 0 {
+			t.Errorf("GetForecast() month %d bands got [%v, %v], want symmetric and widening around %v",
+				i, month.Pessimistic["USD"], month.Optimistic["USD"], month.Expected["USD"])
+		}
+		previousSpread = up
+	}
+	// a standard deviation of the monthly nets
+	if want := mustMoney("3881.6497"); got.Months[0].Optimistic["USD"] != want {
+		t.Errorf("GetForecast() first optimistic balance got = %v, want %v", got.Months[0].Optimistic["USD"], want)
+	}
+
+	// a forecast starting mid-month projects the rest of the month only
+	mid, err := h.GetForecast(context.TODO(), "propID", day(time.June, 16), 3)
+	if err != nil {
+		t.Fatalf("GetForecast() unexpected error = %v", err)
+	}
+	if want := mustMoney("3400"); mid.Months[0].Expected["USD"] != want {
+		t.Errorf("GetForecast() mid-month expected got = %v, want %v", mid.Months[0].Expected["USD"], want)
+	}
+
+	for _, months := range []int{2, 25} {
+		if _, err := h.GetForecast(context.TODO(), "propID", day(time.July, 1), months); err == nil {
+			t.Errorf("GetForecast() expected an error for %d months", months)
+		}
+	}
+}
@@ -1,20 +1,126 @@
 package property
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrConflict is returned by an EventStore when a conditional write lost a race
+// with a concurrent write to the same property ledger.
+var ErrConflict = errors.New("concurrent modification of property ledger")
+
+// ErrInvalid is matched by the errors returned for a request that fails validation.
+var ErrInvalid = errors.New("invalid input")
+
+// maxConflictRetries is how many times a ledger write is retried after losing a race
+// with a concurrent write before giving up.
+const maxConflictRetries = 5
+
+// LedgerWrite is a change to a single property ledger that must be applied atomically.
+type LedgerWrite struct {
+	// Head is the most recently saved event of the ledger the write was computed from,
+	// nil meaning the ledger was empty.
+	Head *Event
+	// Insert holds the new events. The store assigns them IDs, and the sequences following Head.
+	Insert []*Event
+	// Update holds existing events, identified by their ID, that must be replaced.
+	// An updated event given the sequence following Head, as an amended event is, conflicts
+	// with concurrent writes the same way an inserted event does.
+	Update []*Event
+	// Revisions holds the prior versions of the amended events, to be added to their history.
+	Revisions []*EventRevision
+
+	// event is the event the write saves or amends, which a warning overdraft policy marks as Overdrawn.
+	event *Event
+	// lowered holds the inserted and updated events whose running balance the write lowers,
+	// which the overdraft policy applies to.
+	lowered []*Event
+}
+
+// updated returns the event write.Update already holds with the ID of event,
+// adding event to write.Update if it holds none.
+func (w *LedgerWrite) updated(event *Event) *Event {
+	for _, e := range w.Update {
+		if e.ID == event.ID {
+			return e
+		}
+	}
+	w.Update = append(w.Update, event)
+	return event
+}
 
 type EventStore interface {
-	SaveEvent(ctx context.Context, event *Event) error
-	GetEventsForFilter(ctx context.Context, filter *EventFilter, limit int, offset int) ([]*Event, error)
+	// WriteLedger applies write only if write.Head is still the most recently saved
+	// event of the property ledger.
+	// It returns ErrConflict if another write was applied in the meantime, or if an inserted
+	// event reuses the idempotency key of another event of the property.
+	WriteLedger(ctx context.Context, propertyID string, write *LedgerWrite) error
+	// WriteLedgers applies a write to each of several property ledgers, keyed by property ID,
+	// all or none of them, each under the same conditions as WriteLedger.
+	WriteLedgers(ctx context.Context, writes map[string]*LedgerWrite) error
+	// GetLedgerHead returns the most recently saved event of the property ledger,
+	// regardless of its date.
+	GetLedgerHead(ctx context.Context, propertyID string) (*Event, bool, error)
+	// GetEventsForFilter returns the events matching filter in ledger order, skipping the first
+	// offset of them and returning at most limit, or all of them for a limit of 0.
+	GetEventsForFilter(ctx context.Context, filter *EventFilter, sortOrder SortOrder, limit int, offset int) ([]*Event, error)
+	// IterateEvents calls fn with each event matching filter in ledger order, or in reverse for
+	// Descending, until fn returns an error.
+	IterateEvents(ctx context.Context, filter *EventFilter, sortOrder SortOrder, fn func(*Event) error) error
 	GetMostRecentEventForFilter(ctx context.Context, filter *EventFilter) (*Event, bool, error)
+	// GetMostRecentEventPerCurrency returns the most recent event matching filter in each currency.
+	GetMostRecentEventPerCurrency(ctx context.Context, filter *EventFilter) ([]*Event, error)
+	// GetCategoryTotals sums the income and expense of the events matching filter per
+	// category and currency.
+	GetCategoryTotals(ctx context.Context, filter *EventFilter) ([]*CategoryTotal, error)
+	// GetLedgerSummaries summarizes the ledger of each property in each currency, the monthly
+	// net being the sum of the events dated in [monthStart, monthEnd).
+	GetLedgerSummaries(ctx context.Context, propertyIDs []string, monthStart time.Time, monthEnd time.Time) ([]*LedgerSummary, error)
+	// GetEventRevisions returns the prior versions of an event, oldest first.
+	GetEventRevisions(ctx context.Context, propertyID string, eventID string) ([]*EventRevision, error)
 }
 
 type Handler struct {
 	store EventStore
+	// properties is the registry of the properties events can be saved to.
+	// Without one, as in tests of the ledger alone, any property ID is accepted.
+	properties PropertyStore
+	rates      RateProvider
+	// baseCurrency is the currency events are saved in when neither they nor their property
+	// have one, and the currency balance totals are converted to.
+	baseCurrency string
+	// observers are told of every event SaveEvent saves, and of both legs of every transfer.
+	observers []EventObserver
 }
 
-func NewHandler(store EventStore) *Handler {
+// EventObserver is told of the events saved by SaveEvent and Transfer, once they are in the ledger.
+// It is called synchronously, so it should not block.
+type EventObserver interface {
+	EventSaved(ctx context.Context, event *Event)
+}
+
+func NewHandler(store EventStore, properties PropertyStore, rates RateProvider, baseCurrency string) *Handler {
 	return &Handler{
-		store: store,
+		store:        store,
+		properties:   properties,
+		rates:        rates,
+		baseCurrency: baseCurrency,
+	}
+}
+
+// Observe registers o to be told of every event SaveEvent or Transfer saves from then on.
+// It is not safe to call concurrently with them.
+func (h *Handler) Observe(o EventObserver) {
+	h.observers = append(h.observers, o)
+}
+
+// notify tells every observer of events, which are in the ledger.
+func (h *Handler) notify(ctx context.Context, events ...*Event) {
+	for _, event := range events {
+		for _, o := range h.observers {
+			o.EventSaved(ctx, event)
+		}
 	}
 }
 
@@ -32,3 +138,18 @@ const (
 	Expense
 	Income
 )
+
+// retryOnConflict runs fn until it succeeds, fails with an error other than ErrConflict,
+// or maxConflictRetries attempts were made.
+func retryOnConflict(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < maxConflictRetries; attempt++ {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if err = fn(); !errors.Is(err, ErrConflict) {
+			return err
+		}
+	}
+	return err
+}
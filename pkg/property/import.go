@@ -0,0 +1,161 @@
+package property
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ImportLine is a line of a bank statement to import as an event.
+type ImportLine struct {
+	// Row is the position of the line in the statement, starting at 1.
+	Row         int
+	Date        time.Time
+	Amount      Money
+	Currency    string
+	Description string
+	Category    Category
+	// Reference is the identifier the bank gave the transaction, if any, such as the FITID of
+	// an OFX statement.
+	Reference string
+	// Err is why the line could not be parsed, the line being reported as errored.
+	Err error
+}
+
+type ImportStatus string
+
+const (
+	LineImported ImportStatus = "imported"
+	// LineSkipped marks a line imported before, or one without an amount.
+	LineSkipped ImportStatus = "skipped"
+	LineErrored ImportStatus = "error"
+)
+
+// ImportResult is the outcome of importing a line.
+type ImportResult struct {
+	Row    int
+	Status ImportStatus
+	// EventID is the event the line was imported as, or was imported as before.
+	EventID string
+	// Reason explains why the line was skipped or errored.
+	Reason string
+}
+
+type ImportReport struct {
+	Imported int
+	Skipped  int
+	Errored  int
+	// Lines holds the result of every line, in the order of the statement.
+	Lines []*ImportResult
+}
+
+// ImportEvents saves the lines of a bank statement as events of the property, in date order,
+// each line failing on its own without stopping the others.
+// Each line is saved with an idempotency key derived from its reference, or from its date, amount,
+// currency and description and how many identical lines precede it, so importing the same
+// statement again skips the lines already imported.
+func (h *Handler) ImportEvents(ctx context.Context, PropertyID string, lines []*ImportLine) (*ImportReport, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	}
+	if _, err := h.getRegisteredProperty(ctx, PropertyID); err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Lines: make([]*ImportResult, 0, len(lines))}
+	results := make(map[*ImportLine]*ImportResult, len(lines))
+	keys := importIdempotencyKeys(lines)
+
+	ordered := slices.Clone(lines)
+	slices.SortStableFunc(ordered, func(a, b *ImportLine) int {
+		return a.Date.Compare(b.Date)
+	})
+	for _, line := range ordered {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		results[line] = h.importLine(ctx, PropertyID, line, keys[line])
+	}
+
+	for _, line := range lines {
+		result := results[line]
+		switch result.Status {
+		case LineImported:
+			report.Imported++
+		case LineSkipped:
+			report.Skipped++
+		case LineErrored:
+			report.Errored++
+		}
+		report.Lines = append(report.Lines, result)
+	}
+	return report, nil
+}
+
+func (h *Handler) importLine(ctx context.Context, PropertyID string, line *ImportLine, key string) *ImportResult {
+	result := &ImportResult{Row: line.Row}
+	if line.Err != nil {
+		result.Status, result.Reason = LineErrored, line.Err.Error()
+		return result
+	} else if line.Amount.IsZero() {
+		result.Status, result.Reason = LineSkipped, "zero amount"
+		return result
+	}
+
+	event := &Event{
+		PropertyID:     PropertyID,
+		EventAmount:    line.Amount,
+		Currency:       line.Currency,
+		Date:           line.Date,
+		Description:    line.Description,
+		Category:       line.Category,
+		IdempotencyKey: key,
+	}
+	existing, exists, err := h.store.GetMostRecentEventForFilter(ctx, &EventFilter{
+		PropertyID:     PropertyID,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		result.Status, result.Reason = LineErrored, fmt.Sprintf("get events for filter: %v", err)
+		return result
+	}
+	if exists {
+		result.Status, result.EventID, result.Reason = LineSkipped, existing.ID, "already imported"
+		return result
+	}
+
+	if _, err := h.SaveEvent(ctx, event); err != nil {
+		result.Status, result.Reason = LineErrored, err.Error()
+		if errors.Is(err, ErrIdempotencyKeyReused) {
+			result.Reason = fmt.Sprintf("reference %q already imported with another amount", line.Reference)
+		}
+		return result
+	}
+	result.Status, result.EventID = LineImported, event.ID
+	return result
+}
+
+// importIdempotencyKeys derives the idempotency key of each line. Lines without a reference are
+// told apart from identical lines of the same statement by their order.
+func importIdempotencyKeys(lines []*ImportLine) map[*ImportLine]string {
+	keys := make(map[*ImportLine]string, len(lines))
+	occurrences := make(map[string]int)
+	for _, line := range lines {
+		var identity string
+		if line.Reference != "" {
+			identity = "ref\x00" + line.Reference
+		} else {
+			identity = fmt.Sprintf("line\x00%s\x00%s\x00%s\x00%s",
+				line.Date.UTC().Format(time.RFC3339Nano), line.Amount, line.Currency, line.Description)
+			occurrences[identity]++
+			identity = fmt.Sprintf("%s\x00%d", identity, occurrences[identity])
+		}
+		sum := sha256.Sum256([]byte(identity))
+		keys[line] = "import:" + hex.EncodeToString(sum[:])
+	}
+	return keys
+}
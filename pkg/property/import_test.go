@@ -0,0 +1,66 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHandler_ImportEvents(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+	}
+	store := NewMockEventStore(nil, false)
+	h := NewHandler(store, nil, nil, "USD")
+
+	// the statement lists the most recent lines first, and holds two identical coffees
+	lines := []*ImportLine{
+		{Row: 1, Date: day(9), Amount: mustMoney("-4.5"), Description: "Coffee"},
+		{Row: 2, Date: day(9), Amount: mustMoney("-4.5"), Description: "Coffee"},
+		{Row: 3, Date: day(7), Amount: mustMoney("-120.5"), Description: "Plumber", Reference: "A2"},
+		{Row: 4, Err: errors.New("invalid date")},
+		{Row: 5, Date: day(6), Description: "Balance check"},
+		{Row: 6, Date: day(5), Amount: mustMoney("1500"), Description: "Rent", Reference: "A1"},
+	}
+
+	report, err := h.ImportEvents(context.TODO(), "propID", lines)
+	if err != nil {
+		t.Fatalf("ImportEvents() unexpected error = %v", err)
+	}
+	if report.Imported != 4 || report.Skipped != 1 || report.Errored != 1 {
+		t.Errorf("ImportEvents() got %d imported, %d skipped, %d errored, want 4, 1, 1", report.Imported, report.Skipped, report.Errored)
+	}
+	wantStatus := []ImportStatus{LineImported, LineImported, LineImported, LineErrored, LineSkipped, LineImported}
+	for i, result := range report.Lines {
+		if result.Row != i+1 || result.Status != wantStatus[i] {
+			t.Errorf("ImportEvents() line %d got row %d %s (%s), want %s", i, result.Row, result.Status, result.Reason, wantStatus[i])
+		}
+	}
+
+	events, err := h.GetPropertyEvents(context.TODO(), &EventFilter{PropertyID: "propID"}, Ascending, 0, 0)
+	if err != nil {
+		t.Fatalf("GetPropertyEvents() unexpected error = %v", err)
+	}
+	wantBalances := []string{"1500", "1379.5", "1375", "1370.5"}
+	if len(events) != len(wantBalances) {
+		t.Fatalf("GetPropertyEvents() got %d events, want %d", len(events), len(wantBalances))
+	}
+	for i, e := range events {
+		if e.PostEventBalance != mustMoney(wantBalances[i]) || e.Sequence != int64(i+1) {
+			t.Errorf("event %d got balance %v at sequence %d, want %v saved in date order", i, e.PostEventBalance, e.Sequence, wantBalances[i])
+		}
+	}
+
+	// a statement overlapping the first one only adds its new lines
+	again, err := h.ImportEvents(context.TODO(), "propID", append(lines[:4:4], &ImportLine{Row: 5, Date: day(10), Amount: mustMoney("-30"), Description: "Gas"}))
+	if err != nil {
+		t.Fatalf("ImportEvents() unexpected error = %v", err)
+	}
+	if again.Imported != 1 || again.Skipped != 3 || again.Errored != 1 {
+		t.Errorf("ImportEvents() again got %d imported, %d skipped, %d errored, want 1, 3, 1", again.Imported, again.Skipped, again.Errored)
+	}
+	if again.Lines[2].EventID != report.Lines[2].EventID {
+		t.Errorf("ImportEvents() again got event %s for a line imported as %s", again.Lines[2].EventID, report.Lines[2].EventID)
+	}
+}
@@ -0,0 +1,219 @@
+package property
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MoneyScale is the number of decimal places a Money value holds exactly.
+const MoneyScale = 4
+
+// moneyUnitsPerOne is 10^MoneyScale, the number of units in one whole currency unit.
+const moneyUnitsPerOne = 10000
+
+// ErrOverflow is returned when a Money value or calculation does not fit in a Money.
+var ErrOverflow = errors.New("money overflow")
+
+// Money is an exact fixed-point amount with MoneyScale decimal places.
+// Its zero value is zero. It is serialized to JSON as a decimal string.
+type Money struct {
+	units int64
+}
+
+// MoneyFromUnits returns the Money holding units / 10^MoneyScale.
+func MoneyFromUnits(units int64) Money {
+	return Money{units: units}
+}
+
+// NewMoney returns the Money for a whole amount, such as 120 for 120.00.
+func NewMoney(amount int64) (Money, error) {
+	if amount > math.MaxInt64/moneyUnitsPerOne || amount < math.MinInt64/moneyUnitsPerOne {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: amount * moneyUnitsPerOne}, nil
+}
+
+// ParseMoney parses a decimal string such as "-1234.56".
+// It fails rather than round when s has more than MoneyScale decimal places.
+func ParseMoney(s string) (Money, error) {
+	str := strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		negative = str[0] == '-'
+		str = str[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(str, ".")
+	if whole == "" && fraction == "" {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(fraction) > MoneyScale {
+		return Money{}, fmt.Errorf("invalid amount %q: more than %d decimal places", s, MoneyScale)
+	}
+	digits := whole + fraction + strings.Repeat("0", MoneyScale-len(fraction))
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return Money{}, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+
+	units, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil || units > math.MaxInt64 {
+		return Money{}, fmt.Errorf("invalid amount %q: %w", s, ErrOverflow)
+	}
+	if negative {
+		return Money{units: -int64(units)}, nil
+	}
+	return Money{units: int64(units)}, nil
+}
+
+// Units returns the amount in units of 1 / 10^MoneyScale.
+func (m Money) Units() int64 {
+	return m.units
+}
+
+func (m Money) IsZero() bool {
+	return m.units == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.units < 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.units > 0
+}
+
+// Cmp returns -1, 0 or 1 depending on whether m is less than, equal to or greater than o.
+func (m Money) Cmp(o Money) int {
+	switch {
+	case m.units < o.units:
+		return -1
+	case m.units > o.units:
+		return 1
+	}
+	return 0
+}
+
+// Add returns m + o, or ErrOverflow if the sum does not fit in a Money.
+func (m Money) Add(o Money) (Money, error) {
+	sum := m.units + o.units
+	if (o.units > 0 && sum < m.units) || (o.units < 0 && sum > m.units) {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: sum}, nil
+}
+
+// Sub returns m - o, or ErrOverflow if the difference does not fit in a Money.
+func (m Money) Sub(o Money) (Money, error) {
+	if o.units == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(Money{units: -o.units})
+}
+
+// Neg returns -m, or ErrOverflow for the one value that has no negation.
+func (m Money) Neg() (Money, error) {
+	if m.units == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: -m.units}, nil
+}
+
+// Convert returns m multiplied by rate, rounded half away from zero to MoneyScale decimal places.
+func (m Money) Convert(rate *big.Rat) (Money, error) {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(m.units), rate)
+
+	quotient, remainder := new(big.Int).QuoRem(product.Num(), product.Denom(), new(big.Int))
+	if new(big.Int).Lsh(remainder.Abs(remainder), 1).Cmp(product.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(product.Sign())))
+	}
+
+	if !quotient.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: quotient.Int64()}, nil
+}
+
+// String formats m with at least two decimal places, such as "12.50" or "-0.1234".
+func (m Money) String() string {
+	units := uint64(m.units)
+	sign := ""
+	if m.units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	whole := units / moneyUnitsPerOne
+	fraction := fmt.Sprintf("%0*d", MoneyScale, units%moneyUnitsPerOne)
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) < 2 {
+		fraction += strings.Repeat("0", 2-len(fraction))
+	}
+	return fmt.Sprintf("%s%d.%s", sign, whole, fraction)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON accepts both a decimal string and a JSON number, parsing either exactly, and
+// leaves m unchanged for a JSON null, as encoding/json expects.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	s := string(b)
+	if bytes.HasPrefix(b, []byte(`"`)) {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalText(b []byte) error {
+	parsed, err := ParseMoney(string(b))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Split divides m into n parts that add up to m exactly, the last part taking the remainder.
+// n must be positive.
+func (m Money) Split(n int) ([]Money, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("cannot split into %d parts", n)
+	}
+	parts := make([]Money, n)
+	for i := range parts {
+		parts[i] = Money{units: m.units / int64(n)}
+	}
+	parts[n-1].units += m.units % int64(n)
+	return parts, nil
+}
+
+// Percent returns m as a percentage of o, or 0 if o is zero.
+func (m Money) Percent(o Money) float64 {
+	if o.units == 0 {
+		return 0
+	}
+	return float64(m.units) / float64(o.units) * 100
+}
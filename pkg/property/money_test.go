@@ -0,0 +1,164 @@
+package property
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "whole amount", input: "120", want: "120.00"},
+		{name: "cents", input: "0.1", want: "0.10"},
+		{name: "negative", input: "-1234.56", want: "-1234.56"},
+		{name: "explicit plus sign", input: "+7.5", want: "7.50"},
+		{name: "full scale", input: "0.0001", want: "0.0001"},
+		{name: "no whole part", input: ".25", want: "0.25"},
+		{name: "too many decimals", input: "0.00001", wantErr: true},
+		{name: "not a number", input: "12a.5", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+		{name: "overflow", input: "922337203685477.5808", wantErr: true},
+		{name: "largest", input: "922337203685477.5807", want: "922337203685477.5807"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMoney(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseMoney() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("ParseMoney() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_Add_Overflow(t *testing.T) {
+	largest := MoneyFromUnits(math.MaxInt64)
+	if _, err := largest.Add(MoneyFromUnits(1)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Add() error = %v, want %v", err, ErrOverflow)
+	}
+	smallest := MoneyFromUnits(math.MinInt64)
+	if _, err := smallest.Sub(MoneyFromUnits(1)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Sub() error = %v, want %v", err, ErrOverflow)
+	}
+	if _, err := smallest.Neg(); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Neg() error = %v, want %v", err, ErrOverflow)
+	}
+}
+
+func TestMoney_Add_NoDrift(t *testing.T) {
+	sum := Money{}
+	cent := mustMoney("0.01")
+	for i := 0; i < 100000; i++ {
+		var err error
+		if sum, err = sum.Add(cent); err != nil {
+			t.Fatalf("Add() unexpected error = %v", err)
+		}
+	}
+	if sum != mustMoney("1000") {
+		t.Errorf("Add() got = %v, want %v", sum, "1000.00")
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	var got struct {
+		FromString Money `json:"from_string"`
+		FromNumber Money `json:"from_number"`
+	}
+	if err := json.Unmarshal([]byte(`{"from_string":"10.05","from_number":0.3}`), &got); err != nil {
+		t.Fatalf("Unmarshal() unexpected error = %v", err)
+	}
+	if got.FromString != mustMoney("10.05") || got.FromNumber != mustMoney("0.3") {
+		t.Errorf("Unmarshal() got = %v, %v", got.FromString, got.FromNumber)
+	}
+
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error = %v", err)
+	}
+	if want := `{"from_string":"10.05","from_number":"0.30"}`; string(b) != want {
+		t.Errorf("Marshal() got = %s, want %s", b, want)
+	}
+
+	var optional struct {
+		Set   *Money `json:"set"`
+		Unset *Money `json:"unset"`
+		Kept  Money  `json:"kept"`
+	}
+	optional.Kept = mustMoney("7")
+	if err := json.Unmarshal([]byte(`{"set":"1.50","unset":null,"kept":null}`), &optional); err != nil {
+		t.Fatalf("Unmarshal() of null unexpected error = %v", err)
+	}
+	if optional.Set == nil || *optional.Set != mustMoney("1.50") || optional.Unset != nil || optional.Kept != mustMoney("7") {
+		t.Errorf("Unmarshal() of null got = %v, %v, %v", optional.Set, optional.Unset, optional.Kept)
+	}
+}
+
+func TestMoney_Convert(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		rate  string
+		want  string
+	}{
+		{name: "exact", input: "100", rate: "1.0832", want: "108.32"},
+		{name: "rounds half up", input: "0.0001", rate: "0.5", want: "0.0001"},
+		{name: "rounds down", input: "0.0001", rate: "0.4", want: "0.00"},
+		{name: "rounds negative away from zero", input: "-0.0001", rate: "0.5", want: "-0.0001"},
+		{name: "inverse rate", input: "300", rate: "1/3", want: "100.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, _ := new(big.Rat).SetString(tt.rate)
+			got, err := mustMoney(tt.input).Convert(rate)
+			if err != nil {
+				t.Errorf("Convert() unexpected error = %v", err)
+				return
+			}
+			if got.String() != tt.want {
+				t.Errorf("Convert() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_Split(t *testing.T) {
+	tests := []struct {
+		name    string
+		money   Money
+		n       int
+		want    []string
+		wantErr bool
+	}{
+		{name: "even", money: mustMoney("3000"), n: 3, want: []string{"1000.00", "1000.00", "1000.00"}},
+		{name: "remainder to the last part", money: mustMoney("0.0005"), n: 3, want: []string{"0.0001", "0.0001", "0.0003"}},
+		{name: "negative", money: mustMoney("-0.0005"), n: 3, want: []string{"-0.0001", "-0.0001", "-0.0003"}},
+		{name: "no parts", money: mustMoney("10"), n: 0, wantErr: true},
+		{name: "negative parts", money: mustMoney("10"), n: -2, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.money.Split(tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Split() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Split() got %d parts, want %d", len(got), len(tt.want))
+			}
+			for i, part := range got {
+				if part.String() != tt.want[i] {
+					t.Errorf("Split() part %d got = %v, want %v", i, part, tt.want[i])
+				}
+			}
+		})
+	}
+}
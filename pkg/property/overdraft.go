@@ -0,0 +1,100 @@
+package property
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrOverdraft is matched by the OverdraftError of an event rejected by the overdraft policy
+// of its property.
+var ErrOverdraft = errors.New("overdraft")
+
+// OverdraftPolicy is what happens to an event that would take the balance of its property
+// below the minimum balance.
+type OverdraftPolicy string
+
+const (
+	// OverdraftAllow saves the event.
+	OverdraftAllow OverdraftPolicy = "allow"
+	// OverdraftWarn saves the event, marked as Overdrawn.
+	OverdraftWarn OverdraftPolicy = "warn"
+	// OverdraftReject fails the save with an OverdraftError.
+	OverdraftReject OverdraftPolicy = "reject"
+)
+
+func (p OverdraftPolicy) IsValid() bool {
+	switch p {
+	case OverdraftAllow, OverdraftWarn, OverdraftReject:
+		return true
+	}
+	return false
+}
+
+// OverdraftError is returned for a ledger write rejected by the overdraft policy of its property,
+// such as an expense saved by SaveEvent, a transfer out, a reversal of an income or an amendment.
+type OverdraftError struct {
+	PropertyID string
+	Currency   string
+	// ProjectedBalance is the lowest balance the event would have left the property with.
+	ProjectedBalance Money
+	Minimum          Money
+}
+
+func (e *OverdraftError) Error() string {
+	return fmt.Sprintf("property %s: projected %s balance %v below minimum %v", e.PropertyID, e.Currency, e.ProjectedBalance, e.Minimum)
+}
+
+func (e *OverdraftError) Is(target error) bool {
+	return target == ErrOverdraft
+}
+
+// minimumBalance returns the balance the events of property in currency must not go below:
+// the MinimumBalance of the property in its own currency, and zero in any other.
+func (p *Property) minimumBalance(currency string) Money {
+	if currency == p.Currency {
+		return p.MinimumBalance
+	}
+	return Money{}
+}
+
+// checkOverdraft applies the overdraft policy of property to a write to its ledger. The write is an
+// overdraft if it lowers the balance of any event below the minimum, as an expense does at its date
+// and at the date of every later event it shifts. Without a registered property, anything goes.
+func checkOverdraft(property *Property, write *LedgerWrite) error {
+	if write.event != nil {
+		write.event.Overdrawn = false
+	}
+	if property == nil || len(write.lowered) == 0 {
+		return nil
+	}
+	// properties registered before overdraft policies have none, and allow overdrafts
+	if property.OverdraftPolicy != OverdraftWarn && property.OverdraftPolicy != OverdraftReject {
+		return nil
+	}
+
+	var lowest *Event
+	for _, e := range write.lowered {
+		if e.PostEventBalance.Cmp(property.minimumBalance(e.Currency)) >= 0 {
+			continue
+		}
+		if lowest == nil || e.PostEventBalance.Cmp(lowest.PostEventBalance) < 0 {
+			lowest = e
+		}
+	}
+	if lowest == nil {
+		return nil
+	}
+
+	if property.OverdraftPolicy == OverdraftWarn {
+		if write.event != nil {
+			write.event.Overdrawn = true
+		}
+		return nil
+	}
+	return &OverdraftError{
+		PropertyID:       property.ID,
+		Currency:         lowest.Currency,
+		ProjectedBalance: lowest.PostEventBalance,
+		Minimum:          property.minimumBalance(lowest.Currency),
+	}
+}
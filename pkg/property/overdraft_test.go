@@ -0,0 +1,237 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHandler_SaveEvent_Overdraft(t *testing.T) {
+	date := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		policy        OverdraftPolicy
+		minimum       string
+		amount        string
+		currency      string
+		date          time.Time
+		wantOverdrawn bool
+		wantProjected string
+		wantMinimum   string
+	}{
+		{
+			name:    "allowed",
+			policy:  OverdraftAllow,
+			amount:  "-500",
+			minimum: "0",
+		},
+		{
+			name:    "no policy",
+			amount:  "-500",
+			minimum: "0",
+		},
+		{
+			name:          "warned",
+			policy:        OverdraftWarn,
+			amount:        "-500",
+			minimum:       "0",
+			wantOverdrawn: true,
+		},
+		{
+			name:          "rejected below zero",
+			policy:        OverdraftReject,
+			amount:        "-500",
+			minimum:       "0",
+			wantProjected: "-480",
+		},
+		{
+			name:          "rejected below the minimum",
+			policy:        OverdraftReject,
+			amount:        "-15",
+			minimum:       "10",
+			wantProjected: "5",
+		},
+		{
+			name:    "down to the minimum",
+			policy:  OverdraftReject,
+			amount:  "-10",
+			minimum: "10",
+		},
+		{
+			name:    "within an overdraft limit",
+			policy:  OverdraftReject,
+			amount:  "-500",
+			minimum: "-1000",
+		},
+		{
+			name:          "other currencies have no overdraft limit",
+			policy:        OverdraftReject,
+			amount:        "-100",
+			minimum:       "-1000",
+			currency:      "EUR",
+			wantProjected: "-100",
+			wantMinimum:   "0",
+		},
+		{
+			// the balance after the backdated expense stays positive, but the later expense of
+			// the property would leave it below zero
+			name:          "backdated below a later expense",
+			policy:        OverdraftReject,
+			amount:        "-80",
+			minimum:       "0",
+			date:          date.AddDate(0, 0, 1),
+			wantProjected: "-60",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			properties := NewMockPropertyStore(&Property{ID: "propID", Name: "Elm Street 13", Currency: "USD", Timezone: "UTC",
+				OverdraftPolicy: tt.policy, MinimumBalance: mustMoney(tt.minimum)})
+			events := NewMockEventStore([]*Event{
+				{ID: "1", PropertyID: "propID", EventAmount: mustMoney("100"), PostEventBalance: mustMoney("100"), Currency: "USD", Date: date, Sequence: 1},
+				{ID: "2", PropertyID: "propID", EventAmount: mustMoney("-80"), PostEventBalance: mustMoney("20"), Currency: "USD", Date: date.AddDate(0, 0, 2), Sequence: 2},
+			}, false)
+			h := NewHandler(events, properties, nil, "USD")
+
+			eventDate := tt.date
+			if eventDate.IsZero() {
+				eventDate = date.AddDate(0, 0, 3)
+			}
+			event := &Event{PropertyID: "propID", EventAmount: mustMoney(tt.amount), Currency: tt.currency, Date: eventDate}
+			_, err := h.SaveEvent(context.TODO(), event)
+
+			if tt.wantProjected != "" {
+				var overdraft *OverdraftError
+				if !errors.As(err, &overdraft) || !errors.Is(err, ErrOverdraft) {
+					t.Fatalf("SaveEvent() error = %v, want an overdraft error", err)
+				}
+				wantMinimum := tt.minimum
+				if tt.wantMinimum != "" {
+					wantMinimum = tt.wantMinimum
+				}
+				if overdraft.ProjectedBalance != mustMoney(tt.wantProjected) || overdraft.Minimum != mustMoney(wantMinimum) {
+					t.Errorf("SaveEvent() got projected balance %v and minimum %v, want %v and %v",
+						overdraft.ProjectedBalance, overdraft.Minimum, tt.wantProjected, wantMinimum)
+				}
+				if head, _, _ := events.GetLedgerHead(context.TODO(), "propID"); head.ID != "2" {
+					t.Errorf("SaveEvent() saved a rejected event")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SaveEvent() unexpected error = %v", err)
+			}
+			if event.Overdrawn != tt.wantOverdrawn {
+				t.Errorf("SaveEvent() overdrawn got = %v, want %v", event.Overdrawn, tt.wantOverdrawn)
+			}
+		})
+	}
+}
+
+// seedOverdraft returns a handler over a property with an income of 100 followed by an expense
+// of 80, and the event store.
+func seedOverdraft(policy OverdraftPolicy, minimum string) (*Handler, *MockEventStore) {
+	date := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	properties := NewMockPropertyStore(&Property{ID: "propID", Name: "Elm Street 13", Currency: "USD", Timezone: "UTC",
+		OverdraftPolicy: policy, MinimumBalance: mustMoney(minimum)})
+	events := NewMockEventStore([]*Event{
+		{ID: "1", PropertyID: "propID", EventAmount: mustMoney("100"), PostEventBalance: mustMoney("100"), Currency: "USD", Date: date, Sequence: 1},
+		{ID: "2", PropertyID: "propID", EventAmount: mustMoney("-80"), PostEventBalance: mustMoney("20"), Currency: "USD", Date: date.AddDate(0, 0, 2), Sequence: 2},
+	}, false)
+	return NewHandler(events, properties, nil, "USD"), events
+}
+
+func TestHandler_ReverseEvent_Overdraft(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        OverdraftPolicy
+		eventID       string
+		wantOverdrawn bool
+		wantProjected string
+	}{
+		{name: "income rejected", policy: OverdraftReject, eventID: "1", wantProjected: "-80"},
+		{name: "income warned", policy: OverdraftWarn, eventID: "1", wantOverdrawn: true},
+		{name: "income allowed", policy: OverdraftAllow, eventID: "1"},
+		{name: "expense", policy: OverdraftReject, eventID: "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, events := seedOverdraft(tt.policy, "0")
+			compensating, err := h.ReverseEvent(context.TODO(), "propID", tt.eventID, "mistake")
+			if tt.wantProjected != "" {
+				var overdraft *OverdraftError
+				if !errors.As(err, &overdraft) || overdraft.ProjectedBalance != mustMoney(tt.wantProjected) {
+					t.Fatalf("ReverseEvent() error = %v, want an overdraft error projecting %s", err, tt.wantProjected)
+				}
+				if head, _, _ := events.GetLedgerHead(context.TODO(), "propID"); head.ID != "2" {
+					t.Errorf("ReverseEvent() saved a rejected reversal")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReverseEvent() unexpected error = %v", err)
+			}
+			if compensating.Overdrawn != tt.wantOverdrawn {
+				t.Errorf("ReverseEvent() overdrawn got = %v, want %v", compensating.Overdrawn, tt.wantOverdrawn)
+			}
+		})
+	}
+}
+
+func TestHandler_AmendEvent_Overdraft(t *testing.T) {
+	earlier := time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		policy        OverdraftPolicy
+		minimum       string
+		eventID       string
+		amount        string
+		date          *time.Time
+		wantOverdrawn bool
+		wantProjected string
+	}{
+		{name: "larger expense rejected", policy: OverdraftReject, minimum: "0", eventID: "2", amount: "-150", wantProjected: "-50"},
+		{name: "larger expense warned", policy: OverdraftWarn, minimum: "0", eventID: "2", amount: "-150", wantOverdrawn: true},
+		{name: "smaller income rejected", policy: OverdraftReject, minimum: "0", eventID: "1", amount: "50", wantProjected: "-30"},
+		{name: "expense moved before the income", policy: OverdraftReject, minimum: "0", eventID: "2", date: &earlier, wantProjected: "-80"},
+		{name: "smaller expense", policy: OverdraftReject, minimum: "0", eventID: "2", amount: "-10"},
+		{
+			// the balance after the expense is already below the minimum, and the amendment raises it
+			name:    "smaller expense below the minimum",
+			policy:  OverdraftReject,
+			minimum: "50",
+			eventID: "2",
+			amount:  "-60",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := seedOverdraft(tt.policy, tt.minimum)
+			amendment := &Amendment{Date: tt.date, AmendedBy: "alice"}
+			if tt.amount != "" {
+				amount := mustMoney(tt.amount)
+				amendment.EventAmount = &amount
+			}
+			amended, err := h.AmendEvent(context.TODO(), "propID", tt.eventID, amendment)
+			if tt.wantProjected != "" {
+				var overdraft *OverdraftError
+				if !errors.As(err, &overdraft) || overdraft.ProjectedBalance != mustMoney(tt.wantProjected) {
+					t.Fatalf("AmendEvent() error = %v, want an overdraft error projecting %s", err, tt.wantProjected)
+				}
+				if event, _ := h.getEvent(context.TODO(), "propID", tt.eventID); event.Revision != 0 {
+					t.Errorf("AmendEvent() saved a rejected amendment")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AmendEvent() unexpected error = %v", err)
+			}
+			if amended.Overdrawn != tt.wantOverdrawn {
+				t.Errorf("AmendEvent() overdrawn got = %v, want %v", amended.Overdrawn, tt.wantOverdrawn)
+			}
+		})
+	}
+}
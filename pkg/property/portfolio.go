@@ -0,0 +1,257 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// ErrPortfolioNotFound is returned when a portfolio ID does not match any portfolio.
+var ErrPortfolioNotFound = errors.New("portfolio not found")
+
+// Portfolio is a named group of properties, reported on as one.
+type Portfolio struct {
+	// ID is assigned by the PortfolioStore when the portfolio is created.
+	ID          string   `json:"id" bson:"_id"`
+	Name        string   `json:"name" bson:"name"`
+	PropertyIDs []string `json:"property_ids" bson:"property_ids"`
+}
+
+type PortfolioStore interface {
+	// CreatePortfolio saves a new portfolio, assigning it an ID.
+	CreatePortfolio(ctx context.Context, portfolio *Portfolio) error
+	// UpdatePortfolio replaces a portfolio, and reports whether it exists.
+	UpdatePortfolio(ctx context.Context, portfolio *Portfolio) (bool, error)
+	GetPortfolio(ctx context.Context, portfolioID string) (*Portfolio, bool, error)
+	GetPortfolios(ctx context.Context) ([]*Portfolio, error)
+	// DeletePortfolio reports whether the portfolio existed.
+	DeletePortfolio(ctx context.Context, portfolioID string) (bool, error)
+}
+
+// PortfolioBalance is the combined balance of the properties of a portfolio.
+type PortfolioBalance struct {
+	Balance *Balance
+	// ByProperty maps the ID of each property of the portfolio to its own balance.
+	ByProperty map[string]*Balance
+}
+
+// PropertySubtotal is the share of one property in a portfolio report.
+type PropertySubtotal struct {
+	PropertyID      string
+	StartingBalance *Balance
+	// Net is the sum of the property events in the period per currency, and NetTotal
+	// their total in the base currency.
+	Net      map[string]Money
+	NetTotal Money
+}
+
+// PortfolioReport is the report of a period merged across the properties of a portfolio.
+type PortfolioReport struct {
+	// Events are the events of every property of the portfolio, in date order.
+	Events []*Event
+	// StartingBalance is the combined balance of the properties at the start of the period.
+	StartingBalance *Balance
+	Subtotals       []*PropertySubtotal
+}
+
+// PortfolioHandler manages portfolios, and consolidates the ledgers of their properties.
+type PortfolioHandler struct {
+	handler *Handler
+	store   PortfolioStore
+}
+
+func NewPortfolioHandler(handler *Handler, store PortfolioStore) *PortfolioHandler {
+	return &PortfolioHandler{
+		handler: handler,
+		store:   store,
+	}
+}
+
+// CreatePortfolio saves a new portfolio of registered properties.
+// The created portfolio, including its ID, is written back to portfolio.
+func (p *PortfolioHandler) CreatePortfolio(ctx context.Context, portfolio *Portfolio) error {
+	if err := p.validatePortfolio(ctx, portfolio); err != nil {
+		return err
+	}
+
+	if err := p.store.CreatePortfolio(ctx, portfolio); err != nil {
+		return fmt.Errorf("create portfolio: %v", err)
+	}
+	return nil
+}
+
+// UpdatePortfolio renames a portfolio or changes its properties.
+func (p *PortfolioHandler) UpdatePortfolio(ctx context.Context, portfolio *Portfolio) error {
+	if portfolio.ID == "" {
+		return fmt.Errorf("empty portfolio ID")
+	}
+	if err := p.validatePortfolio(ctx, portfolio); err != nil {
+		return err
+	}
+
+	exists, err := p.store.UpdatePortfolio(ctx, portfolio)
+	if err != nil {
+		return fmt.Errorf("update portfolio: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("portfolio %s: %w", portfolio.ID, ErrPortfolioNotFound)
+	}
+	return nil
+}
+
+func (p *PortfolioHandler) GetPortfolio(ctx context.Context, portfolioID string) (*Portfolio, error) {
+	if portfolioID == "" {
+		return nil, fmt.Errorf("empty portfolio ID")
+	}
+
+	portfolio, exists, err := p.store.GetPortfolio(ctx, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("get portfolio: %v", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("portfolio %s: %w", portfolioID, ErrPortfolioNotFound)
+	}
+	return portfolio, nil
+}
+
+func (p *PortfolioHandler) GetPortfolios(ctx context.Context) ([]*Portfolio, error) {
+	portfolios, err := p.store.GetPortfolios(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get portfolios: %v", err)
+	}
+	return portfolios, nil
+}
+
+// DeletePortfolio deletes a portfolio, leaving its properties as they are.
+func (p *PortfolioHandler) DeletePortfolio(ctx context.Context, portfolioID string) error {
+	if portfolioID == "" {
+		return fmt.Errorf("empty portfolio ID")
+	}
+
+	exists, err := p.store.DeletePortfolio(ctx, portfolioID)
+	if err != nil {
+		return fmt.Errorf("delete portfolio: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("portfolio %s: %w", portfolioID, ErrPortfolioNotFound)
+	}
+	return nil
+}
+
+// validatePortfolio checks that portfolio is named and only holds registered properties,
+// dropping repeated ones.
+func (p *PortfolioHandler) validatePortfolio(ctx context.Context, portfolio *Portfolio) error {
+	portfolio.Name = strings.TrimSpace(portfolio.Name)
+	if portfolio.Name == "" {
+		return fmt.Errorf("%w: empty portfolio name", ErrInvalid)
+	}
+
+	portfolio.PropertyIDs = lo.Uniq(lo.Compact(portfolio.PropertyIDs))
+	if len(portfolio.PropertyIDs) == 0 {
+		return fmt.Errorf("%w: empty portfolio", ErrInvalid)
+	}
+	for _, PropertyID := range portfolio.PropertyIDs {
+		if _, err := p.handler.getRegisteredProperty(ctx, PropertyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPortfolioBalance returns the current balance of each property of the portfolio, and their
+// combined balance.
+func (p *PortfolioHandler) GetPortfolioBalance(ctx context.Context, portfolioID string) (*PortfolioBalance, error) {
+	portfolio, err := p.GetPortfolio(ctx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	balance := &PortfolioBalance{ByProperty: make(map[string]*Balance, len(portfolio.PropertyIDs))}
+	for _, PropertyID := range portfolio.PropertyIDs {
+		if balance.ByProperty[PropertyID], err = p.handler.GetBalance(ctx, PropertyID); err != nil {
+			return nil, fmt.Errorf("property %s: %w", PropertyID, err)
+		}
+	}
+	if balance.Balance, err = p.handler.combineBalances(ctx, balance.ByProperty, now); err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+// GetPortfolioMonthlyReport is the GetMonthlyReport of a portfolio: the events of all its
+// properties in the month, merged in date order, along with the starting balance and net of each
+// property and their combined starting balance. Transfers are left out of the events and the nets
+// if excludeTransfers is set. As its properties may lie in different time zones, the month is a UTC month.
+func (p *PortfolioHandler) GetPortfolioMonthlyReport(ctx context.Context, portfolioID string, month time.Month, year int, excludeTransfers bool, offset int, limit int) (*PortfolioReport, error) {
+	portfolio, err := p.GetPortfolio(ctx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	startOfMonth, endOfMonth := UTCCalendar.Month(month, year)
+
+	report := &PortfolioReport{Subtotals: make([]*PropertySubtotal, 0, len(portfolio.PropertyIDs))}
+	startingBalances := make(map[string]*Balance, len(portfolio.PropertyIDs))
+	for _, PropertyID := range portfolio.PropertyIDs {
+		totals, err := p.handler.GetPeriodTotals(ctx, PropertyID, startOfMonth, endOfMonth, excludeTransfers)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", PropertyID, err)
+		}
+		subtotal := &PropertySubtotal{
+			PropertyID:      PropertyID,
+			StartingBalance: totals.StartingBalance,
+			Net: lo.PickBy(totals.Net, func(_ string, net Money) bool {
+				return !net.IsZero()
+			}),
+		}
+		if subtotal.NetTotal, err = p.handler.convertTotal(ctx, subtotal.Net, endOfMonth); err != nil {
+			return nil, fmt.Errorf("property %s: %w", PropertyID, err)
+		}
+		startingBalances[PropertyID] = subtotal.StartingBalance
+		report.Subtotals = append(report.Subtotals, subtotal)
+	}
+	if report.StartingBalance, err = p.handler.combineBalances(ctx, startingBalances, startOfMonth); err != nil {
+		return nil, err
+	}
+
+	filter := &EventFilter{
+		PropertyIDs:      portfolio.PropertyIDs,
+		AfterTime:        startOfMonth,
+		EndTime:          endOfMonth,
+		ExcludeTransfers: excludeTransfers,
+	}
+	if report.Events, err = p.handler.GetPropertyEvents(ctx, filter, Ascending, offset, limit); err != nil {
+		return nil, fmt.Errorf("get property events: %v", err)
+	}
+	return report, nil
+}
+
+// combineBalances sums the balances of several properties per currency into a Balance as of date.
+func (h *Handler) combineBalances(ctx context.Context, balances map[string]*Balance, date time.Time) (*Balance, error) {
+	combined := &Balance{
+		ByCurrency:   make(map[string]Money),
+		BaseCurrency: h.baseCurrency,
+		AsOf:         date,
+	}
+	for _, balance := range balances {
+		for currency, amount := range balance.ByCurrency {
+			sum, err := combined.ByCurrency[currency].Add(amount)
+			if err != nil {
+				return nil, fmt.Errorf("combine balances: %w", err)
+			}
+			combined.ByCurrency[currency] = sum
+		}
+	}
+
+	total, err := h.convertTotal(ctx, combined.ByCurrency, date)
+	if err != nil {
+		return nil, err
+	}
+	combined.Total = total
+	return combined, nil
+}
@@ -0,0 +1,261 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+type MockPortfolioStore struct {
+	portfolios map[string]*Portfolio
+}
+
+func NewMockPortfolioStore(portfolios ...*Portfolio) *MockPortfolioStore {
+	m := &MockPortfolioStore{portfolios: map[string]*Portfolio{}}
+	for _, p := range portfolios {
+		m.portfolios[p.ID] = p
+	}
+	return m
+}
+
+func (m *MockPortfolioStore) CreatePortfolio(ctx context.Context, portfolio *Portfolio) error {
+	portfolio.ID = gofakeit.UUID()
+	c := *portfolio
+	m.portfolios[portfolio.ID] = &c
+	return nil
+}
+
+func (m *MockPortfolioStore) UpdatePortfolio(ctx context.Context, portfolio *Portfolio) (bool, error) {
+	if _, ok := m.portfolios[portfolio.ID]; !ok {
+		return false, nil
+	}
+	c := *portfolio
+	m.portfolios[portfolio.ID] = &c
+	return true, nil
+}
+
+func (m *MockPortfolioStore) GetPortfolio(ctx context.Context, portfolioID string) (*Portfolio, bool, error) {
+	p, ok := m.portfolios[portfolioID]
+	if !ok {
+		return nil, false, nil
+	}
+	c := *p
+	return &c, true, nil
+}
+
+func (m *MockPortfolioStore) GetPortfolios(ctx context.Context) ([]*Portfolio, error) {
+	portfolios := make([]*Portfolio, 0, len(m.portfolios))
+	for _, p := range m.portfolios {
+		c := *p
+		portfolios = append(portfolios, &c)
+	}
+	return portfolios, nil
+}
+
+func (m *MockPortfolioStore) DeletePortfolio(ctx context.Context, portfolioID string) (bool, error) {
+	_, ok := m.portfolios[portfolioID]
+	delete(m.portfolios, portfolioID)
+	return ok, nil
+}
+
+func TestPortfolioHandler_CreatePortfolio(t *testing.T) {
+	properties := NewMockPropertyStore(
+		&Property{ID: "elm", Name: "Elm Street 13"},
+		&Property{ID: "baker", Name: "Baker Street 221b"},
+	)
+
+	tests := []struct {
+		name      string
+		portfolio *Portfolio
+		wantIDs   []string
+		wantErr   error
+	}{
+		{
+			name:      "drops repeated properties",
+			portfolio: &Portfolio{Name: " Rentals ", PropertyIDs: []string{"elm", "baker", "elm"}},
+			wantIDs:   []string{"elm", "baker"},
+		},
+		{
+			name:      "unknown property",
+			portfolio: &Portfolio{Name: "Rentals", PropertyIDs: []string{"elm", "nowhere"}},
+			wantErr:   ErrPropertyNotFound,
+		},
+		{
+			name:      "no properties",
+			portfolio: &Portfolio{Name: "Rentals"},
+			wantErr:   ErrInvalid,
+		},
+		{
+			name:      "no name",
+			portfolio: &Portfolio{PropertyIDs: []string{"elm"}},
+			wantErr:   ErrInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPortfolioHandler(NewHandler(NewMockEventStore(nil, false), properties, nil, "USD"), NewMockPortfolioStore())
+			err := p.CreatePortfolio(context.TODO(), tt.portfolio)
+			if tt.wantErr != nil {
+				if err == nil || (!errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error()) {
+					t.Fatalf("CreatePortfolio() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreatePortfolio() unexpected error = %v", err)
+			}
+
+			got, err := p.GetPortfolio(context.TODO(), tt.portfolio.ID)
+			if err != nil {
+				t.Fatalf("GetPortfolio() unexpected error = %v", err)
+			}
+			if got.Name != "Rentals" || len(got.PropertyIDs) != len(tt.wantIDs) {
+				t.Fatalf("GetPortfolio() got = %+v, want properties %v", got, tt.wantIDs)
+			}
+			for i, id := range tt.wantIDs {
+				if got.PropertyIDs[i] != id {
+					t.Errorf("GetPortfolio() property %d got = %v, want %v", i, got.PropertyIDs[i], id)
+				}
+			}
+		})
+	}
+}
+
+func TestPortfolioHandler_GetPortfolioBalance(t *testing.T) {
+	date := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+	events := NewMockEventStore([]*Event{
+		{ID: "1", PropertyID: "elm", EventAmount: mustMoney("1000"), PostEventBalance: mustMoney("1000"), Currency: "USD", Date: date, Sequence: 1},
+		{ID: "2", PropertyID: "baker", EventAmount: mustMoney("500"), PostEventBalance: mustMoney("500"), Currency: "USD", Date: date, Sequence: 1},
+		{ID: "3", PropertyID: "baker", EventAmount: mustMoney("100"), PostEventBalance: mustMoney("100"), Currency: "EUR", Date: date, Sequence: 2},
+		{ID: "4", PropertyID: "other", EventAmount: mustMoney("9999"), PostEventBalance: mustMoney("9999"), Currency: "USD", Date: date, Sequence: 1},
+	}, false)
+	h := NewHandler(events, nil, MockRateProvider{"EURUSD": big.NewRat(11, 10)}, "USD")
+	p := NewPortfolioHandler(h, NewMockPortfolioStore(&Portfolio{ID: "rentals", Name: "Rentals", PropertyIDs: []string{"elm", "baker"}}))
+
+	got, err := p.GetPortfolioBalance(context.TODO(), "rentals")
+	if err != nil {
+		t.Fatalf("GetPortfolioBalance() unexpected error = %v", err)
+	}
+	if got.Balance.ByCurrency["USD"] != mustMoney("1500") || got.Balance.ByCurrency["EUR"] != mustMoney("100") {
+		t.Errorf("GetPortfolioBalance() by currency got = %v", got.Balance.ByCurrency)
+	}
+	if got.Balance.Total != mustMoney("1610") {
+		t.Errorf("GetPortfolioBalance() total got = %v, want %v", got.Balance.Total, "1610.00")
+	}
+	if got.ByProperty["elm"].Total != mustMoney("1000") || got.ByProperty["baker"].Total != mustMoney("610") {
+		t.Errorf("GetPortfolioBalance() by property got = %v, %v", got.ByProperty["elm"].Total, got.ByProperty["baker"].Total)
+	}
+
+	if _, err := p.GetPortfolioBalance(context.TODO(), "missing"); !errors.Is(err, ErrPortfolioNotFound) {
+		t.Errorf("GetPortfolioBalance() error = %v, want %v", err, ErrPortfolioNotFound)
+	}
+}
+
+func TestPortfolioHandler_GetPortfolioMonthlyReport(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2024, month, d, 12, 0, 0, 0, time.UTC)
+	}
+	events := NewMockEventStore([]*Event{
+		{ID: "1", PropertyID: "elm", EventAmount: mustMoney("1000"), PostEventBalance: mustMoney("1000"), Currency: "USD", Date: day(time.February, 20), Sequence: 1},
+		{ID: "2", PropertyID: "baker", EventAmount: mustMoney("400"), PostEventBalance: mustMoney("400"), Currency: "USD", Date: day(time.February, 25), Sequence: 1},
+		{ID: "3", PropertyID: "elm", EventAmount: mustMoney("-100"), PostEventBalance: mustMoney("900"), Currency: "USD", Date: day(time.March, 15), Sequence: 2},
+		{ID: "4", PropertyID: "baker", EventAmount: mustMoney("50"), PostEventBalance: mustMoney("450"), Currency: "USD", Date: day(time.March, 5), Sequence: 2},
+		{ID: "5", PropertyID: "baker", EventAmount: mustMoney("-20"), PostEventBalance: mustMoney("430"), Currency: "USD", Date: day(time.March, 25), Sequence: 3},
+		{ID: "6", PropertyID: "other", EventAmount: mustMoney("70"), PostEventBalance: mustMoney("70"), Currency: "USD", Date: day(time.March, 10), Sequence: 1},
+	}, false)
+	p := NewPortfolioHandler(NewHandler(events, nil, nil, "USD"), NewMockPortfolioStore(&Portfolio{ID: "rentals", Name: "Rentals", PropertyIDs: []string{"elm", "baker"}}))
+
+	got, err := p.GetPortfolioMonthlyReport(context.TODO(), "rentals", time.March, 2024, false, 0, 0)
+	if err != nil {
+		t.Fatalf("GetPortfolioMonthlyReport() unexpected error = %v", err)
+	}
+
+	wantIDs := []string{"4", "3", "5"}
+	if len(got.Events) != len(wantIDs) {
+		t.Fatalf("GetPortfolioMonthlyReport() got %d events, want %d", len(got.Events), len(wantIDs))
+	}
+	for i, id := range wantIDs {
+		if got.Events[i].ID != id {
+			t.Errorf("GetPortfolioMonthlyReport() event %d got = %v, want %v", i, got.Events[i].ID, id)
+		}
+	}
+	if got.StartingBalance.Total != mustMoney("1400") {
+		t.Errorf("GetPortfolioMonthlyReport() starting balance got = %v, want %v", got.StartingBalance.Total, "1400.00")
+	}
+
+	wantSubtotals := map[string]struct{ start, net Money }{
+		"elm":   {start: mustMoney("1000"), net: mustMoney("-100")},
+		"baker": {start: mustMoney("400"), net: mustMoney("30")},
+	}
+	if len(got.Subtotals) != len(wantSubtotals) {
+		t.Fatalf("GetPortfolioMonthlyReport() got %d subtotals, want %d", len(got.Subtotals), len(wantSubtotals))
+	}
+	for _, subtotal := range got.Subtotals {
+		want := wantSubtotals[subtotal.PropertyID]
+		if subtotal.StartingBalance.Total != want.start || subtotal.NetTotal != want.net {
+			t.Errorf("GetPortfolioMonthlyReport() subtotal of %s got = %v, %v, want %v, %v",
+				subtotal.PropertyID, subtotal.StartingBalance.Total, subtotal.NetTotal, want.start, want.net)
+		}
+	}
+}
+
+func TestPortfolioHandler_GetPortfolioMonthlyReport_ExcludeTransfers(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+	events := NewMockEventStore([]*Event{
+		{ID: "5", PropertyID: "baker", EventAmount: mustMoney("-20"), PostEventBalance: mustMoney("230"), Currency: "USD", Date: day(25), Sequence: 3},
+		{ID: "3", PropertyID: "elm", EventAmount: mustMoney("-300"), PostEventBalance: mustMoney("700"), Currency: "USD", Date: day(15), Category: Transfer, TransferID: "t", Sequence: 2},
+		{ID: "1", PropertyID: "elm", EventAmount: mustMoney("1000"), PostEventBalance: mustMoney("1000"), Currency: "USD", Date: day(1), Sequence: 1},
+		{ID: "4", PropertyID: "baker", EventAmount: mustMoney("300"), PostEventBalance: mustMoney("250"), Currency: "USD", Date: day(15).Add(time.Hour), Category: Transfer, TransferID: "t", Sequence: 2},
+		{ID: "2", PropertyID: "baker", EventAmount: mustMoney("-50"), PostEventBalance: mustMoney("-50"), Currency: "USD", Date: day(5), Sequence: 1},
+	}, false)
+	p := NewPortfolioHandler(NewHandler(events, nil, nil, "USD"), NewMockPortfolioStore(&Portfolio{ID: "rentals", Name: "Rentals", PropertyIDs: []string{"elm", "baker"}}))
+
+	tests := []struct {
+		name             string
+		excludeTransfers bool
+		wantIDs          []string
+		wantNets         map[string]Money
+	}{
+		{
+			name:     "with transfers",
+			wantIDs:  []string{"1", "2", "3", "4", "5"},
+			wantNets: map[string]Money{"elm": mustMoney("700"), "baker": mustMoney("230")},
+		},
+		{
+			name:             "without transfers",
+			excludeTransfers: true,
+			wantIDs:          []string{"1", "2", "5"},
+			wantNets:         map[string]Money{"elm": mustMoney("1000"), "baker": mustMoney("-70")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the pages, read two events at a time, follow one another in date order
+			var gotIDs []string
+			for offset := 0; offset < len(tt.wantIDs); offset += 2 {
+				got, err := p.GetPortfolioMonthlyReport(context.TODO(), "rentals", time.March, 2024, tt.excludeTransfers, offset, 2)
+				if err != nil {
+					t.Fatalf("GetPortfolioMonthlyReport() unexpected error = %v", err)
+				}
+				for _, e := range got.Events {
+					gotIDs = append(gotIDs, e.ID)
+				}
+				for _, subtotal := range got.Subtotals {
+					if subtotal.NetTotal != tt.wantNets[subtotal.PropertyID] {
+						t.Errorf("GetPortfolioMonthlyReport() net of %s got = %v, want %v", subtotal.PropertyID, subtotal.NetTotal, tt.wantNets[subtotal.PropertyID])
+					}
+				}
+			}
+			if !slices.Equal(gotIDs, tt.wantIDs) {
+				t.Errorf("GetPortfolioMonthlyReport() got events %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,561 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrReconciliationNotFound is returned when a reconciliation ID does not match any
+	// reconciliation of the property.
+	ErrReconciliationNotFound = errors.New("reconciliation not found")
+	// ErrStatementLineNotFound is returned when a row does not match any line of the statement of
+	// a reconciliation.
+	ErrStatementLineNotFound = errors.New("statement line not found")
+	// ErrReconciled is returned when changing a reconciliation that was already completed.
+	ErrReconciled = errors.New("reconciliation already completed")
+	// ErrEventMatched is returned when matching a statement line to an event already matched to
+	// another line, of this reconciliation or of another one of the property.
+	ErrEventMatched = errors.New("event already matched to a statement line")
+	// ErrNotMatchable is returned when matching a statement line to an event of another amount
+	// or currency, or to a reversed event, or when confirming the suggestion of a line without one.
+	ErrNotMatchable = errors.New("event does not match the statement line")
+	// ErrBalanceMismatch is matched by the BalanceMismatchError of a reconciliation whose closing
+	// balance does not agree with the ledger.
+	ErrBalanceMismatch = errors.New("closing balance does not match the ledger")
+)
+
+// MaxDateToleranceDays bounds how many days apart a statement line and an event can be dated
+// and still be matched automatically.
+const MaxDateToleranceDays = 31
+
+type ReconciliationStatus string
+
+const (
+	ReconciliationOpen       ReconciliationStatus = "open"
+	ReconciliationReconciled ReconciliationStatus = "reconciled"
+)
+
+// MatchStatus is how a statement line was matched to an event.
+type MatchStatus string
+
+const (
+	Unmatched MatchStatus = ""
+	// MatchSuggested is a match found automatically, yet to be confirmed.
+	MatchSuggested MatchStatus = "suggested"
+	// MatchConfirmed is a match made or confirmed by a user.
+	MatchConfirmed MatchStatus = "confirmed"
+)
+
+// StatementLine is a transaction of a bank statement, and the event it was matched to.
+type StatementLine struct {
+	// Row numbers the lines of the statement from 1.
+	Row         int         `json:"row" bson:"row"`
+	Date        time.Time   `json:"date" bson:"date"`
+	Amount      Money       `json:"amount" bson:"amount"`
+	Description string      `json:"description,omitempty" bson:"description,omitempty"`
+	Reference   string      `json:"reference,omitempty" bson:"reference,omitempty"`
+	EventID     string      `json:"event_id,omitempty" bson:"event_id,omitempty"`
+	Match       MatchStatus `json:"match,omitempty" bson:"match,omitempty"`
+}
+
+// Reconciliation compares a bank statement of a property over the period [From, To) with the
+// events of its ledger in the currency of the statement.
+type Reconciliation struct {
+	// ID is assigned by the ReconciliationStore when the reconciliation is created.
+	ID         string    `json:"id" bson:"_id"`
+	PropertyID string    `json:"property_id" bson:"property_id"`
+	Currency   string    `json:"currency" bson:"currency"`
+	From       time.Time `json:"from" bson:"from"`
+	To         time.Time `json:"to" bson:"to"`
+	// ClosingBalance is the balance of the statement at the end of the period.
+	ClosingBalance Money `json:"closing_balance" bson:"closing_balance"`
+	// DateToleranceDays is how many days in the time zone of the property a line and an event
+	// can be dated apart and still be matched automatically.
+	DateToleranceDays int                  `json:"date_tolerance_days" bson:"date_tolerance_days"`
+	Lines             []*StatementLine     `json:"lines" bson:"lines"`
+	Status            ReconciliationStatus `json:"status" bson:"status"`
+	CreatedAt         time.Time            `json:"created_at" bson:"created_at"`
+	ReconciledAt      time.Time            `json:"reconciled_at,omitempty" bson:"reconciled_at,omitempty"`
+	// Revision is incremented by every update, which only applies to the revision it was read at.
+	Revision int `json:"revision" bson:"revision"`
+}
+
+type ReconciliationStore interface {
+	// CreateReconciliation saves a new reconciliation, assigning it an ID. It returns
+	// ErrEventMatched, saving nothing, if an event matched to one of its lines is matched to
+	// another line of the property, so that concurrent reconciliations cannot claim an event twice.
+	CreateReconciliation(ctx context.Context, reconciliation *Reconciliation) error
+	// UpdateReconciliation replaces the reconciliation if it is still at its Revision, and
+	// increments the Revision of both. It returns ErrConflict if it was updated in the meantime,
+	// and ErrEventMatched as CreateReconciliation does.
+	UpdateReconciliation(ctx context.Context, reconciliation *Reconciliation) error
+	GetReconciliation(ctx context.Context, propertyID string, reconciliationID string) (*Reconciliation, bool, error)
+	// GetReconciliations returns the reconciliations of the property by the start of their period.
+	GetReconciliations(ctx context.Context, propertyID string) ([]*Reconciliation, error)
+	// DeleteReconciliation reports whether the reconciliation existed.
+	DeleteReconciliation(ctx context.Context, propertyID string, reconciliationID string) (bool, error)
+}
+
+// BalanceMismatchError is returned by CompleteReconciliation when the closing balance of the
+// statement differs from the balance of the ledger at the end of the period.
+type BalanceMismatchError struct {
+	Currency       string
+	ClosingBalance Money
+	LedgerBalance  Money
+}
+
+func (e *BalanceMismatchError) Error() string {
+	return fmt.Sprintf("closing %s balance %v does not match the ledger balance %v", e.Currency, e.ClosingBalance, e.LedgerBalance)
+}
+
+func (e *BalanceMismatchError) Is(target error) bool {
+	return target == ErrBalanceMismatch
+}
+
+// ReconciliationReview is a reconciliation along with what is left to reconcile.
+type ReconciliationReview struct {
+	Reconciliation *Reconciliation
+	// UnmatchedLines are the statement lines without an event, or matched to an event reversed
+	// since, and UnmatchedEvents the events of the period in the currency of the reconciliation
+	// without a statement line.
+	UnmatchedLines  []*StatementLine
+	UnmatchedEvents []*Event
+	// LedgerBalance is the balance of the ledger at the end of the period, and Difference the
+	// closing balance of the statement less it.
+	LedgerBalance Money
+	Difference    Money
+}
+
+// Reconciliations manages the reconciliation of bank statements against the ledgers of properties.
+type Reconciliations struct {
+	handler *Handler
+	store   ReconciliationStore
+	clock   Clock
+}
+
+func NewReconciliations(handler *Handler, store ReconciliationStore, clock Clock) *Reconciliations {
+	return &Reconciliations{
+		handler: handler,
+		store:   store,
+		clock:   clock,
+	}
+}
+
+// CreateReconciliation saves a new reconciliation of a registered property, in the property
+// currency if it has none, and matches its lines to the events of the ledger where it can.
+// The saved reconciliation, including its ID, is written back to reconciliation.
+func (r *Reconciliations) CreateReconciliation(ctx context.Context, reconciliation *Reconciliation) (*ReconciliationReview, error) {
+	property, err := r.handler.getRegisteredProperty(ctx, reconciliation.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	if reconciliation.Currency == "" && property != nil {
+		reconciliation.Currency = property.Currency
+	}
+	if err := r.validateReconciliation(reconciliation); err != nil {
+		return nil, err
+	}
+
+	reconciliation.Status = ReconciliationOpen
+	reconciliation.CreatedAt = r.clock.Now()
+	reconciliation.ReconciledAt = time.Time{}
+	for i, line := range reconciliation.Lines {
+		line.Row = i + 1
+		line.EventID, line.Match = "", Unmatched
+	}
+	if err := r.autoMatch(ctx, reconciliation); err != nil {
+		return nil, err
+	}
+
+	if err := r.store.CreateReconciliation(ctx, reconciliation); err != nil {
+		if errors.Is(err, ErrEventMatched) {
+			return nil, fmt.Errorf("create reconciliation: %w", err)
+		}
+		return nil, fmt.Errorf("create reconciliation: %v", err)
+	}
+	return r.review(ctx, reconciliation)
+}
+
+func (r *Reconciliations) GetReconciliation(ctx context.Context, PropertyID string, reconciliationID string) (*ReconciliationReview, error) {
+	reconciliation, err := r.getReconciliation(ctx, PropertyID, reconciliationID)
+	if err != nil {
+		return nil, err
+	}
+	return r.review(ctx, reconciliation)
+}
+
+func (r *Reconciliations) GetReconciliations(ctx context.Context, PropertyID string) ([]*Reconciliation, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	}
+
+	reconciliations, err := r.store.GetReconciliations(ctx, PropertyID)
+	if err != nil {
+		return nil, fmt.Errorf("get reconciliations: %v", err)
+	}
+	return reconciliations, nil
+}
+
+// DeleteReconciliation deletes a reconciliation, completed or not, releasing the events matched
+// to its lines.
+func (r *Reconciliations) DeleteReconciliation(ctx context.Context, PropertyID string, reconciliationID string) error {
+	if PropertyID == "" {
+		return fmt.Errorf("empty property ID")
+	} else if reconciliationID == "" {
+		return fmt.Errorf("empty reconciliation ID")
+	}
+
+	exists, err := r.store.DeleteReconciliation(ctx, PropertyID, reconciliationID)
+	if err != nil {
+		return fmt.Errorf("delete reconciliation: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("reconciliation %s: %w", reconciliationID, ErrReconciliationNotFound)
+	}
+	return nil
+}
+
+// AutoMatch matches the unmatched lines of an open reconciliation to the events of the ledger,
+// such as events saved since it was created, or since the events of their matches were reversed.
+func (r *Reconciliations) AutoMatch(ctx context.Context, PropertyID string, reconciliationID string) (*ReconciliationReview, error) {
+	return r.update(ctx, PropertyID, reconciliationID, func(reconciliation *Reconciliation) error {
+		return r.autoMatch(ctx, reconciliation)
+	})
+}
+
+// MatchLine confirms the match of a line of an open reconciliation: to the event with eventID,
+// which must have the amount and currency of the line, or to its suggested event if eventID is
+// empty. The event must be dated within the period, or within the date tolerance of the line as
+// the suggestions are.
+func (r *Reconciliations) MatchLine(ctx context.Context, PropertyID string, reconciliationID string, row int, eventID string) (*ReconciliationReview, error) {
+	return r.update(ctx, PropertyID, reconciliationID, func(reconciliation *Reconciliation) error {
+		line, err := reconciliation.line(row)
+		if err != nil {
+			return err
+		}
+		if eventID == "" {
+			if line.Match != MatchSuggested {
+				return fmt.Errorf("line %d has no suggested event: %w", row, ErrNotMatchable)
+			}
+			line.Match = MatchConfirmed
+			return nil
+		}
+
+		event, err := r.handler.getEvent(ctx, PropertyID, eventID)
+		if err != nil {
+			return err
+		}
+		if event.Currency != reconciliation.Currency || event.EventAmount != line.Amount || event.Reversed || event.ReversalOf != "" {
+			return fmt.Errorf("line %d and event %s: %w", row, eventID, ErrNotMatchable)
+		}
+		if event.Date.Before(reconciliation.From) || !event.Date.Before(reconciliation.To) {
+			calendar, err := r.handler.GetCalendar(ctx, PropertyID, "")
+			if err != nil {
+				return err
+			}
+			if calendar.daysBetween(line.Date, event.Date) > reconciliation.DateToleranceDays {
+				return fmt.Errorf("event %s is dated outside the period: %w", eventID, ErrNotMatchable)
+			}
+		}
+		matched, err := r.matchedEvents(ctx, reconciliation)
+		if err != nil {
+			return err
+		}
+		if matched[eventID] && line.EventID != eventID {
+			return fmt.Errorf("event %s: %w", eventID, ErrEventMatched)
+		}
+
+		line.EventID, line.Match = eventID, MatchConfirmed
+		return nil
+	})
+}
+
+// UnmatchLine removes the match of a line of an open reconciliation, suggested or confirmed.
+func (r *Reconciliations) UnmatchLine(ctx context.Context, PropertyID string, reconciliationID string, row int) (*ReconciliationReview, error) {
+	return r.update(ctx, PropertyID, reconciliationID, func(reconciliation *Reconciliation) error {
+		line, err := reconciliation.line(row)
+		if err != nil {
+			return err
+		}
+		line.EventID, line.Match = "", Unmatched
+		return nil
+	})
+}
+
+// CompleteReconciliation marks the period of an open reconciliation as reconciled, confirming its
+// suggested matches. It fails with a BalanceMismatchError unless the closing balance of the
+// statement agrees with the balance of the ledger at the end of the period.
+// The ledger of a reconciled period is not locked, so that late corrections can still be booked
+// at their date: the review of the reconciliation then shows the events saved since as unmatched,
+// the lines whose events were reversed as unmatched too, and the change to the ledger balance as
+// a difference.
+func (r *Reconciliations) CompleteReconciliation(ctx context.Context, PropertyID string, reconciliationID string) (*ReconciliationReview, error) {
+	return r.update(ctx, PropertyID, reconciliationID, func(reconciliation *Reconciliation) error {
+		balance, err := r.ledgerBalance(ctx, reconciliation)
+		if err != nil {
+			return err
+		}
+		if balance != reconciliation.ClosingBalance {
+			return &BalanceMismatchError{
+				Currency:       reconciliation.Currency,
+				ClosingBalance: reconciliation.ClosingBalance,
+				LedgerBalance:  balance,
+			}
+		}
+
+		for _, line := range reconciliation.Lines {
+			if line.Match == MatchSuggested {
+				line.Match = MatchConfirmed
+			}
+		}
+		reconciliation.Status = ReconciliationReconciled
+		reconciliation.ReconciledAt = r.clock.Now()
+		return nil
+	})
+}
+
+func (r *Reconciliations) getReconciliation(ctx context.Context, PropertyID string, reconciliationID string) (*Reconciliation, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	} else if reconciliationID == "" {
+		return nil, fmt.Errorf("empty reconciliation ID")
+	}
+
+	reconciliation, exists, err := r.store.GetReconciliation(ctx, PropertyID, reconciliationID)
+	if err != nil {
+		return nil, fmt.Errorf("get reconciliation: %v", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("reconciliation %s: %w", reconciliationID, ErrReconciliationNotFound)
+	}
+	return reconciliation, nil
+}
+
+// update applies fn to an open reconciliation and saves it, retrying on a concurrent update.
+// The lines matched to events reversed since are unmatched first, releasing their rows.
+func (r *Reconciliations) update(ctx context.Context, PropertyID string, reconciliationID string, fn func(*Reconciliation) error) (*ReconciliationReview, error) {
+	var reconciliation *Reconciliation
+	err := retryOnConflict(ctx, func() error {
+		var err error
+		if reconciliation, err = r.getReconciliation(ctx, PropertyID, reconciliationID); err != nil {
+			return err
+		}
+		if reconciliation.Status == ReconciliationReconciled {
+			return fmt.Errorf("reconciliation %s: %w", reconciliationID, ErrReconciled)
+		}
+		stale, err := r.staleLines(ctx, reconciliation)
+		if err != nil {
+			return err
+		}
+		for _, line := range stale {
+			line.EventID, line.Match = "", Unmatched
+		}
+		if err := fn(reconciliation); err != nil {
+			return err
+		}
+		return r.store.UpdateReconciliation(ctx, reconciliation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.review(ctx, reconciliation)
+}
+
+// autoMatch suggests an event for each unmatched line of reconciliation: an event of the same
+// amount and currency, dated within the date tolerance and not matched to any other line. Of
+// several, the one dated closest to the line is suggested, and the earliest of those.
+func (r *Reconciliations) autoMatch(ctx context.Context, reconciliation *Reconciliation) error {
+	calendar, err := r.handler.GetCalendar(ctx, reconciliation.PropertyID, "")
+	if err != nil {
+		return err
+	}
+	matched, err := r.matchedEvents(ctx, reconciliation)
+	if err != nil {
+		return err
+	}
+	tolerance := time.Duration(reconciliation.DateToleranceDays+1) * 24 * time.Hour
+	events, err := r.handler.store.GetEventsForFilter(ctx, &EventFilter{
+		PropertyID:      reconciliation.PropertyID,
+		Currency:        reconciliation.Currency,
+		AfterTime:       reconciliation.From.Add(-tolerance),
+		EndTime:         reconciliation.To.Add(tolerance),
+		ExcludeReversed: true,
+	}, Ascending, 0, 0)
+	if err != nil {
+		return fmt.Errorf("get events for filter: %v", err)
+	}
+	sortByDateAsc(events)
+
+	lines := slices.Clone(reconciliation.Lines)
+	slices.SortStableFunc(lines, func(a, b *StatementLine) int {
+		return a.Date.Compare(b.Date)
+	})
+	for _, line := range lines {
+		if line.Match != Unmatched {
+			continue
+		}
+		var best *Event
+		bestDays := 0
+		for _, event := range events {
+			if matched[event.ID] || event.EventAmount != line.Amount {
+				continue
+			}
+			days := calendar.daysBetween(line.Date, event.Date)
+			if days <= reconciliation.DateToleranceDays && (best == nil || days < bestDays) {
+				best, bestDays = event, days
+			}
+		}
+		if best != nil {
+			line.EventID, line.Match = best.ID, MatchSuggested
+			matched[best.ID] = true
+		}
+	}
+	return nil
+}
+
+// matchedEvents returns the IDs of the events matched to the lines of every reconciliation of the
+// property, including reconciliation.
+func (r *Reconciliations) matchedEvents(ctx context.Context, reconciliation *Reconciliation) (map[string]bool, error) {
+	reconciliations, err := r.store.GetReconciliations(ctx, reconciliation.PropertyID)
+	if err != nil {
+		return nil, fmt.Errorf("get reconciliations: %v", err)
+	}
+
+	matched := make(map[string]bool)
+	for _, other := range reconciliations {
+		if other.ID != reconciliation.ID {
+			other.addMatched(matched)
+		}
+	}
+	reconciliation.addMatched(matched)
+	return matched, nil
+}
+
+// review lists what is left to reconcile of reconciliation.
+func (r *Reconciliations) review(ctx context.Context, reconciliation *Reconciliation) (*ReconciliationReview, error) {
+	review := &ReconciliationReview{Reconciliation: reconciliation}
+	stale, err := r.staleLines(ctx, reconciliation)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range reconciliation.Lines {
+		if line.EventID == "" || slices.Contains(stale, line) {
+			review.UnmatchedLines = append(review.UnmatchedLines, line)
+		}
+	}
+
+	matched, err := r.matchedEvents(ctx, reconciliation)
+	if err != nil {
+		return nil, err
+	}
+	events, err := r.handler.GetPropertyEvents(ctx, &EventFilter{
+		PropertyID:      reconciliation.PropertyID,
+		Currency:        reconciliation.Currency,
+		AfterTime:       reconciliation.From,
+		EndTime:         reconciliation.To,
+		ExcludeReversed: true,
+	}, Ascending, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		if !matched[event.ID] {
+			review.UnmatchedEvents = append(review.UnmatchedEvents, event)
+		}
+	}
+
+	if review.LedgerBalance, err = r.ledgerBalance(ctx, reconciliation); err != nil {
+		return nil, err
+	}
+	if review.Difference, err = reconciliation.ClosingBalance.Sub(review.LedgerBalance); err != nil {
+		return nil, fmt.Errorf("difference: %w", err)
+	}
+	return review, nil
+}
+
+// staleLines returns the lines of reconciliation matched to events that have since been reversed,
+// or are gone.
+func (r *Reconciliations) staleLines(ctx context.Context, reconciliation *Reconciliation) ([]*StatementLine, error) {
+	matched := make(map[string]bool)
+	reconciliation.addMatched(matched)
+	if len(matched) == 0 {
+		return nil, nil
+	}
+	events, err := r.handler.store.GetEventsForFilter(ctx, &EventFilter{
+		IDs:             slices.Collect(maps.Keys(matched)),
+		PropertyID:      reconciliation.PropertyID,
+		ExcludeReversed: true,
+	}, Ascending, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get events for filter: %v", err)
+	}
+	for _, event := range events {
+		delete(matched, event.ID)
+	}
+
+	var stale []*StatementLine
+	for _, line := range reconciliation.Lines {
+		if matched[line.EventID] {
+			stale = append(stale, line)
+		}
+	}
+	return stale, nil
+}
+
+// ledgerBalance returns the balance of the ledger in the currency of reconciliation after every
+// event of its period.
+func (r *Reconciliations) ledgerBalance(ctx context.Context, reconciliation *Reconciliation) (Money, error) {
+	balance, err := r.handler.getBalanceBefore(ctx, reconciliation.PropertyID, reconciliation.To)
+	if err != nil {
+		return Money{}, fmt.Errorf("get balance before: %v", err)
+	}
+	return balance.ByCurrency[reconciliation.Currency], nil
+}
+
+// validateReconciliation checks the statement of reconciliation, normalizing its currency.
+func (r *Reconciliations) validateReconciliation(reconciliation *Reconciliation) error {
+	if reconciliation.PropertyID == "" {
+		return fmt.Errorf("%w: empty property ID", ErrInvalid)
+	} else if !reconciliation.From.Before(reconciliation.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalid)
+	} else if reconciliation.DateToleranceDays < 0 || reconciliation.DateToleranceDays > MaxDateToleranceDays {
+		return fmt.Errorf("%w: date tolerance must be between 0 and %d days", ErrInvalid, MaxDateToleranceDays)
+	}
+	for i, line := range reconciliation.Lines {
+		if line.Amount.IsZero() {
+			return fmt.Errorf("%w: line %d: zero amount", ErrInvalid, i+1)
+		} else if line.Date.Before(reconciliation.From) || !line.Date.Before(reconciliation.To) {
+			return fmt.Errorf("%w: line %d: dated outside the period", ErrInvalid, i+1)
+		}
+	}
+
+	if reconciliation.Currency == "" {
+		reconciliation.Currency = r.handler.baseCurrency
+	} else {
+		var err error
+		if reconciliation.Currency, err = NormalizeCurrency(reconciliation.Currency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reconciliation) line(row int) (*StatementLine, error) {
+	if row < 1 || row > len(r.Lines) {
+		return nil, fmt.Errorf("line %d: %w", row, ErrStatementLineNotFound)
+	}
+	return r.Lines[row-1], nil
+}
+
+func (r *Reconciliation) addMatched(matched map[string]bool) {
+	for _, line := range r.Lines {
+		if line.EventID != "" {
+			matched[line.EventID] = true
+		}
+	}
+}
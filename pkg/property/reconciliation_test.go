@@ -0,0 +1,380 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/samber/lo"
+)
+
+type MockReconciliationStore struct {
+	reconciliations map[string]*Reconciliation
+}
+
+func NewMockReconciliationStore() *MockReconciliationStore {
+	return &MockReconciliationStore{reconciliations: map[string]*Reconciliation{}}
+}
+
+// copyReconciliation copies r deeply enough for the store to keep its own lines.
+func copyReconciliation(r *Reconciliation) *Reconciliation {
+	c := *r
+	c.Lines = lo.Map(r.Lines, func(l *StatementLine, _ int) *StatementLine {
+		lc := *l
+		return &lc
+	})
+	return &c
+}
+
+// checkMatches fails with ErrEventMatched, as the unique index of the matches does, if an event
+// matched to a line of reconciliation is matched to another line of the property.
+func (m *MockReconciliationStore) checkMatches(reconciliation *Reconciliation) error {
+	matched := map[string]bool{}
+	for _, r := range m.reconciliations {
+		if r.PropertyID == reconciliation.PropertyID && r.ID != reconciliation.ID {
+			r.addMatched(matched)
+		}
+	}
+	for _, line := range reconciliation.Lines {
+		if line.EventID == "" {
+			continue
+		}
+		if matched[line.EventID] {
+			return ErrEventMatched
+		}
+		matched[line.EventID] = true
+	}
+	return nil
+}
+
+func (m *MockReconciliationStore) CreateReconciliation(ctx context.Context, reconciliation *Reconciliation) error {
+	if err := m.checkMatches(reconciliation); err != nil {
+		return err
+	}
+	reconciliation.ID = gofakeit.UUID()
+	m.reconciliations[reconciliation.ID] = copyReconciliation(reconciliation)
+	return nil
+}
+
+func (m *MockReconciliationStore) UpdateReconciliation(ctx context.Context, reconciliation *Reconciliation) error {
+	existing, ok := m.reconciliations[reconciliation.ID]
+	if !ok || existing.PropertyID != reconciliation.PropertyID || existing.Revision != reconciliation.Revision {
+		return ErrConflict
+	}
+	if err := m.checkMatches(reconciliation); err != nil {
+		return err
+	}
+	reconciliation.Revision++
+	m.reconciliations[reconciliation.ID] = copyReconciliation(reconciliation)
+	return nil
+}
+
+func (m *MockReconciliationStore) GetReconciliation(ctx context.Context, propertyID string, reconciliationID string) (*Reconciliation, bool, error) {
+	r, ok := m.reconciliations[reconciliationID]
+	if !ok || r.PropertyID != propertyID {
+		return nil, false, nil
+	}
+	return copyReconciliation(r), true, nil
+}
+
+func (m *MockReconciliationStore) GetReconciliations(ctx context.Context, propertyID string) ([]*Reconciliation, error) {
+	reconciliations := lo.FilterMap(lo.Values(m.reconciliations), func(r *Reconciliation, _ int) (*Reconciliation, bool) {
+		return copyReconciliation(r), r.PropertyID == propertyID
+	})
+	slices.SortFunc(reconciliations, func(a, b *Reconciliation) int {
+		return a.From.Compare(b.From)
+	})
+	return reconciliations, nil
+}
+
+func (m *MockReconciliationStore) DeleteReconciliation(ctx context.Context, propertyID string, reconciliationID string) (bool, error) {
+	r, ok := m.reconciliations[reconciliationID]
+	if !ok || r.PropertyID != propertyID {
+		return false, nil
+	}
+	delete(m.reconciliations, reconciliationID)
+	return true, nil
+}
+
+// staleReconciliationStore misses the other reconciliations of the property, as a read racing
+// their updates would.
+type staleReconciliationStore struct {
+	*MockReconciliationStore
+}
+
+func (s staleReconciliationStore) GetReconciliations(ctx context.Context, propertyID string) ([]*Reconciliation, error) {
+	return nil, nil
+}
+
+func marchDay(day int) time.Time {
+	return time.Date(2024, time.March, day, 12, 0, 0, 0, time.UTC)
+}
+
+// seedReconciliations returns Reconciliations over a property with a ledger for March 2024, and
+// the IDs of its events by name.
+func seedReconciliations(t *testing.T) (*Reconciliations, map[string]string) {
+	t.Helper()
+	properties := NewMockPropertyStore(&Property{ID: "elm", Name: "Elm Street 13", Currency: "USD"})
+	h := NewHandler(NewMockEventStore(nil, false), properties, nil, "USD")
+	ids := map[string]string{}
+	for _, e := range []struct {
+		name   string
+		amount string
+		date   time.Time
+	}{
+		{"deposit", "-50", marchDay(-2)},
+		{"rent", "1000", marchDay(2)},
+		{"plumber", "-120.50", marchDay(5)},
+		{"second plumber", "-120.50", marchDay(5).Add(time.Hour)},
+		{"cash", "-80", marchDay(20)},
+	} {
+		event := &Event{PropertyID: "elm", EventAmount: mustMoney(e.amount), Date: e.date, Description: e.name}
+		if _, err := h.SaveEvent(context.TODO(), event); err != nil {
+			t.Fatalf("SaveEvent() unexpected error = %v", err)
+		}
+		ids[e.name] = event.ID
+	}
+	return NewReconciliations(h, NewMockReconciliationStore(), &MockClock{now: marchDay(31)}), ids
+}
+
+func marchStatement(tolerance int) *Reconciliation {
+	return &Reconciliation{
+		PropertyID:        "elm",
+		From:              time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		To:                time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		ClosingBalance:    mustMoney("-370"),
+		DateToleranceDays: tolerance,
+		Lines: []*StatementLine{
+			{Date: marchDay(3), Amount: mustMoney("1000")},
+			{Date: marchDay(6), Amount: mustMoney("-120.50")},
+			{Date: marchDay(7), Amount: mustMoney("-120.50")},
+			{Date: marchDay(1), Amount: mustMoney("-50")},
+			{Date: marchDay(15), Amount: mustMoney("-999")},
+		},
+	}
+}
+
+func TestReconciliations_Workflow(t *testing.T) {
+	r, ids := seedReconciliations(t)
+	ctx := context.TODO()
+
+	review, err := r.CreateReconciliation(ctx, marchStatement(3))
+	if err != nil {
+		t.Fatalf("CreateReconciliation() unexpected error = %v", err)
+	}
+	reconciliation := review.Reconciliation
+	if reconciliation.ID == "" || reconciliation.Currency != "USD" || reconciliation.Status != ReconciliationOpen {
+		t.Fatalf("CreateReconciliation() got = %+v, want an open USD reconciliation with an ID", reconciliation)
+	}
+	wantMatches := []string{ids["rent"], ids["plumber"], ids["second plumber"], ids["deposit"], ""}
+	for i, line := range reconciliation.Lines {
+		wantMatch := lo.Ternary(wantMatches[i] == "", Unmatched, MatchSuggested)
+		if line.Row != i+1 || line.EventID != wantMatches[i] || line.Match != wantMatch {
+			t.Errorf("CreateReconciliation() line %d got = %+v, want event %q", i+1, line, wantMatches[i])
+		}
+	}
+	if len(review.UnmatchedLines) != 1 || review.UnmatchedLines[0].Row != 5 {
+		t.Errorf("CreateReconciliation() unmatched lines got = %+v, want row 5", review.UnmatchedLines)
+	}
+	if len(review.UnmatchedEvents) != 1 || review.UnmatchedEvents[0].ID != ids["cash"] {
+		t.Errorf("CreateReconciliation() unmatched events got = %+v, want the cash event", review.UnmatchedEvents)
+	}
+	if review.LedgerBalance != mustMoney("629") || review.Difference != mustMoney("-999") {
+		t.Errorf("CreateReconciliation() ledger balance = %v, difference = %v, want 629 and -999", review.LedgerBalance, review.Difference)
+	}
+
+	_, err = r.CompleteReconciliation(ctx, "elm", reconciliation.ID)
+	var mismatch *BalanceMismatchError
+	if !errors.As(err, &mismatch) || !errors.Is(err, ErrBalanceMismatch) || mismatch.LedgerBalance != mustMoney("629") {
+		t.Fatalf("CompleteReconciliation() error = %v, want a BalanceMismatchError", err)
+	}
+
+	// the missing event is saved, and matched on the next pass
+	missing := &Event{PropertyID: "elm", EventAmount: mustMoney("-999"), Date: marchDay(16)}
+	if _, err := r.handler.SaveEvent(ctx, missing); err != nil {
+		t.Fatalf("SaveEvent() unexpected error = %v", err)
+	}
+	if review, err = r.AutoMatch(ctx, "elm", reconciliation.ID); err != nil {
+		t.Fatalf("AutoMatch() unexpected error = %v", err)
+	}
+	if line := review.Reconciliation.Lines[4]; line.EventID != missing.ID || line.Match != MatchSuggested {
+		t.Errorf("AutoMatch() line 5 got = %+v, want event %s", line, missing.ID)
+	}
+	if !review.Difference.IsZero() {
+		t.Errorf("AutoMatch() difference = %v, want 0", review.Difference)
+	}
+
+	if review, err = r.CompleteReconciliation(ctx, "elm", reconciliation.ID); err != nil {
+		t.Fatalf("CompleteReconciliation() unexpected error = %v", err)
+	}
+	if review.Reconciliation.Status != ReconciliationReconciled || !review.Reconciliation.ReconciledAt.Equal(marchDay(31)) {
+		t.Errorf("CompleteReconciliation() got = %+v, want reconciled", review.Reconciliation)
+	}
+	for _, line := range review.Reconciliation.Lines {
+		if line.Match != MatchConfirmed {
+			t.Errorf("CompleteReconciliation() line %d match = %q, want confirmed", line.Row, line.Match)
+		}
+	}
+
+	if _, err := r.UnmatchLine(ctx, "elm", reconciliation.ID, 1); !errors.Is(err, ErrReconciled) {
+		t.Errorf("UnmatchLine() error = %v, want %v", err, ErrReconciled)
+	}
+
+	// a later statement cannot claim the events of the reconciled one
+	again, err := r.CreateReconciliation(ctx, marchStatement(3))
+	if err != nil {
+		t.Fatalf("CreateReconciliation() unexpected error = %v", err)
+	}
+	if len(again.UnmatchedLines) != 5 || len(again.UnmatchedEvents) != 1 {
+		t.Errorf("CreateReconciliation() got %d unmatched lines and %d events, want 5 and 1",
+			len(again.UnmatchedLines), len(again.UnmatchedEvents))
+	}
+}
+
+func TestReconciliations_CreateReconciliation_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(r *Reconciliation)
+		wantErr error
+	}{
+		{name: "unknown property", modify: func(r *Reconciliation) { r.PropertyID = "nowhere" }, wantErr: ErrPropertyNotFound},
+		{name: "empty period", modify: func(r *Reconciliation) { r.To = r.From }, wantErr: ErrInvalid},
+		{name: "tolerance too wide", modify: func(r *Reconciliation) { r.DateToleranceDays = 32 }, wantErr: ErrInvalid},
+		{name: "line outside the period", modify: func(r *Reconciliation) { r.Lines[0].Date = marchDay(-1) }, wantErr: ErrInvalid},
+		{name: "zero line", modify: func(r *Reconciliation) { r.Lines[1].Amount = Money{} }, wantErr: ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := seedReconciliations(t)
+			reconciliation := marchStatement(3)
+			tt.modify(reconciliation)
+			_, err := r.CreateReconciliation(context.TODO(), reconciliation)
+			if err == nil || (!errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error()) {
+				t.Errorf("CreateReconciliation() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReconciliations_MatchLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		tolerance int
+		row       int
+		event     string
+		wantEvent string
+		wantErr   error
+	}{
+		{name: "confirm the suggestion", tolerance: 3, row: 1, wantEvent: "rent"},
+		{name: "no suggestion to confirm", tolerance: 0, row: 1, wantErr: ErrNotMatchable},
+		{name: "outside the tolerance", tolerance: 0, row: 1, event: "rent", wantEvent: "rent"},
+		{name: "another amount", tolerance: 0, row: 1, event: "cash", wantErr: ErrNotMatchable},
+		{name: "before the period", tolerance: 0, row: 4, event: "deposit", wantErr: ErrNotMatchable},
+		{name: "before the period within the tolerance", tolerance: 3, row: 4, event: "deposit", wantEvent: "deposit"},
+		{name: "matched to another line", tolerance: 3, row: 3, event: "plumber", wantErr: ErrEventMatched},
+		{name: "swap the matches of the same amount", tolerance: 3, row: 2, event: "plumber", wantEvent: "plumber"},
+		{name: "unknown event", tolerance: 3, row: 5, event: "nothing", wantErr: ErrEventNotFound},
+		{name: "unknown row", tolerance: 3, row: 9, event: "rent", wantErr: ErrStatementLineNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, ids := seedReconciliations(t)
+			ids["nothing"] = "nothing"
+			created, err := r.CreateReconciliation(context.TODO(), marchStatement(tt.tolerance))
+			if err != nil {
+				t.Fatalf("CreateReconciliation() unexpected error = %v", err)
+			}
+
+			review, err := r.MatchLine(context.TODO(), "elm", created.Reconciliation.ID, tt.row, ids[tt.event])
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("MatchLine() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MatchLine() unexpected error = %v", err)
+			}
+			line := review.Reconciliation.Lines[tt.row-1]
+			if line.EventID != ids[tt.wantEvent] || line.Match != MatchConfirmed {
+				t.Errorf("MatchLine() line got = %+v, want a confirmed match to %s", line, tt.wantEvent)
+			}
+			if review.Reconciliation.Revision != created.Reconciliation.Revision+1 {
+				t.Errorf("MatchLine() revision = %d, want %d", review.Reconciliation.Revision, created.Reconciliation.Revision+1)
+			}
+		})
+	}
+}
+
+func TestReconciliations_ConcurrentClaim(t *testing.T) {
+	r, ids := seedReconciliations(t)
+	ctx := context.TODO()
+	if _, err := r.CreateReconciliation(ctx, marchStatement(3)); err != nil {
+		t.Fatalf("CreateReconciliation() unexpected error = %v", err)
+	}
+
+	// the claims of the first reconciliation are missed by the reads of the second, yet the store
+	// still rejects them
+	stale := NewReconciliations(r.handler, staleReconciliationStore{r.store.(*MockReconciliationStore)}, r.clock)
+	if _, err := stale.CreateReconciliation(ctx, marchStatement(3)); !errors.Is(err, ErrEventMatched) {
+		t.Errorf("CreateReconciliation() error = %v, want %v", err, ErrEventMatched)
+	}
+	created, err := stale.CreateReconciliation(ctx, marchStatement(0))
+	if err != nil {
+		t.Fatalf("CreateReconciliation() unexpected error = %v", err)
+	}
+	if _, err := stale.MatchLine(ctx, "elm", created.Reconciliation.ID, 1, ids["rent"]); !errors.Is(err, ErrEventMatched) {
+		t.Errorf("MatchLine() error = %v, want %v", err, ErrEventMatched)
+	}
+	review, err := r.GetReconciliation(ctx, "elm", created.Reconciliation.ID)
+	if err != nil {
+		t.Fatalf("GetReconciliation() unexpected error = %v", err)
+	}
+	if line := review.Reconciliation.Lines[0]; line.EventID != "" {
+		t.Errorf("MatchLine() saved line 1 = %+v, want it unmatched", line)
+	}
+}
+
+func TestReconciliations_ReversedMatch(t *testing.T) {
+	r, ids := seedReconciliations(t)
+	ctx := context.TODO()
+	created, err := r.CreateReconciliation(ctx, marchStatement(3))
+	if err != nil {
+		t.Fatalf("CreateReconciliation() unexpected error = %v", err)
+	}
+	reconciliationID := created.Reconciliation.ID
+	if _, err := r.MatchLine(ctx, "elm", reconciliationID, 1, ""); err != nil {
+		t.Fatalf("MatchLine() unexpected error = %v", err)
+	}
+
+	// the rent is reversed and booked again
+	if _, err := r.handler.ReverseEvent(ctx, "elm", ids["rent"], "wrong account"); err != nil {
+		t.Fatalf("ReverseEvent() unexpected error = %v", err)
+	}
+	rebooked := &Event{PropertyID: "elm", EventAmount: mustMoney("1000"), Date: marchDay(3)}
+	if _, err := r.handler.SaveEvent(ctx, rebooked); err != nil {
+		t.Fatalf("SaveEvent() unexpected error = %v", err)
+	}
+
+	review, err := r.GetReconciliation(ctx, "elm", reconciliationID)
+	if err != nil {
+		t.Fatalf("GetReconciliation() unexpected error = %v", err)
+	}
+	if len(review.UnmatchedLines) != 2 || review.UnmatchedLines[0].Row != 1 {
+		t.Errorf("GetReconciliation() unmatched lines got = %+v, want rows 1 and 5", review.UnmatchedLines)
+	}
+
+	review, err = r.AutoMatch(ctx, "elm", reconciliationID)
+	if err != nil {
+		t.Fatalf("AutoMatch() unexpected error = %v", err)
+	}
+	if line := review.Reconciliation.Lines[0]; line.EventID != rebooked.ID || line.Match != MatchSuggested {
+		t.Errorf("AutoMatch() line 1 got = %+v, want event %s", line, rebooked.ID)
+	}
+	if len(review.UnmatchedLines) != 1 || review.UnmatchedLines[0].Row != 5 {
+		t.Errorf("AutoMatch() unmatched lines got = %+v, want row 5", review.UnmatchedLines)
+	}
+}
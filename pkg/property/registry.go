@@ -0,0 +1,184 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPropertyNotFound is returned when a property ID does not match any registered property.
+	ErrPropertyNotFound = errors.New("property not found")
+	// ErrPropertyExists is returned when registering a property under an ID already in use.
+	ErrPropertyExists = errors.New("property already exists")
+	// ErrPropertyHasEvents is returned when deleting a property whose ledger has events.
+	ErrPropertyHasEvents = errors.New("property has events")
+)
+
+// Property is a registered property, the owner of a ledger.
+type Property struct {
+	// ID is assigned by the PropertyStore when the property is created without one.
+	ID      string `json:"id" bson:"_id"`
+	Name    string `json:"name" bson:"name"`
+	Address string `json:"address,omitempty" bson:"address,omitempty"`
+	Owner   string `json:"owner,omitempty" bson:"owner,omitempty"`
+	// AcquisitionDate is when the property was acquired, if known.
+	AcquisitionDate time.Time `json:"acquisition_date,omitempty" bson:"acquisition_date,omitempty"`
+	// Currency is the ISO-4217 code events of the property are saved in when they have none.
+	Currency string `json:"currency" bson:"currency"`
+	// Timezone is the IANA name of the time zone of the property, such as "Europe/London".
+	Timezone string `json:"timezone" bson:"timezone"`
+	// FiscalYearStart is the month the fiscal years of the property start in.
+	FiscalYearStart time.Month `json:"fiscal_year_start" bson:"fiscal_year_start,omitempty"`
+	Tags            []string   `json:"tags,omitempty" bson:"tags,omitempty"`
+	// OverdraftPolicy is applied to the expenses that would take the balance of the property
+	// below MinimumBalance in its currency, or below zero in any other currency.
+	OverdraftPolicy OverdraftPolicy `json:"overdraft_policy" bson:"overdraft_policy,omitempty"`
+	// MinimumBalance may be negative, to allow an overdraft up to a limit.
+	MinimumBalance Money `json:"minimum_balance" bson:"minimum_balance,omitempty"`
+}
+
+type PropertyStore interface {
+	// CreateProperty saves a new property, assigning it an ID if it has none.
+	// It returns ErrPropertyExists if the ID is already in use.
+	CreateProperty(ctx context.Context, property *Property) error
+	// UpdateProperty replaces a property, and reports whether it exists.
+	UpdateProperty(ctx context.Context, property *Property) (bool, error)
+	GetProperty(ctx context.Context, propertyID string) (*Property, bool, error)
+	GetProperties(ctx context.Context, filter *PropertyFilter) ([]*Property, error)
+	// DeleteProperty reports whether the property existed.
+	DeleteProperty(ctx context.Context, propertyID string) (bool, error)
+}
+
+// CreateProperty registers a new property, under property.ID if it is set, which lets a ledger
+// saved before properties were registered be claimed. The created property is written back to property.
+func (h *Handler) CreateProperty(ctx context.Context, property *Property) error {
+	if err := h.validateProperty(property); err != nil {
+		return err
+	}
+
+	if err := h.properties.CreateProperty(ctx, property); err != nil {
+		if errors.Is(err, ErrPropertyExists) {
+			return fmt.Errorf("property %s: %w", property.ID, err)
+		}
+		return fmt.Errorf("create property: %v", err)
+	}
+	return nil
+}
+
+// UpdateProperty replaces the metadata of a property. A property without an overdraft policy keeps
+// the overdraft policy and minimum balance it has, so that a client unaware of them cannot lift
+// the limits of a trust account. The updated property is written back to property.
+func (h *Handler) UpdateProperty(ctx context.Context, property *Property) error {
+	if property.ID == "" {
+		return fmt.Errorf("empty property ID")
+	}
+	if property.OverdraftPolicy == "" {
+		existing, err := h.GetProperty(ctx, property.ID)
+		if err != nil {
+			return err
+		}
+		property.OverdraftPolicy = existing.OverdraftPolicy
+		property.MinimumBalance = existing.MinimumBalance
+	}
+	if err := h.validateProperty(property); err != nil {
+		return err
+	}
+
+	exists, err := h.properties.UpdateProperty(ctx, property)
+	if err != nil {
+		return fmt.Errorf("update property: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("property %s: %w", property.ID, ErrPropertyNotFound)
+	}
+	return nil
+}
+
+func (h *Handler) GetProperty(ctx context.Context, PropertyID string) (*Property, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	}
+
+	property, exists, err := h.properties.GetProperty(ctx, PropertyID)
+	if err != nil {
+		return nil, fmt.Errorf("get property: %v", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("property %s: %w", PropertyID, ErrPropertyNotFound)
+	}
+	return property, nil
+}
+
+// DeleteProperty unregisters a property. A property whose ledger has events cannot be deleted.
+func (h *Handler) DeleteProperty(ctx context.Context, PropertyID string) error {
+	if PropertyID == "" {
+		return fmt.Errorf("empty property ID")
+	}
+
+	_, hasEvents, err := h.store.GetLedgerHead(ctx, PropertyID)
+	if err != nil {
+		return fmt.Errorf("get ledger head: %v", err)
+	}
+	if hasEvents {
+		return fmt.Errorf("property %s: %w", PropertyID, ErrPropertyHasEvents)
+	}
+
+	exists, err := h.properties.DeleteProperty(ctx, PropertyID)
+	if err != nil {
+		return fmt.Errorf("delete property: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("property %s: %w", PropertyID, ErrPropertyNotFound)
+	}
+	return nil
+}
+
+// validateProperty checks the metadata of property, defaulting its currency to the base currency,
+// its timezone to UTC, its fiscal year to the calendar year and its overdraft policy to allow.
+func (h *Handler) validateProperty(property *Property) error {
+	property.Name = strings.TrimSpace(property.Name)
+	if property.Name == "" {
+		return fmt.Errorf("empty property name")
+	}
+
+	if property.Currency == "" {
+		property.Currency = h.baseCurrency
+	} else {
+		var err error
+		if property.Currency, err = NormalizeCurrency(property.Currency); err != nil {
+			return err
+		}
+	}
+
+	if property.Timezone == "" {
+		property.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(property.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", property.Timezone)
+	}
+
+	if property.FiscalYearStart == 0 {
+		property.FiscalYearStart = time.January
+	} else if property.FiscalYearStart < time.January || property.FiscalYearStart > time.December {
+		return fmt.Errorf("invalid fiscal year start month %d", property.FiscalYearStart)
+	}
+	if property.OverdraftPolicy == "" {
+		property.OverdraftPolicy = OverdraftAllow
+	} else if !property.OverdraftPolicy.IsValid() {
+		return fmt.Errorf("invalid overdraft policy %q", property.OverdraftPolicy)
+	}
+	property.Tags = normalizeTags(property.Tags)
+	return nil
+}
+
+// getRegisteredProperty returns the registered property with PropertyID, or ErrPropertyNotFound.
+// Without a property registry every property ID is accepted, and nil is returned.
+func (h *Handler) getRegisteredProperty(ctx context.Context, PropertyID string) (*Property, error) {
+	if h.properties == nil {
+		return nil, nil
+	}
+	return h.GetProperty(ctx, PropertyID)
+}
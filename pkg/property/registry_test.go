@@ -0,0 +1,218 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/samber/lo"
+	"testing"
+	"time"
+)
+
+type MockPropertyStore struct {
+	properties map[string]*Property
+}
+
+func NewMockPropertyStore(properties ...*Property) *MockPropertyStore {
+	m := &MockPropertyStore{properties: map[string]*Property{}}
+	for _, p := range properties {
+		m.properties[p.ID] = p
+	}
+	return m
+}
+
+func (m *MockPropertyStore) CreateProperty(ctx context.Context, property *Property) error {
+	if property.ID == "" {
+		property.ID = gofakeit.UUID()
+	}
+	if _, ok := m.properties[property.ID]; ok {
+		return ErrPropertyExists
+	}
+	c := *property
+	m.properties[property.ID] = &c
+	return nil
+}
+
+func (m *MockPropertyStore) UpdateProperty(ctx context.Context, property *Property) (bool, error) {
+	if _, ok := m.properties[property.ID]; !ok {
+		return false, nil
+	}
+	c := *property
+	m.properties[property.ID] = &c
+	return true, nil
+}
+
+func (m *MockPropertyStore) GetProperty(ctx context.Context, propertyID string) (*Property, bool, error) {
+	p, ok := m.properties[propertyID]
+	if !ok {
+		return nil, false, nil
+	}
+	c := *p
+	return &c, true, nil
+}
+
+func (m *MockPropertyStore) GetProperties(ctx context.Context, filter *PropertyFilter) ([]*Property, error) {
+	properties := lo.PickBy(m.properties, func(_ string, p *Property) bool {
+		return (filter.Owner == "" || p.Owner == filter.Owner) && lo.Every(p.Tags, filter.Tags)
+	})
+	return lo.MapToSlice(properties, func(_ string, p *Property) *Property {
+		c := *p
+		return &c
+	}), nil
+}
+
+func (m *MockPropertyStore) DeleteProperty(ctx context.Context, propertyID string) (bool, error) {
+	_, ok := m.properties[propertyID]
+	delete(m.properties, propertyID)
+	return ok, nil
+}
+
+func TestHandler_CreateProperty(t *testing.T) {
+	tests := []struct {
+		name         string
+		property     *Property
+		wantCurrency string
+		wantTimezone string
+		wantErr      error
+	}{
+		{
+			name:         "defaults",
+			property:     &Property{Name: "Elm Street 13"},
+			wantCurrency: "USD",
+			wantTimezone: "UTC",
+		},
+		{
+			name:         "own currency and timezone",
+			property:     &Property{Name: "Baker Street 221b", Currency: "gbp", Timezone: "Europe/London"},
+			wantCurrency: "GBP",
+			wantTimezone: "Europe/London",
+		},
+		{
+			name:     "existing ID",
+			property: &Property{ID: "taken", Name: "Elm Street 13"},
+			wantErr:  ErrPropertyExists,
+		},
+		{
+			name:     "no name",
+			property: &Property{Name: " "},
+			wantErr:  errors.New("empty property name"),
+		},
+		{
+			name:     "invalid timezone",
+			property: &Property{Name: "Elm Street 13", Timezone: "Mars/Olympus"},
+			wantErr:  errors.New("invalid timezone"),
+		},
+		{
+			name:     "invalid overdraft policy",
+			property: &Property{Name: "Elm Street 13", OverdraftPolicy: "sometimes"},
+			wantErr:  errors.New("invalid overdraft policy"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(NewMockEventStore(nil, false), NewMockPropertyStore(&Property{ID: "taken"}), nil, "USD")
+			err := h.CreateProperty(context.TODO(), tt.property)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("CreateProperty() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(tt.wantErr, ErrPropertyExists) && !errors.Is(err, ErrPropertyExists) {
+				t.Errorf("CreateProperty() error = %v, want %v", err, ErrPropertyExists)
+			}
+			if err != nil {
+				return
+			}
+
+			got, err := h.GetProperty(context.TODO(), tt.property.ID)
+			if err != nil {
+				t.Fatalf("GetProperty() unexpected error = %v", err)
+			}
+			if got.Currency != tt.wantCurrency || got.Timezone != tt.wantTimezone {
+				t.Errorf("GetProperty() got = %+v, want currency %v and timezone %v", got, tt.wantCurrency, tt.wantTimezone)
+			}
+		})
+	}
+}
+
+func TestHandler_UpdateProperty(t *testing.T) {
+	tests := []struct {
+		name        string
+		property    *Property
+		wantPolicy  OverdraftPolicy
+		wantMinimum string
+		wantErr     error
+	}{
+		{
+			name:        "keeps the overdraft policy",
+			property:    &Property{ID: "trust", Name: "Client trust"},
+			wantPolicy:  OverdraftReject,
+			wantMinimum: "100",
+		},
+		{
+			name:        "changes the overdraft policy",
+			property:    &Property{ID: "trust", Name: "Client trust", OverdraftPolicy: OverdraftWarn, MinimumBalance: mustMoney("50")},
+			wantPolicy:  OverdraftWarn,
+			wantMinimum: "50",
+		},
+		{
+			name:        "lifts the overdraft policy",
+			property:    &Property{ID: "trust", Name: "Client trust", OverdraftPolicy: OverdraftAllow},
+			wantPolicy:  OverdraftAllow,
+			wantMinimum: "0",
+		},
+		{
+			name:     "unknown property",
+			property: &Property{ID: "nothing", Name: "Elm Street 13"},
+			wantErr:  ErrPropertyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			properties := NewMockPropertyStore(&Property{ID: "trust", Name: "Trust", Currency: "USD", Timezone: "UTC",
+				OverdraftPolicy: OverdraftReject, MinimumBalance: mustMoney("100")})
+			h := NewHandler(NewMockEventStore(nil, false), properties, nil, "USD")
+			err := h.UpdateProperty(context.TODO(), tt.property)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("UpdateProperty() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateProperty() unexpected error = %v", err)
+			}
+
+			got, err := h.GetProperty(context.TODO(), "trust")
+			if err != nil {
+				t.Fatalf("GetProperty() unexpected error = %v", err)
+			}
+			if got.Name != "Client trust" || got.OverdraftPolicy != tt.wantPolicy || got.MinimumBalance != mustMoney(tt.wantMinimum) {
+				t.Errorf("GetProperty() got = %+v, want policy %v and minimum %v", got, tt.wantPolicy, tt.wantMinimum)
+			}
+		})
+	}
+}
+
+func TestHandler_SaveEvent_Registry(t *testing.T) {
+	properties := NewMockPropertyStore(&Property{ID: "registered", Name: "Baker Street 221b", Currency: "GBP", Timezone: "UTC"})
+	h := NewHandler(NewMockEventStore(nil, false), properties, nil, "USD")
+
+	event := &Event{PropertyID: "registered", EventAmount: mustMoney("100"), Date: time.Now()}
+	if _, err := h.SaveEvent(context.TODO(), event); err != nil {
+		t.Fatalf("SaveEvent() unexpected error = %v", err)
+	}
+	if event.Currency != "GBP" {
+		t.Errorf("SaveEvent() currency got = %v, want the property currency %v", event.Currency, "GBP")
+	}
+
+	phantom := &Event{PropertyID: "registred", EventAmount: mustMoney("100"), Date: time.Now()}
+	if _, err := h.SaveEvent(context.TODO(), phantom); !errors.Is(err, ErrPropertyNotFound) {
+		t.Errorf("SaveEvent() to an unknown property error = %v, want %v", err, ErrPropertyNotFound)
+	}
+	if _, err := h.Transfer(context.TODO(), "registered", "registred", mustMoney("10"), time.Now()); !errors.Is(err, ErrPropertyNotFound) {
+		t.Errorf("Transfer() to an unknown property error = %v, want %v", err, ErrPropertyNotFound)
+	}
+
+	if err := h.DeleteProperty(context.TODO(), "registered"); !errors.Is(err, ErrPropertyHasEvents) {
+		t.Errorf("DeleteProperty() with events error = %v, want %v", err, ErrPropertyHasEvents)
+	}
+}
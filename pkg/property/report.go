@@ -6,24 +6,134 @@ import (
 	"time"
 )
 
-func (h *Handler) GetMonthlyReport(ctx context.Context, PropertyID string, month time.Month, year int, offset int, limit int) ([]*Event, float64, error) {
+// PeriodReport is the state of a property ledger over the period [From, To).
+type PeriodReport struct {
+	PropertyID string
+	From       time.Time
+	To         time.Time
+	// ExcludeTransfers leaves the legs of transfers between properties out of the events and totals.
+	ExcludeTransfers bool
+	// StartingBalance is the balance before the events of the period, and EndingBalance the balance
+	// after them.
+	StartingBalance *Balance
+	EndingBalance   *Balance
+	// Income and Expense are the sums of the positive and negative amounts of the events of the
+	// period per currency, and IncomeTotal and ExpenseTotal their totals in the base currency.
+	Income       map[string]Money
+	Expense      map[string]Money
+	IncomeTotal  Money
+	ExpenseTotal Money
+	// Net is the sum of Income and Expense per currency, and NetTotal its total in the base currency.
+	Net      map[string]Money
+	NetTotal Money
+	// EventCount is the number of events in the whole period.
+	EventCount int64
+	// Events is the requested page of the events of the period, with their running balance.
+	Events []*Event
+}
+
+// GetPeriodReport returns the report of the property over [from, to): its balance at both ends of
+// the period, the totals of the period, and a page of its events. The totals cover the whole period
+// whichever page is requested, and are computed by the store. Transfers between properties are
+// left out of the events and the totals if excludeTransfers is set, though they still count toward
+// the balances.
+func (h *Handler) GetPeriodReport(ctx context.Context, PropertyID string, from time.Time, to time.Time, excludeTransfers bool, offset int, limit int) (*PeriodReport, error) {
+	report, err := h.GetPeriodTotals(ctx, PropertyID, from, to, excludeTransfers)
+	if err != nil {
+		return nil, err
+	}
+
+	if report.Events, err = h.GetPropertyEvents(ctx, report.Filter(), Ascending, offset, limit); err != nil {
+		return nil, fmt.Errorf("get property events: %v", err)
+	}
+	return report, nil
+}
+
+// GetPeriodTotals returns the report of the property over [from, to) as GetPeriodReport does,
+// without any of its events. They can be read with EachEvent and the filter of the report.
+func (h *Handler) GetPeriodTotals(ctx context.Context, PropertyID string, from time.Time, to time.Time, excludeTransfers bool) (*PeriodReport, error) {
 	if PropertyID == "" {
-		return nil, 0, fmt.Errorf("empty property ID")
+		return nil, fmt.Errorf("empty property ID")
+	} else if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	report := &PeriodReport{
+		PropertyID:       PropertyID,
+		From:             from,
+		To:               to,
+		ExcludeTransfers: excludeTransfers,
+		Income:           make(map[string]Money),
+		Expense:          make(map[string]Money),
+		Net:              make(map[string]Money),
+	}
+	var err error
+	if report.StartingBalance, err = h.getBalanceBefore(ctx, PropertyID, from); err != nil {
+		return nil, fmt.Errorf("get balance before: %v", err)
+	}
+	if report.EndingBalance, err = h.getBalanceBefore(ctx, PropertyID, to); err != nil {
+		return nil, fmt.Errorf("get balance before: %v", err)
+	}
+
+	totals, err := h.store.GetCategoryTotals(ctx, report.Filter())
+	if err != nil {
+		return nil, fmt.Errorf("get category totals: %v", err)
+	}
+	for _, total := range totals {
+		if err := report.add(total); err != nil {
+			return nil, err
+		}
+	}
+	if report.IncomeTotal, err = h.convertTotal(ctx, report.Income, to); err != nil {
+		return nil, err
 	}
+	if report.ExpenseTotal, err = h.convertTotal(ctx, report.Expense, to); err != nil {
+		return nil, err
+	}
+	if report.NetTotal, err = h.convertTotal(ctx, report.Net, to); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
 
-	// Calculate the start and end of the month
-	startOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
-	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Nanosecond)
+// Filter matches the events of the report.
+func (r *PeriodReport) Filter() *EventFilter {
+	return &EventFilter{
+		PropertyID:       r.PropertyID,
+		AfterTime:        r.From,
+		EndTime:          r.To,
+		ExcludeTransfers: r.ExcludeTransfers,
+	}
+}
 
-	startingBalance, err := h.getBalanceForDate(ctx, PropertyID, startOfMonth)
+// add adds the totals of a category to the totals of the report.
+func (r *PeriodReport) add(total *CategoryTotal) error {
+	var err error
+	if r.Income[total.Currency], err = r.Income[total.Currency].Add(total.Income); err != nil {
+		return fmt.Errorf("sum income: %w", err)
+	}
+	if r.Expense[total.Currency], err = r.Expense[total.Currency].Add(total.Expense); err != nil {
+		return fmt.Errorf("sum expense: %w", err)
+	}
+	net, err := total.Income.Add(total.Expense)
 	if err != nil {
-		return nil, 0, fmt.Errorf("get balance for date: %v", err)
+		return fmt.Errorf("sum net: %w", err)
+	}
+	if r.Net[total.Currency], err = r.Net[total.Currency].Add(net); err != nil {
+		return fmt.Errorf("sum net: %w", err)
 	}
+	r.EventCount += total.EventCount
+	return nil
+}
 
-	events, err := h.GetPropertyEvents(ctx, PropertyID, startOfMonth, endOfMonth, Ascending, All, offset, limit)
+// GetMonthlyReport returns the report of the property over a calendar month, in timezone if it is
+// set and otherwise in the time zone of the property.
+func (h *Handler) GetMonthlyReport(ctx context.Context, PropertyID string, month time.Month, year int, timezone string, excludeTransfers bool, offset int, limit int) (*PeriodReport, error) {
+	calendar, err := h.GetCalendar(ctx, PropertyID, timezone)
 	if err != nil {
-		return nil, 0, fmt.Errorf("get property events: %v", err)
+		return nil, err
 	}
 
-	return events, startingBalance, nil
+	from, to := calendar.Month(month, year)
+	return h.GetPeriodReport(ctx, PropertyID, from, to, excludeTransfers, offset, limit)
 }
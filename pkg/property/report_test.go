@@ -0,0 +1,153 @@
+package property
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCalendar_Quarter(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation() unexpected error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		calendar *Calendar
+		quarter  int
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "first quarter",
+			calendar: UTCCalendar,
+			quarter:  1,
+			wantFrom: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "last quarter ends with the year",
+			calendar: UTCCalendar,
+			quarter:  4,
+			wantFrom: time.Date(2024, time.October, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "fiscal year starting in April",
+			calendar: &Calendar{Location: time.UTC, FiscalYearStart: time.April},
+			quarter:  4,
+			wantFrom: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "in a time zone",
+			calendar: &Calendar{Location: newYork, FiscalYearStart: time.January},
+			quarter:  1,
+			wantFrom: time.Date(2024, time.January, 1, 5, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, time.April, 1, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "no fifth quarter",
+			calendar: UTCCalendar,
+			quarter:  5,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := tt.calendar.Quarter(tt.quarter, 2024)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Quarter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("Quarter() got = [%v, %v), want [%v, %v)", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestHandler_GetMonthlyReport_Timezone(t *testing.T) {
+	// a late-evening event on March 31 in New York is already April in UTC
+	lateEvening := time.Date(2024, time.April, 1, 2, 0, 0, 0, time.UTC)
+	properties := NewMockPropertyStore(&Property{ID: "propID", Name: "Elm Street 13", Timezone: "America/New_York"})
+	store := NewMockEventStore([]*Event{
+		{ID: "1", PropertyID: "propID", EventAmount: mustMoney("100"), PostEventBalance: mustMoney("100"), Currency: "USD", Date: lateEvening, Sequence: 1},
+	}, false)
+	h := NewHandler(store, properties, nil, "USD")
+
+	tests := []struct {
+		name     string
+		month    time.Month
+		timezone string
+		want     int
+	}{
+		{
+			name:  "in the time zone of the property",
+			month: time.March,
+			want:  1,
+		},
+		{
+			name:     "in the requested time zone",
+			month:    time.April,
+			timezone: "UTC",
+			want:     1,
+		},
+		{
+			name:     "outside the month in the requested time zone",
+			month:    time.March,
+			timezone: "Australia/Sydney",
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.GetMonthlyReport(context.TODO(), "propID", tt.month, 2024, tt.timezone, false, 0, 0)
+			if err != nil {
+				t.Fatalf("GetMonthlyReport() unexpected error = %v", err)
+			}
+			if len(got.Events) != tt.want || got.EventCount != int64(tt.want) {
+				t.Errorf("GetMonthlyReport() got %d events, %d counted, want %d", len(got.Events), got.EventCount, tt.want)
+			}
+		})
+	}
+
+	if _, err := h.GetMonthlyReport(context.TODO(), "propID", time.March, 2024, "Mars/Olympus", false, 0, 0); err == nil {
+		t.Errorf("GetMonthlyReport() expected an error for an unknown time zone")
+	}
+}
+
+func TestHandler_GetPeriodReport(t *testing.T) {
+	from := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMockEventStore([]*Event{
+		{ID: "1", PropertyID: "propID", EventAmount: mustMoney("1000"), PostEventBalance: mustMoney("1000"), Currency: "USD", Date: from.Add(-time.Nanosecond), Sequence: 1},
+		{ID: "2", PropertyID: "propID", EventAmount: mustMoney("200"), PostEventBalance: mustMoney("1200"), Currency: "USD", Date: from, Sequence: 2},
+		{ID: "3", PropertyID: "propID", EventAmount: mustMoney("-50"), PostEventBalance: mustMoney("1150"), Currency: "USD", Date: from.AddDate(0, 1, 0), Sequence: 3},
+		{ID: "4", PropertyID: "propID", EventAmount: mustMoney("-30"), PostEventBalance: mustMoney("1120"), Currency: "USD", Date: to.Add(-time.Nanosecond), Sequence: 4},
+		{ID: "5", PropertyID: "propID", EventAmount: mustMoney("500"), PostEventBalance: mustMoney("1620"), Currency: "USD", Date: to, Sequence: 5},
+	}, false)
+	h := NewHandler(store, nil, nil, "USD")
+
+	got, err := h.GetPeriodReport(context.TODO(), "propID", from, to, false, 0, 2)
+	if err != nil {
+		t.Fatalf("GetPeriodReport() unexpected error = %v", err)
+	}
+	if got.StartingBalance.Total != mustMoney("1000") || got.EndingBalance.Total != mustMoney("1120") {
+		t.Errorf("GetPeriodReport() balances got = %v, %v, want %v, %v", got.StartingBalance.Total, got.EndingBalance.Total, "1000.00", "1120.00")
+	}
+	if got.IncomeTotal != mustMoney("200") || got.ExpenseTotal != mustMoney("-80") {
+		t.Errorf("GetPeriodReport() income and expense got = %v, %v, want %v, %v", got.IncomeTotal, got.ExpenseTotal, "200.00", "-80.00")
+	}
+	if got.NetTotal != mustMoney("120") || got.EventCount != 3 {
+		t.Errorf("GetPeriodReport() net and count got = %v, %v, want %v, %v", got.NetTotal, got.EventCount, "120.00", 3)
+	}
+	if len(got.Events) != 2 || got.Events[0].ID != "2" || got.Events[1].ID != "3" {
+		t.Errorf("GetPeriodReport() got events %v, want the first page of the period", got.Events)
+	}
+
+	if _, err := h.GetPeriodReport(context.TODO(), "propID", to, from, false, 0, 0); err == nil {
+		t.Errorf("GetPeriodReport() expected an error for an empty period")
+	}
+}
@@ -0,0 +1,84 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrEventNotFound is returned when an event ID does not match any event of the property.
+	ErrEventNotFound = errors.New("event not found")
+	// ErrNotReversible is returned when reversing an event that was already reversed,
+	// that is itself a reversal, or that is a transfer leg, which is undone by a transfer back.
+	ErrNotReversible = errors.New("event cannot be reversed")
+)
+
+// ReverseEvent voids an event by appending a compensating event of the opposite amount,
+// dated now, and marking the original as reversed, in a single ledger write.
+// It returns the compensating event. Reversing an income is subject to the overdraft policy of
+// the property, as an expense is.
+func (h *Handler) ReverseEvent(ctx context.Context, PropertyID string, eventID string, reason string) (*Event, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	} else if eventID == "" {
+		return nil, fmt.Errorf("empty event ID")
+	}
+
+	var compensating *Event
+	err := retryOnConflict(ctx, func() error {
+		original, err := h.getEvent(ctx, PropertyID, eventID)
+		if err != nil {
+			return err
+		}
+		if original.Reversed {
+			return fmt.Errorf("event %s is already reversed: %w", eventID, ErrNotReversible)
+		} else if original.ReversalOf != "" {
+			return fmt.Errorf("event %s is a reversal: %w", eventID, ErrNotReversible)
+		} else if original.TransferID != "" {
+			return fmt.Errorf("event %s is part of transfer %s: %w", eventID, original.TransferID, ErrNotReversible)
+		}
+
+		amount, err := original.EventAmount.Neg()
+		if err != nil {
+			return fmt.Errorf("negate amount: %w", err)
+		}
+		compensating = &Event{
+			PropertyID:     PropertyID,
+			EventAmount:    amount,
+			Currency:       original.Currency,
+			Date:           time.Now(),
+			Category:       original.Category,
+			Tags:           original.Tags,
+			ReversalOf:     original.ID,
+			ReversalReason: reason,
+		}
+		write, err := h.planInsert(ctx, compensating)
+		if err != nil {
+			return err
+		}
+		write.updated(original).Reversed = true
+
+		return h.writeLedger(ctx, PropertyID, write)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reverse event: %w", err)
+	}
+	return compensating, nil
+}
+
+// getEvent returns the event of the property with eventID, or ErrEventNotFound.
+func (h *Handler) getEvent(ctx context.Context, PropertyID string, eventID string) (*Event, error) {
+	event, exists, err := h.store.GetMostRecentEventForFilter(ctx, &EventFilter{
+		ID:         eventID,
+		PropertyID: PropertyID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get events for filter: %v", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("event %s: %w", eventID, ErrEventNotFound)
+	}
+	return event, nil
+}
@@ -0,0 +1,74 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"github.com/brianvoe/gofakeit/v7"
+	"testing"
+	"time"
+)
+
+func TestHandler_ReverseEvent(t *testing.T) {
+	propertyID := gofakeit.Address().Address
+	store := NewMockEventStore(nil, false)
+	h := NewHandler(store, nil, nil, "USD")
+
+	var saved []*Event
+	for _, amount := range []string{"1000", "-250", "40"} {
+		event := &Event{
+			PropertyID:  propertyID,
+			EventAmount: mustMoney(amount),
+			Date:        time.Now().Add(-time.Hour),
+			Category:    Maintenance,
+		}
+		if _, err := h.SaveEvent(context.TODO(), event); err != nil {
+			t.Fatalf("SaveEvent() unexpected error = %v", err)
+		}
+		saved = append(saved, event)
+	}
+
+	compensating, err := h.ReverseEvent(context.TODO(), propertyID, saved[1].ID, "duplicate invoice")
+	if err != nil {
+		t.Fatalf("ReverseEvent() unexpected error = %v", err)
+	}
+	if compensating.EventAmount != mustMoney("250") || compensating.ReversalOf != saved[1].ID ||
+		compensating.ReversalReason != "duplicate invoice" || compensating.Category != Maintenance {
+		t.Errorf("ReverseEvent() got compensating event %+v", compensating)
+	}
+	if compensating.PostEventBalance != mustMoney("1040") {
+		t.Errorf("ReverseEvent() balance got = %v, want %v", compensating.PostEventBalance, "1040.00")
+	}
+
+	original, err := h.getEvent(context.TODO(), propertyID, saved[1].ID)
+	if err != nil {
+		t.Fatalf("getEvent() unexpected error = %v", err)
+	}
+	if !original.Reversed {
+		t.Errorf("ReverseEvent() did not mark the original event as reversed")
+	}
+
+	all, err := h.GetPropertyEvents(context.TODO(), &EventFilter{PropertyID: propertyID}, Ascending, 0, 0)
+	if err != nil {
+		t.Fatalf("GetPropertyEvents() unexpected error = %v", err)
+	}
+	if len(all) != 4 {
+		t.Errorf("GetPropertyEvents() got %d events, want %d", len(all), 4)
+	}
+	remaining, err := h.GetPropertyEvents(context.TODO(), &EventFilter{PropertyID: propertyID, ExcludeReversed: true}, Ascending, 0, 0)
+	if err != nil {
+		t.Fatalf("GetPropertyEvents() unexpected error = %v", err)
+	}
+	if len(remaining) != 2 || remaining[0].ID != saved[0].ID || remaining[1].ID != saved[2].ID {
+		t.Errorf("GetPropertyEvents() excluding reversed got = %v", remaining)
+	}
+
+	if _, err := h.ReverseEvent(context.TODO(), propertyID, saved[1].ID, ""); !errors.Is(err, ErrNotReversible) {
+		t.Errorf("ReverseEvent() twice error = %v, want %v", err, ErrNotReversible)
+	}
+	if _, err := h.ReverseEvent(context.TODO(), propertyID, compensating.ID, ""); !errors.Is(err, ErrNotReversible) {
+		t.Errorf("ReverseEvent() of a reversal error = %v, want %v", err, ErrNotReversible)
+	}
+	if _, err := h.ReverseEvent(context.TODO(), propertyID, "missing", ""); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("ReverseEvent() of a missing event error = %v, want %v", err, ErrEventNotFound)
+	}
+}
@@ -0,0 +1,123 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrScheduleNotFound is returned when a schedule ID does not match any schedule of the property.
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "daily"
+	Weekly  Frequency = "weekly"
+	Monthly Frequency = "monthly"
+	Yearly  Frequency = "yearly"
+)
+
+func (f Frequency) IsValid() bool {
+	switch f {
+	case Daily, Weekly, Monthly, Yearly:
+		return true
+	}
+	return false
+}
+
+// Schedule is a recurring event, such as rent due monthly on the 1st, whose occurrences are
+// saved to the property ledger by a Scheduler once they are due.
+type Schedule struct {
+	// ID is assigned by the ScheduleStore when the schedule is created.
+	ID          string    `json:"id" bson:"_id"`
+	PropertyID  string    `json:"property_id" bson:"property_id"`
+	EventAmount Money     `json:"event_amount" bson:"event_amount"`
+	Currency    string    `json:"currency" bson:"currency"`
+	Description string    `json:"description,omitempty" bson:"description,omitempty"`
+	Category    Category  `json:"category,omitempty" bson:"category,omitempty"`
+	Tags        []string  `json:"tags,omitempty" bson:"tags,omitempty"`
+	Frequency   Frequency `json:"frequency" bson:"frequency"`
+	// Interval is the number of Frequency periods between occurrences, such as 2 for every 2 weeks.
+	Interval int `json:"interval" bson:"interval"`
+	// Start is the date of the first occurrence, which sets the day and time of the later ones.
+	// Monthly occurrences past the end of a shorter month fall on its last day.
+	Start time.Time `json:"start" bson:"start"`
+	// End, when set, is the date after which the schedule has no more occurrences.
+	End time.Time `json:"end,omitempty" bson:"end,omitempty"`
+
+	// Materialized counts the occurrences already saved to the ledger.
+	Materialized int `json:"materialized" bson:"materialized"`
+	// NextOccurrence is the date of the first occurrence not yet saved to the ledger.
+	NextOccurrence time.Time `json:"next_occurrence" bson:"next_occurrence"`
+	// Done marks a schedule that has no more occurrences.
+	Done bool `json:"done" bson:"done"`
+	// Skipped are the occurrences that were rejected when they fell due, such as by the overdraft
+	// policy of the property, and that the schedule moved past without saving.
+	Skipped []*SkippedOccurrence `json:"skipped,omitempty" bson:"skipped,omitempty"`
+}
+
+// SkippedOccurrence is an occurrence of a schedule that was rejected when it fell due.
+type SkippedOccurrence struct {
+	// Occurrence counts the occurrences of the schedule from 0.
+	Occurrence int       `json:"occurrence" bson:"occurrence"`
+	Date       time.Time `json:"date" bson:"date"`
+	Reason     string    `json:"reason" bson:"reason"`
+	SkippedAt  time.Time `json:"skipped_at" bson:"skipped_at"`
+}
+
+// occurrence returns the date of the nth occurrence of the schedule, counting from 0.
+func (s *Schedule) occurrence(n int) time.Time {
+	switch s.Frequency {
+	case Daily:
+		return s.Start.AddDate(0, 0, n*s.Interval)
+	case Weekly:
+		return s.Start.AddDate(0, 0, 7*n*s.Interval)
+	case Yearly:
+		return addMonths(s.Start, 12*n*s.Interval)
+	default:
+		return addMonths(s.Start, n*s.Interval)
+	}
+}
+
+// advance moves the schedule past its next occurrence.
+func (s *Schedule) advance() {
+	s.Materialized++
+	s.NextOccurrence = s.occurrence(s.Materialized)
+	s.Done = !s.End.IsZero() && s.NextOccurrence.After(s.End)
+}
+
+// addMonths adds months to t, keeping its day unless the target month is shorter,
+// in which case the result falls on the last day of that month.
+func addMonths(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	firstOfMonth := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	return firstOfMonth.AddDate(0, 0, min(day, lastDay)-1)
+}
+
+// occurrenceIdempotencyKey is the idempotency key of the nth occurrence of a schedule, which
+// makes saving an occurrence more than once, such as after a crash, save it only once.
+func occurrenceIdempotencyKey(scheduleID string, n int) string {
+	return fmt.Sprintf("schedule:%s:%d", scheduleID, n)
+}
+
+type ScheduleStore interface {
+	// CreateSchedule saves a new schedule, assigning it an ID.
+	CreateSchedule(ctx context.Context, schedule *Schedule) error
+	// UpdateSchedule replaces the definition of a schedule and whether it is done,
+	// leaving its other progress as is.
+	// It reports whether the schedule exists.
+	UpdateSchedule(ctx context.Context, schedule *Schedule) (bool, error)
+	// AdvanceSchedule saves the progress of a schedule, including its skipped occurrences, only
+	// if the store still records materialized occurrences for it, returning ErrConflict otherwise.
+	AdvanceSchedule(ctx context.Context, schedule *Schedule, materialized int) error
+	GetSchedule(ctx context.Context, propertyID string, scheduleID string) (*Schedule, bool, error)
+	GetSchedules(ctx context.Context, propertyID string) ([]*Schedule, error)
+	// GetDueSchedules returns the schedules of every property that are not done and
+	// have an occurrence at or before date.
+	GetDueSchedules(ctx context.Context, date time.Time) ([]*Schedule, error)
+	// DeleteSchedule reports whether the schedule existed.
+	DeleteSchedule(ctx context.Context, propertyID string, scheduleID string) (bool, error)
+}
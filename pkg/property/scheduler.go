@@ -0,0 +1,278 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock of the system time.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SchedulerConfig holds the configuration for materializing scheduled events
+type SchedulerConfig struct {
+	// Interval is how often due occurrences are looked for
+	Interval time.Duration `validate:"required"`
+}
+
+// Scheduler manages the schedules of properties, and saves their occurrences to the ledger
+// once they are due.
+// Each occurrence is saved with an idempotency key of its own, so it is saved exactly once
+// even if the scheduler stops midway, or several schedulers run at once.
+type Scheduler struct {
+	handler *Handler
+	store   ScheduleStore
+	clock   Clock
+}
+
+func NewScheduler(handler *Handler, store ScheduleStore, clock Clock) *Scheduler {
+	return &Scheduler{
+		handler: handler,
+		store:   store,
+		clock:   clock,
+	}
+}
+
+// CreateSchedule saves a new schedule of a registered property, in the property currency if
+// it has none, its first occurrence being due at schedule.Start.
+// The saved schedule, including its ID, is written back to schedule.
+func (s *Scheduler) CreateSchedule(ctx context.Context, schedule *Schedule) error {
+	property, err := s.handler.getRegisteredProperty(ctx, schedule.PropertyID)
+	if err != nil {
+		return err
+	}
+	if schedule.Currency == "" && property != nil {
+		schedule.Currency = property.Currency
+	}
+
+	if err := s.validateSchedule(schedule); err != nil {
+		return err
+	}
+	schedule.Materialized = 0
+	schedule.NextOccurrence = schedule.Start
+	schedule.Done = false
+
+	if err := s.store.CreateSchedule(ctx, schedule); err != nil {
+		return fmt.Errorf("create schedule: %v", err)
+	}
+	return nil
+}
+
+// UpdateSchedule changes the amount, currency, description, category, tags or end date of a
+// schedule, which apply to the occurrences not saved yet. The frequency, interval and start of a
+// schedule cannot be changed. The updated schedule is written back to schedule.
+func (s *Scheduler) UpdateSchedule(ctx context.Context, schedule *Schedule) error {
+	existing, err := s.GetSchedule(ctx, schedule.PropertyID, schedule.ID)
+	if err != nil {
+		return err
+	}
+	if schedule.Interval == 0 {
+		schedule.Interval = 1
+	}
+	if schedule.Frequency != existing.Frequency || schedule.Interval != existing.Interval || !schedule.Start.Equal(existing.Start) {
+		return fmt.Errorf("%w: frequency, interval and start of a schedule cannot be changed", ErrInvalid)
+	}
+	if err := s.validateSchedule(schedule); err != nil {
+		return err
+	}
+	schedule.Materialized = existing.Materialized
+	schedule.NextOccurrence = existing.NextOccurrence
+	schedule.Skipped = existing.Skipped
+	schedule.Done = !schedule.End.IsZero() && schedule.NextOccurrence.After(schedule.End)
+
+	exists, err := s.store.UpdateSchedule(ctx, schedule)
+	if err != nil {
+		return fmt.Errorf("update schedule: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("schedule %s: %w", schedule.ID, ErrScheduleNotFound)
+	}
+	return nil
+}
+
+func (s *Scheduler) GetSchedule(ctx context.Context, PropertyID string, scheduleID string) (*Schedule, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	} else if scheduleID == "" {
+		return nil, fmt.Errorf("empty schedule ID")
+	}
+
+	schedule, exists, err := s.store.GetSchedule(ctx, PropertyID, scheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("get schedule: %v", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("schedule %s: %w", scheduleID, ErrScheduleNotFound)
+	}
+	return schedule, nil
+}
+
+func (s *Scheduler) GetSchedules(ctx context.Context, PropertyID string) ([]*Schedule, error) {
+	if PropertyID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	}
+
+	schedules, err := s.store.GetSchedules(ctx, PropertyID)
+	if err != nil {
+		return nil, fmt.Errorf("get schedules: %v", err)
+	}
+	return schedules, nil
+}
+
+// DeleteSchedule stops a schedule. The occurrences it already saved stay in the ledger.
+func (s *Scheduler) DeleteSchedule(ctx context.Context, PropertyID string, scheduleID string) error {
+	if PropertyID == "" {
+		return fmt.Errorf("empty property ID")
+	} else if scheduleID == "" {
+		return fmt.Errorf("empty schedule ID")
+	}
+
+	exists, err := s.store.DeleteSchedule(ctx, PropertyID, scheduleID)
+	if err != nil {
+		return fmt.Errorf("delete schedule: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("schedule %s: %w", scheduleID, ErrScheduleNotFound)
+	}
+	return nil
+}
+
+// validateSchedule checks the definition of schedule, normalizing its currency and tags.
+func (s *Scheduler) validateSchedule(schedule *Schedule) error {
+	if schedule.PropertyID == "" {
+		return fmt.Errorf("%w: empty property ID", ErrInvalid)
+	} else if schedule.EventAmount.IsZero() {
+		return fmt.Errorf("%w: invalid amount", ErrInvalid)
+	} else if !schedule.Frequency.IsValid() {
+		return fmt.Errorf("%w: invalid frequency %q", ErrInvalid, schedule.Frequency)
+	} else if schedule.Interval < 0 {
+		return fmt.Errorf("%w: invalid interval %d", ErrInvalid, schedule.Interval)
+	} else if schedule.Start.IsZero() {
+		return fmt.Errorf("%w: invalid start", ErrInvalid)
+	} else if !schedule.End.IsZero() && schedule.End.Before(schedule.Start) {
+		return fmt.Errorf("%w: end must not be before start", ErrInvalid)
+	} else if !schedule.Category.IsValid() {
+		return fmt.Errorf("%w: invalid category %q", ErrInvalid, schedule.Category)
+	} else if schedule.Category == Transfer {
+		return fmt.Errorf("%w: category %q is reserved for transfers", ErrInvalid, Transfer)
+	}
+
+	if schedule.Interval == 0 {
+		schedule.Interval = 1
+	}
+	if schedule.Currency == "" {
+		schedule.Currency = s.handler.baseCurrency
+	} else {
+		var err error
+		if schedule.Currency, err = NormalizeCurrency(schedule.Currency); err != nil {
+			return err
+		}
+	}
+	schedule.Tags = normalizeTags(schedule.Tags)
+	return nil
+}
+
+// RunDue saves every occurrence due by now, according to the scheduler clock, of every schedule,
+// and returns how many occurrences were saved.
+// A schedule failing to save an occurrence does not stop the others.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	now := s.clock.Now()
+	due, err := s.store.GetDueSchedules(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("get due schedules: %v", err)
+	}
+
+	saved := 0
+	var errs []error
+	for _, schedule := range due {
+		n, err := s.materialize(ctx, schedule, now)
+		saved += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule %s: %w", schedule.ID, err))
+		}
+	}
+	return saved, errors.Join(errs...)
+}
+
+// materialize saves the occurrences of schedule due by now, in order, recording the progress
+// of the schedule after each one. An occurrence the ledger rejects would be rejected again on
+// every run, holding back the later ones, so it is recorded as skipped instead.
+func (s *Scheduler) materialize(ctx context.Context, schedule *Schedule, now time.Time) (int, error) {
+	saved := 0
+	for !schedule.Done && !schedule.NextOccurrence.After(now) {
+		event := &Event{
+			PropertyID:     schedule.PropertyID,
+			EventAmount:    schedule.EventAmount,
+			Currency:       schedule.Currency,
+			Date:           schedule.NextOccurrence,
+			Description:    schedule.Description,
+			Category:       schedule.Category,
+			Tags:           schedule.Tags,
+			IdempotencyKey: occurrenceIdempotencyKey(schedule.ID, schedule.Materialized),
+		}
+		skipped := false
+		// the key being reused means the occurrence was saved before the schedule was updated
+		if _, err := s.handler.SaveEvent(ctx, event); err != nil && !errors.Is(err, ErrIdempotencyKeyReused) {
+			if !isRejection(err) {
+				return saved, err
+			}
+			slog.Warn("skipped scheduled event", slog.String("schedule_id", schedule.ID),
+				slog.Int("occurrence", schedule.Materialized), slog.String("err", err.Error()))
+			schedule.Skipped = append(schedule.Skipped, &SkippedOccurrence{
+				Occurrence: schedule.Materialized,
+				Date:       schedule.NextOccurrence,
+				Reason:     err.Error(),
+				SkippedAt:  now,
+			})
+			skipped = true
+		}
+
+		materialized := schedule.Materialized
+		schedule.advance()
+		if err := s.store.AdvanceSchedule(ctx, schedule, materialized); err != nil {
+			if errors.Is(err, ErrConflict) {
+				// another scheduler is materializing the same schedule
+				return saved, nil
+			}
+			return saved, fmt.Errorf("advance schedule: %v", err)
+		}
+		if !skipped {
+			saved++
+		}
+	}
+	return saved, nil
+}
+
+// isRejection reports whether err rejects an event for what it is, so saving it again would fail
+// the same way, rather than for a failure of the store.
+func isRejection(err error) bool {
+	return errors.Is(err, ErrOverdraft) || errors.Is(err, ErrPropertyNotFound) || errors.Is(err, ErrOverflow)
+}
+
+// Run calls RunDue every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunDue(ctx); err != nil {
+			slog.Error("failed to save scheduled events", slog.String("err", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
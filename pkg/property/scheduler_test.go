@@ -0,0 +1,326 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/samber/lo"
+	"slices"
+	"testing"
+	"time"
+)
+
+type MockScheduleStore struct {
+	schedules []*Schedule
+}
+
+func (m *MockScheduleStore) CreateSchedule(ctx context.Context, schedule *Schedule) error {
+	schedule.ID = gofakeit.UUID()
+	c := *schedule
+	m.schedules = append(m.schedules, &c)
+	return nil
+}
+
+func (m *MockScheduleStore) UpdateSchedule(ctx context.Context, schedule *Schedule) (bool, error) {
+	existing, ok := m.find(schedule.PropertyID, schedule.ID)
+	if !ok {
+		return false, nil
+	}
+	c := *schedule
+	c.Materialized, c.NextOccurrence = existing.Materialized, existing.NextOccurrence
+	*existing = c
+	return true, nil
+}
+
+func (m *MockScheduleStore) AdvanceSchedule(ctx context.Context, schedule *Schedule, materialized int) error {
+	existing, ok := m.find(schedule.PropertyID, schedule.ID)
+	if !ok || existing.Materialized != materialized {
+		return ErrConflict
+	}
+	existing.Materialized, existing.NextOccurrence, existing.Done = schedule.Materialized, schedule.NextOccurrence, schedule.Done
+	existing.Skipped = slices.Clone(schedule.Skipped)
+	return nil
+}
+
+func (m *MockScheduleStore) GetSchedule(ctx context.Context, propertyID string, scheduleID string) (*Schedule, bool, error) {
+	existing, ok := m.find(propertyID, scheduleID)
+	if !ok {
+		return nil, false, nil
+	}
+	c := *existing
+	return &c, true, nil
+}
+
+func (m *MockScheduleStore) GetSchedules(ctx context.Context, propertyID string) ([]*Schedule, error) {
+	return m.filter(func(s *Schedule) bool {
+		return s.PropertyID == propertyID
+	}), nil
+}
+
+func (m *MockScheduleStore) GetDueSchedules(ctx context.Context, date time.Time) ([]*Schedule, error) {
+	return m.filter(func(s *Schedule) bool {
+		return !s.Done && !s.NextOccurrence.After(date)
+	}), nil
+}
+
+func (m *MockScheduleStore) DeleteSchedule(ctx context.Context, propertyID string, scheduleID string) (bool, error) {
+	_, i, ok := lo.FindIndexOf(m.schedules, func(s *Schedule) bool {
+		return s.PropertyID == propertyID && s.ID == scheduleID
+	})
+	if !ok {
+		return false, nil
+	}
+	m.schedules = append(m.schedules[:i], m.schedules[i+1:]...)
+	return true, nil
+}
+
+func (m *MockScheduleStore) find(propertyID string, scheduleID string) (*Schedule, bool) {
+	return lo.Find(m.schedules, func(s *Schedule) bool {
+		return s.PropertyID == propertyID && s.ID == scheduleID
+	})
+}
+
+// filter returns copies of the schedules matching predicate.
+func (m *MockScheduleStore) filter(predicate func(s *Schedule) bool) []*Schedule {
+	return lo.FilterMap(m.schedules, func(s *Schedule, _ int) (*Schedule, bool) {
+		c := *s
+		return &c, predicate(s)
+	})
+}
+
+type MockClock struct {
+	now time.Time
+}
+
+func (m *MockClock) Now() time.Time {
+	return m.now
+}
+
+func TestSchedule_occurrence(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		n        int
+		want     time.Time
+	}{
+		{
+			name:     "monthly on the 1st",
+			schedule: &Schedule{Frequency: Monthly, Interval: 1, Start: time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)},
+			n:        13,
+			want:     time.Date(2025, time.February, 1, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "monthly on the 31st falls on the last day of shorter months",
+			schedule: &Schedule{Frequency: Monthly, Interval: 1, Start: time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)},
+			n:        1,
+			want:     time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "monthly on the 31st comes back to the 31st",
+			schedule: &Schedule{Frequency: Monthly, Interval: 1, Start: time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)},
+			n:        2,
+			want:     time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "every 2 weeks",
+			schedule: &Schedule{Frequency: Weekly, Interval: 2, Start: time.Date(2024, time.January, 5, 0, 0, 0, 0, time.UTC)},
+			n:        3,
+			want:     time.Date(2024, time.February, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "yearly on a leap day",
+			schedule: &Schedule{Frequency: Yearly, Interval: 1, Start: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+			n:        1,
+			want:     time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.occurrence(tt.n); !got.Equal(tt.want) {
+				t.Errorf("occurrence() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduler_RunDue(t *testing.T) {
+	propertyID := gofakeit.Address().Address
+	events := NewMockEventStore(nil, false)
+	schedules := &MockScheduleStore{}
+	clock := &MockClock{now: time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)}
+	s := NewScheduler(NewHandler(events, nil, nil, "USD"), schedules, clock)
+
+	rent := &Schedule{
+		PropertyID:  propertyID,
+		EventAmount: mustMoney("1500"),
+		Category:    Rent,
+		Frequency:   Monthly,
+		Start:       time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		End:         time.Date(2024, time.June, 30, 0, 0, 0, 0, time.UTC),
+	}
+	if err := s.CreateSchedule(context.TODO(), rent); err != nil {
+		t.Fatalf("CreateSchedule() unexpected error = %v", err)
+	}
+
+	if saved, err := s.RunDue(context.TODO()); err != nil || saved != 1 {
+		t.Fatalf("RunDue() got = %v, %v, want %v", saved, err, 1)
+	}
+	// running again at the same time saves nothing
+	if saved, err := s.RunDue(context.TODO()); err != nil || saved != 0 {
+		t.Fatalf("RunDue() again got = %v, %v, want %v", saved, err, 0)
+	}
+
+	// after downtime, every missed occurrence up to the end date is saved
+	clock.now = time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC)
+	if saved, err := s.RunDue(context.TODO()); err != nil || saved != 5 {
+		t.Fatalf("RunDue() after downtime got = %v, %v, want %v", saved, err, 5)
+	}
+
+	ledger, err := s.handler.GetPropertyEvents(context.TODO(), &EventFilter{PropertyID: propertyID}, Ascending, 0, 0)
+	if err != nil {
+		t.Fatalf("GetPropertyEvents() unexpected error = %v", err)
+	}
+	if len(ledger) != 6 {
+		t.Fatalf("GetPropertyEvents() got %d events, want %d", len(ledger), 6)
+	}
+	if last := ledger[5]; !last.Date.Equal(time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)) ||
+		last.PostEventBalance != mustMoney("9000") || last.Category != Rent {
+		t.Errorf("GetPropertyEvents() last event got = %+v", last)
+	}
+
+	got, err := s.GetSchedule(context.TODO(), propertyID, rent.ID)
+	if err != nil {
+		t.Fatalf("GetSchedule() unexpected error = %v", err)
+	}
+	if !got.Done || got.Materialized != 6 {
+		t.Errorf("GetSchedule() got done = %v with %d occurrences, want done with %d", got.Done, got.Materialized, 6)
+	}
+}
+
+func TestScheduler_RunDue_ResumesAfterCrash(t *testing.T) {
+	propertyID := gofakeit.Address().Address
+	events := NewMockEventStore(nil, false)
+	schedules := &MockScheduleStore{}
+	clock := &MockClock{now: time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC)}
+	s := NewScheduler(NewHandler(events, nil, nil, "USD"), schedules, clock)
+
+	mortgage := &Schedule{
+		PropertyID:  propertyID,
+		EventAmount: mustMoney("-900"),
+		Frequency:   Weekly,
+		Interval:    2,
+		Start:       time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := s.CreateSchedule(context.TODO(), mortgage); err != nil {
+		t.Fatalf("CreateSchedule() unexpected error = %v", err)
+	}
+
+	// the first occurrence was saved, but the scheduler stopped before recording it
+	first := &Event{
+		PropertyID:     propertyID,
+		EventAmount:    mortgage.EventAmount,
+		Date:           mortgage.Start,
+		IdempotencyKey: occurrenceIdempotencyKey(mortgage.ID, 0),
+	}
+	if _, err := s.handler.SaveEvent(context.TODO(), first); err != nil {
+		t.Fatalf("SaveEvent() unexpected error = %v", err)
+	}
+
+	if saved, err := s.RunDue(context.TODO()); err != nil || saved != 2 {
+		t.Fatalf("RunDue() got = %v, %v, want %v", saved, err, 2)
+	}
+	if len(events.events) != 2 {
+		t.Errorf("RunDue() saved %d events, want %d", len(events.events), 2)
+	}
+}
+
+func TestScheduler_RunDue_SkipsRejected(t *testing.T) {
+	properties := NewMockPropertyStore(&Property{ID: "trust", Currency: "USD", OverdraftPolicy: OverdraftReject})
+	clock := &MockClock{now: time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)}
+	s := NewScheduler(NewHandler(NewMockEventStore(nil, false), properties, nil, "USD"), &MockScheduleStore{}, clock)
+
+	deposit := &Event{PropertyID: "trust", EventAmount: mustMoney("700"), Date: time.Date(2023, time.December, 31, 0, 0, 0, 0, time.UTC)}
+	if _, err := s.handler.SaveEvent(context.TODO(), deposit); err != nil {
+		t.Fatalf("SaveEvent() unexpected error = %v", err)
+	}
+	fee := &Schedule{
+		PropertyID:  "trust",
+		EventAmount: mustMoney("-500"),
+		Frequency:   Monthly,
+		Start:       time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := s.CreateSchedule(context.TODO(), fee); err != nil {
+		t.Fatalf("CreateSchedule() unexpected error = %v", err)
+	}
+
+	// February and March would overdraw the account, so they are skipped rather than retried
+	if saved, err := s.RunDue(context.TODO()); err != nil || saved != 1 {
+		t.Fatalf("RunDue() got = %v, %v, want %v", saved, err, 1)
+	}
+	got, err := s.GetSchedule(context.TODO(), "trust", fee.ID)
+	if err != nil {
+		t.Fatalf("GetSchedule() unexpected error = %v", err)
+	}
+	if got.Materialized != 3 || len(got.Skipped) != 2 {
+		t.Fatalf("GetSchedule() got %d occurrences with %d skipped, want 3 with 2", got.Materialized, len(got.Skipped))
+	}
+	if skipped := got.Skipped[0]; skipped.Occurrence != 1 || !skipped.Date.Equal(time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)) ||
+		skipped.Reason == "" || !skipped.SkippedAt.Equal(clock.now) {
+		t.Errorf("GetSchedule() skipped occurrence got = %+v", skipped)
+	}
+
+	// the later occurrences are saved once the account is funded again
+	topUp := &Event{PropertyID: "trust", EventAmount: mustMoney("1000"), Date: clock.now}
+	if _, err := s.handler.SaveEvent(context.TODO(), topUp); err != nil {
+		t.Fatalf("SaveEvent() unexpected error = %v", err)
+	}
+	clock.now = time.Date(2024, time.April, 2, 0, 0, 0, 0, time.UTC)
+	if saved, err := s.RunDue(context.TODO()); err != nil || saved != 1 {
+		t.Fatalf("RunDue() got = %v, %v, want %v", saved, err, 1)
+	}
+}
+
+func TestScheduler_UpdateSchedule(t *testing.T) {
+	propertyID := gofakeit.Address().Address
+	s := NewScheduler(NewHandler(NewMockEventStore(nil, false), nil, nil, "USD"), &MockScheduleStore{}, SystemClock{})
+	schedule := &Schedule{
+		PropertyID:  propertyID,
+		EventAmount: mustMoney("-30"),
+		Category:    Utilities,
+		Frequency:   Monthly,
+		Start:       time.Now().AddDate(0, 1, 0),
+	}
+	if err := s.CreateSchedule(context.TODO(), schedule); err != nil {
+		t.Fatalf("CreateSchedule() unexpected error = %v", err)
+	}
+
+	update := *schedule
+	update.EventAmount = mustMoney("-35")
+	update.End = update.Start
+	if err := s.UpdateSchedule(context.TODO(), &update); err != nil {
+		t.Fatalf("UpdateSchedule() unexpected error = %v", err)
+	}
+	got, err := s.GetSchedule(context.TODO(), propertyID, schedule.ID)
+	if err != nil {
+		t.Fatalf("GetSchedule() unexpected error = %v", err)
+	}
+	if got.EventAmount != mustMoney("-35") || got.Done {
+		t.Errorf("GetSchedule() got = %+v", got)
+	}
+
+	update.Frequency = Weekly
+	if err := s.UpdateSchedule(context.TODO(), &update); !errors.Is(err, ErrInvalid) {
+		t.Errorf("UpdateSchedule() of the frequency error = %v, want %v", err, ErrInvalid)
+	}
+	update.Frequency, update.Interval = Monthly, -1
+	if err := s.UpdateSchedule(context.TODO(), &update); !errors.Is(err, ErrInvalid) {
+		t.Errorf("UpdateSchedule() of the interval error = %v, want %v", err, ErrInvalid)
+	}
+
+	if err := s.DeleteSchedule(context.TODO(), propertyID, schedule.ID); err != nil {
+		t.Fatalf("DeleteSchedule() unexpected error = %v", err)
+	}
+	if _, err := s.GetSchedule(context.TODO(), propertyID, schedule.ID); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("GetSchedule() after delete error = %v, want %v", err, ErrScheduleNotFound)
+	}
+}
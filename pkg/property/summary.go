@@ -0,0 +1,128 @@
+package property
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// PropertyFilter selects registered properties. Its zero value selects all of them.
+type PropertyFilter struct {
+	Owner string
+	// Tags matches properties carrying every one of the tags.
+	Tags []string
+}
+
+type PropertySortField int8
+
+const (
+	SortByName PropertySortField = iota
+	SortByBalance
+)
+
+// LedgerSummary is the state of a property ledger in a single currency.
+type LedgerSummary struct {
+	PropertyID string
+	Currency   string
+	// Balance is the running balance of the most recent event.
+	Balance       Money
+	LastEventDate time.Time
+	// MonthlyNet is the sum of the amounts of the events dated in the summarized month.
+	MonthlyNet Money
+}
+
+// PropertySummary is a registered property along with the current state of its ledger.
+type PropertySummary struct {
+	Property *Property
+	Balance  *Balance
+	// LastEventDate is zero for a property without events.
+	LastEventDate time.Time
+	// MonthlyNet is the net of the events dated in the current month per currency, and
+	// MonthlyNetTotal their total in the base currency.
+	MonthlyNet      map[string]Money
+	MonthlyNetTotal Money
+}
+
+// ListProperties returns the registered properties matching filter along with their current balance,
+// last event date and net for the current month of their calendar, sorted by sortBy.
+// The ledgers of the properties sharing a month are summarized in a single store call, so there is
+// one call per time zone the properties are in. Without a property registry there are none.
+func (h *Handler) ListProperties(ctx context.Context, filter *PropertyFilter, sortBy PropertySortField, sortOrder SortOrder) ([]*PropertySummary, error) {
+	if h.properties == nil {
+		return nil, nil
+	}
+	properties, err := h.properties.GetProperties(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("get properties: %v", err)
+	}
+
+	now := time.Now().UTC()
+	byMonth := make(map[time.Time][]string)
+	for _, p := range properties {
+		calendar, err := propertyCalendar(p, "")
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", p.ID, err)
+		}
+		local := now.In(calendar.Location)
+		from, _ := calendar.Month(local.Month(), local.Year())
+		byMonth[from] = append(byMonth[from], p.ID)
+	}
+	var ledgers []*LedgerSummary
+	for from, propertyIDs := range byMonth {
+		summaries, err := h.store.GetLedgerSummaries(ctx, propertyIDs, from, from.AddDate(0, 1, 0))
+		if err != nil {
+			return nil, fmt.Errorf("get ledger summaries: %v", err)
+		}
+		ledgers = append(ledgers, summaries...)
+	}
+	byProperty := lo.GroupBy(ledgers, func(l *LedgerSummary) string {
+		return l.PropertyID
+	})
+
+	summaries := make([]*PropertySummary, 0, len(properties))
+	for _, p := range properties {
+		summary := &PropertySummary{
+			Property: p,
+			Balance: &Balance{
+				ByCurrency:   make(map[string]Money),
+				BaseCurrency: h.baseCurrency,
+				AsOf:         now,
+			},
+			MonthlyNet: make(map[string]Money),
+		}
+		for _, ledger := range byProperty[p.ID] {
+			summary.Balance.ByCurrency[ledger.Currency] = ledger.Balance
+			summary.MonthlyNet[ledger.Currency] = ledger.MonthlyNet
+			if ledger.LastEventDate.After(summary.LastEventDate) {
+				summary.LastEventDate = ledger.LastEventDate
+			}
+		}
+		if summary.Balance.Total, err = h.convertTotal(ctx, summary.Balance.ByCurrency, now); err != nil {
+			return nil, fmt.Errorf("property %s: %w", p.ID, err)
+		}
+		if summary.MonthlyNetTotal, err = h.convertTotal(ctx, summary.MonthlyNet, now); err != nil {
+			return nil, fmt.Errorf("property %s: %w", p.ID, err)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	slices.SortFunc(summaries, func(a, b *PropertySummary) int {
+		c := 0
+		if sortBy == SortByBalance {
+			c = a.Balance.Total.Cmp(b.Balance.Total)
+		}
+		if c == 0 {
+			c = cmp.Or(strings.Compare(a.Property.Name, b.Property.Name), strings.Compare(a.Property.ID, b.Property.ID))
+		}
+		if sortOrder == Descending {
+			return -c
+		}
+		return c
+	})
+	return summaries, nil
+}
@@ -0,0 +1,124 @@
+package property
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestHandler_ListProperties(t *testing.T) {
+	now := time.Now().UTC()
+	lastMonth := now.AddDate(0, -1, -1)
+	properties := NewMockPropertyStore(
+		&Property{ID: "elm", Name: "Elm Street 13", Owner: "alice", Tags: []string{"rental"}},
+		&Property{ID: "baker", Name: "Baker Street 221b", Owner: "alice", Currency: "EUR"},
+		&Property{ID: "empty", Name: "Empty Lot", Owner: "bob", Tags: []string{"rental"}},
+	)
+	events := NewMockEventStore([]*Event{
+		{ID: "1", PropertyID: "elm", EventAmount: mustMoney("1000"), PostEventBalance: mustMoney("1000"), Currency: "USD", Date: lastMonth, Sequence: 1},
+		{ID: "2", PropertyID: "elm", EventAmount: mustMoney("-300"), PostEventBalance: mustMoney("700"), Currency: "USD", Date: now, Sequence: 2},
+		{ID: "3", PropertyID: "baker", EventAmount: mustMoney("2000"), PostEventBalance: mustMoney("2000"), Currency: "EUR", Date: lastMonth, Sequence: 1},
+	}, false)
+	h := NewHandler(events, properties, MockRateProvider{"EURUSD": big.NewRat(11, 10)}, "USD")
+
+	tests := []struct {
+		name      string
+		filter    *PropertyFilter
+		sortBy    PropertySortField
+		sortOrder SortOrder
+		wantIDs   []string
+	}{
+		{
+			name:    "by name",
+			filter:  &PropertyFilter{},
+			sortBy:  SortByName,
+			wantIDs: []string{"baker", "elm", "empty"},
+		},
+		{
+			name:      "by balance descending",
+			filter:    &PropertyFilter{},
+			sortBy:    SortByBalance,
+			sortOrder: Descending,
+			wantIDs:   []string{"baker", "elm", "empty"},
+		},
+		{
+			name:    "by balance",
+			filter:  &PropertyFilter{},
+			sortBy:  SortByBalance,
+			wantIDs: []string{"empty", "elm", "baker"},
+		},
+		{
+			name:    "by owner",
+			filter:  &PropertyFilter{Owner: "bob"},
+			wantIDs: []string{"empty"},
+		},
+		{
+			name:    "by tag",
+			filter:  &PropertyFilter{Tags: []string{"rental"}},
+			wantIDs: []string{"elm", "empty"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.ListProperties(context.TODO(), tt.filter, tt.sortBy, tt.sortOrder)
+			if err != nil {
+				t.Fatalf("ListProperties() unexpected error = %v", err)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("ListProperties() got %d properties, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, summary := range got {
+				if summary.Property.ID != tt.wantIDs[i] {
+					t.Errorf("ListProperties() property %d got = %v, want %v", i, summary.Property.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+
+	summaries, err := h.ListProperties(context.TODO(), &PropertyFilter{Owner: "alice"}, SortByName, Ascending)
+	if err != nil {
+		t.Fatalf("ListProperties() unexpected error = %v", err)
+	}
+	baker, elm := summaries[0], summaries[1]
+	if baker.Balance.Total != mustMoney("2200") || !baker.MonthlyNetTotal.IsZero() {
+		t.Errorf("ListProperties() got balance %v and monthly net %v, want %v and %v", baker.Balance.Total, baker.MonthlyNetTotal, "2200.00", "0.00")
+	}
+	if elm.Balance.Total != mustMoney("700") || elm.MonthlyNetTotal != mustMoney("-300") || !elm.LastEventDate.Equal(now) {
+		t.Errorf("ListProperties() got balance %v, monthly net %v and last event %v", elm.Balance.Total, elm.MonthlyNetTotal, elm.LastEventDate)
+	}
+}
+
+func TestHandler_ListProperties_PropertyMonth(t *testing.T) {
+	location, err := time.LoadLocation("Pacific/Kiritimati")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	// the local month of the property starts on the last day of the previous month in UTC
+	local := time.Now().In(location)
+	monthStart := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, location)
+	properties := NewMockPropertyStore(
+		&Property{ID: "atoll", Name: "Atoll", Currency: "USD", Timezone: "Pacific/Kiritimati"},
+		&Property{ID: "elm", Name: "Elm Street 13", Currency: "USD", Timezone: "UTC"},
+	)
+	events := NewMockEventStore([]*Event{
+		{ID: "1", PropertyID: "atoll", EventAmount: mustMoney("100"), PostEventBalance: mustMoney("100"), Currency: "USD", Date: monthStart.Add(-time.Hour), Sequence: 1},
+		{ID: "2", PropertyID: "atoll", EventAmount: mustMoney("50"), PostEventBalance: mustMoney("150"), Currency: "USD", Date: monthStart.Add(time.Hour), Sequence: 2},
+		{ID: "3", PropertyID: "elm", EventAmount: mustMoney("70"), PostEventBalance: mustMoney("70"), Currency: "USD", Date: time.Now(), Sequence: 1},
+	}, false)
+	h := NewHandler(events, properties, nil, "USD")
+
+	summaries, err := h.ListProperties(context.TODO(), &PropertyFilter{}, SortByName, Ascending)
+	if err != nil {
+		t.Fatalf("ListProperties() unexpected error = %v", err)
+	}
+	atoll, elm := summaries[0], summaries[1]
+	if atoll.MonthlyNetTotal != mustMoney("50") || elm.MonthlyNetTotal != mustMoney("70") {
+		t.Errorf("ListProperties() got monthly nets %v and %v, want %v and %v", atoll.MonthlyNetTotal, elm.MonthlyNetTotal, "50.00", "70.00")
+	}
+
+	summaries, err = NewHandler(events, nil, nil, "USD").ListProperties(context.TODO(), &PropertyFilter{}, SortByName, Ascending)
+	if err != nil || len(summaries) != 0 {
+		t.Errorf("ListProperties() without a registry got = %v, %v, want no properties", summaries, err)
+	}
+}
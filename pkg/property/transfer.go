@@ -0,0 +1,97 @@
+package property
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// PropertyTransfer is money moved from one property to another, recorded as an expense of the first
+// and an income of the second, both in the Transfer category and sharing the transfer ID.
+type PropertyTransfer struct {
+	ID string `json:"id"`
+	// From is the leg of the property the money was moved out of.
+	From *Event `json:"from"`
+	// To is the leg of the property the money was moved into.
+	To *Event `json:"to"`
+}
+
+// Transfer moves amount, in the base currency, from one property to another at date.
+// Both legs are written in a single atomic write to the two ledgers. The outgoing leg is subject
+// to the overdraft policy of its property, as an expense saved by SaveEvent is.
+func (h *Handler) Transfer(ctx context.Context, fromID string, toID string, amount Money, date time.Time) (*PropertyTransfer, error) {
+	if fromID == "" || toID == "" {
+		return nil, fmt.Errorf("empty property ID")
+	} else if fromID == toID {
+		return nil, fmt.Errorf("cannot transfer a property to itself")
+	} else if !amount.IsPositive() {
+		return nil, fmt.Errorf("transfer amount must be positive")
+	} else if date.IsZero() {
+		return nil, fmt.Errorf("invalid date")
+	}
+
+	for _, PropertyID := range []string{fromID, toID} {
+		if _, err := h.getRegisteredProperty(ctx, PropertyID); err != nil {
+			return nil, err
+		}
+	}
+
+	outgoing, err := amount.Neg()
+	if err != nil {
+		return nil, fmt.Errorf("negate amount: %w", err)
+	}
+	transferID, err := newTransferID()
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := &PropertyTransfer{ID: transferID}
+	err = retryOnConflict(ctx, func() error {
+		transfer.From = &Event{
+			PropertyID:  fromID,
+			EventAmount: outgoing,
+			Currency:    h.baseCurrency,
+			Date:        date,
+			Category:    Transfer,
+			TransferID:  transferID,
+		}
+		transfer.To = &Event{
+			PropertyID:  toID,
+			EventAmount: amount,
+			Currency:    h.baseCurrency,
+			Date:        date,
+			Category:    Transfer,
+			TransferID:  transferID,
+		}
+
+		fromWrite, err := h.planInsert(ctx, transfer.From)
+		if err != nil {
+			return err
+		}
+		toWrite, err := h.planInsert(ctx, transfer.To)
+		if err != nil {
+			return err
+		}
+		return h.writeLedgers(ctx, map[string]*LedgerWrite{
+			fromID: fromWrite,
+			toID:   toWrite,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("transfer: %w", err)
+	}
+	h.notify(ctx, transfer.From, transfer.To)
+	return transfer, nil
+}
+
+// newTransferID returns a random ID for a transfer, which unlike event IDs is not assigned
+// by the EventStore, as it is shared by events of two ledgers.
+func newTransferID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate transfer ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,206 @@
+package property
+
+import (
+	"context"
+	"errors"
+	"github.com/brianvoe/gofakeit/v7"
+	"testing"
+	"time"
+)
+
+func TestHandler_Transfer(t *testing.T) {
+	fromID := gofakeit.Address().Address
+	toID := gofakeit.Address().Address
+	date := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		fromID   string
+		toID     string
+		amount   Money
+		store    *MockEventStore
+		wantFrom Money
+		wantTo   Money
+		wantErr  bool
+	}{
+		{
+			name:   "both legs",
+			fromID: fromID,
+			toID:   toID,
+			amount: mustMoney("250"),
+			store: NewMockEventStore([]*Event{
+				{ID: "1", PropertyID: fromID, EventAmount: mustMoney("1000"), PostEventBalance: mustMoney("1000"), Currency: "USD", Date: date.Add(-time.Hour), Sequence: 1},
+			}, false),
+			wantFrom: mustMoney("750"),
+			wantTo:   mustMoney("250"),
+		},
+		{
+			name:   "retries on conflict",
+			fromID: fromID,
+			toID:   toID,
+			amount: mustMoney("10"),
+			store: func() *MockEventStore {
+				store := NewMockEventStore(nil, false)
+				store.conflicts = 2
+				return store
+			}(),
+			wantFrom: mustMoney("-10"),
+			wantTo:   mustMoney("10"),
+		},
+		{
+			name:    "to itself",
+			fromID:  fromID,
+			toID:    fromID,
+			amount:  mustMoney("10"),
+			store:   NewMockEventStore(nil, false),
+			wantErr: true,
+		},
+		{
+			name:    "negative amount",
+			fromID:  fromID,
+			toID:    toID,
+			amount:  mustMoney("-10"),
+			store:   NewMockEventStore(nil, false),
+			wantErr: true,
+		},
+		{
+			name:    "err from state writes neither leg",
+			fromID:  fromID,
+			toID:    toID,
+			amount:  mustMoney("10"),
+			store:   NewMockEventStore(nil, true),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(tt.store, nil, nil, "USD")
+			got, err := h.Transfer(context.TODO(), tt.fromID, tt.toID, tt.amount, date)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Transfer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if tt.store.err {
+					tt.store.err = false
+					events, _ := tt.store.GetEventsForFilter(context.TODO(), &EventFilter{Category: Transfer}, Ascending, 0, 0)
+					if len(events) != 0 {
+						t.Errorf("Transfer() failed but saved %d legs", len(events))
+					}
+				}
+				return
+			}
+
+			if got.From.PostEventBalance != tt.wantFrom || got.To.PostEventBalance != tt.wantTo {
+				t.Errorf("Transfer() balances got = %v, %v, want %v, %v", got.From.PostEventBalance, got.To.PostEventBalance, tt.wantFrom, tt.wantTo)
+			}
+			if got.From.TransferID != got.ID || got.To.TransferID != got.ID || got.From.Category != Transfer {
+				t.Errorf("Transfer() legs are not linked: %+v, %+v", got.From, got.To)
+			}
+
+			all, err := h.GetPropertyEvents(context.TODO(), &EventFilter{PropertyID: tt.fromID, AmountType: Expense}, Ascending, 0, 0)
+			if err != nil {
+				t.Fatalf("GetPropertyEvents() unexpected error = %v", err)
+			}
+			withoutTransfers, err := h.GetPropertyEvents(context.TODO(), &EventFilter{PropertyID: tt.fromID, AmountType: Expense, ExcludeTransfers: true}, Ascending, 0, 0)
+			if err != nil {
+				t.Fatalf("GetPropertyEvents() unexpected error = %v", err)
+			}
+			if len(all) != 1 || len(withoutTransfers) != 0 {
+				t.Errorf("GetPropertyEvents() got %d expenses, %d without transfers, want 1, 0", len(all), len(withoutTransfers))
+			}
+		})
+	}
+}
+
+func TestHandler_Transfer_LegsAreFinal(t *testing.T) {
+	fromID := gofakeit.Address().Address
+	h := NewHandler(NewMockEventStore(nil, false), nil, nil, "USD")
+	transfer, err := h.Transfer(context.TODO(), fromID, gofakeit.Address().Address, mustMoney("10"), time.Now())
+	if err != nil {
+		t.Fatalf("Transfer() unexpected error = %v", err)
+	}
+
+	if _, err := h.ReverseEvent(context.TODO(), fromID, transfer.From.ID, ""); !errors.Is(err, ErrNotReversible) {
+		t.Errorf("ReverseEvent() of a transfer leg error = %v, want %v", err, ErrNotReversible)
+	}
+	amount := mustMoney("-20")
+	if _, err := h.AmendEvent(context.TODO(), fromID, transfer.From.ID, &Amendment{EventAmount: &amount, AmendedBy: "alice"}); !errors.Is(err, ErrNotAmendable) {
+		t.Errorf("AmendEvent() of a transfer leg error = %v, want %v", err, ErrNotAmendable)
+	}
+	if _, err := h.SaveEvent(context.TODO(), &Event{PropertyID: fromID, EventAmount: amount, Date: time.Now(), Category: Transfer}); err == nil {
+		t.Errorf("SaveEvent() in the transfer category expected an error")
+	}
+}
+
+type recordingObserver struct {
+	events []*Event
+}
+
+func (o *recordingObserver) EventSaved(ctx context.Context, event *Event) {
+	o.events = append(o.events, event)
+}
+
+func TestHandler_Transfer_NotifiesBothLegs(t *testing.T) {
+	properties := NewMockPropertyStore(&Property{ID: "trust", Currency: "USD"}, &Property{ID: "elm", Currency: "USD"})
+	h := NewHandler(NewMockEventStore(nil, false), properties, nil, "USD")
+	observer := &recordingObserver{}
+	h.Observe(observer)
+
+	transfer, err := h.Transfer(context.TODO(), "trust", "elm", mustMoney("100"), time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Transfer() unexpected error = %v", err)
+	}
+	if len(observer.events) != 2 || observer.events[0] != transfer.From || observer.events[1] != transfer.To {
+		t.Errorf("Transfer() notified %v, want both legs", observer.events)
+	}
+}
+
+func TestHandler_Transfer_Overdraft(t *testing.T) {
+	date := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		policy        OverdraftPolicy
+		amount        string
+		wantOverdrawn bool
+		wantProjected string
+	}{
+		{name: "within the balance", policy: OverdraftReject, amount: "100"},
+		{name: "allowed", policy: OverdraftAllow, amount: "250"},
+		{name: "warned", policy: OverdraftWarn, amount: "250", wantOverdrawn: true},
+		{name: "rejected", policy: OverdraftReject, amount: "250", wantProjected: "-150"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			properties := NewMockPropertyStore(
+				&Property{ID: "trust", Currency: "USD", OverdraftPolicy: tt.policy},
+				&Property{ID: "elm", Currency: "USD"},
+			)
+			events := NewMockEventStore([]*Event{
+				{ID: "1", PropertyID: "trust", EventAmount: mustMoney("100"), PostEventBalance: mustMoney("100"), Currency: "USD", Date: date, Sequence: 1},
+			}, false)
+			h := NewHandler(events, properties, nil, "USD")
+			observer := &recordingObserver{}
+			h.Observe(observer)
+
+			transfer, err := h.Transfer(context.TODO(), "trust", "elm", mustMoney(tt.amount), date.AddDate(0, 0, 1))
+			if tt.wantProjected != "" {
+				var overdraft *OverdraftError
+				if !errors.As(err, &overdraft) || overdraft.ProjectedBalance != mustMoney(tt.wantProjected) {
+					t.Fatalf("Transfer() error = %v, want an overdraft error projecting %s", err, tt.wantProjected)
+				}
+				legs, _ := events.GetEventsForFilter(context.TODO(), &EventFilter{Category: Transfer}, Ascending, 0, 0)
+				if len(legs) != 0 || len(observer.events) != 0 {
+					t.Errorf("Transfer() was rejected but saved %d legs and notified %d", len(legs), len(observer.events))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Transfer() unexpected error = %v", err)
+			}
+			if transfer.From.Overdrawn != tt.wantOverdrawn {
+				t.Errorf("Transfer() overdrawn got = %v, want %v", transfer.From.Overdrawn, tt.wantOverdrawn)
+			}
+		})
+	}
+}
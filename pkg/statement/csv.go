@@ -0,0 +1,174 @@
+package statement
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+// CSVMapping names the columns of a CSV statement, as they appear in its header row.
+// Amounts are either signed in Amount, or split between Debit and Credit.
+type CSVMapping struct {
+	Date        string
+	Amount      string
+	Debit       string
+	Credit      string
+	Description string
+	// Reference, Category and Currency are optional.
+	Reference string
+	Category  string
+	Currency  string
+	// Delimiter separates the columns, and is a comma by default.
+	Delimiter rune
+}
+
+// csvColumns holds the position of each mapped column, -1 for an unmapped one.
+type csvColumns struct {
+	date, amount, debit, credit, description, reference, category, currency int
+}
+
+func parseCSV(r io.Reader, options Options) ([]*property.ImportLine, error) {
+	mapping := options.CSV
+	if mapping.Date == "" {
+		return nil, fmt.Errorf("no date column")
+	} else if mapping.Amount == "" && mapping.Debit == "" && mapping.Credit == "" {
+		return nil, fmt.Errorf("no amount, debit or credit column")
+	}
+	dateFormat := options.DateFormat
+	if dateFormat == "" {
+		dateFormat = time.DateOnly
+	}
+
+	reader := csv.NewReader(r)
+	if mapping.Delimiter != 0 {
+		reader.Comma = mapping.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	columns, err := mapping.columns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []*property.ImportLine
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line := &property.ImportLine{Row: row}
+		lines = append(lines, line)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("read row %d: %w", row, err)
+			}
+			line.Err = err
+			continue
+		}
+		line.Err = columns.read(record, line, dateFormat, options)
+	}
+	return lines, nil
+}
+
+// columns finds the mapped columns in header, ignoring case.
+func (m CSVMapping) columns(header []string) (*csvColumns, error) {
+	index := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")), name) {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("no column %q in header", name)
+	}
+
+	columns := &csvColumns{}
+	var err error
+	for _, c := range []struct {
+		name  string
+		index *int
+	}{
+		{m.Date, &columns.date},
+		{m.Amount, &columns.amount},
+		{m.Debit, &columns.debit},
+		{m.Credit, &columns.credit},
+		{m.Description, &columns.description},
+		{m.Reference, &columns.reference},
+		{m.Category, &columns.category},
+		{m.Currency, &columns.currency},
+	} {
+		if *c.index, err = index(c.name); err != nil {
+			return nil, err
+		}
+	}
+	return columns, nil
+}
+
+// read fills line from the fields of record.
+func (c *csvColumns) read(record []string, line *property.ImportLine, dateFormat string, options Options) error {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var err error
+	if line.Date, err = time.ParseInLocation(dateFormat, field(c.date), options.Location); err != nil {
+		return fmt.Errorf("invalid date %q", field(c.date))
+	}
+
+	if c.amount >= 0 {
+		if line.Amount, err = parseAmount(field(c.amount)); err != nil {
+			return err
+		}
+	} else {
+		// debits may be written as positive or negative amounts, and are expenses either way
+		var debit, credit property.Money
+		if s := field(c.debit); s != "" {
+			if debit, err = parseAmount(s); err != nil {
+				return err
+			}
+			if debit.IsPositive() {
+				if debit, err = debit.Neg(); err != nil {
+					return err
+				}
+			}
+		}
+		if s := field(c.credit); s != "" {
+			if credit, err = parseAmount(s); err != nil {
+				return err
+			}
+		}
+		if line.Amount, err = credit.Add(debit); err != nil {
+			return err
+		}
+	}
+
+	line.Description = field(c.description)
+	line.Reference = field(c.reference)
+	line.Category = property.Category(strings.ToLower(field(c.category)))
+	if !line.Category.IsValid() {
+		return fmt.Errorf("invalid category %q", line.Category)
+	}
+	line.Currency = options.Currency
+	if currency := field(c.currency); currency != "" {
+		if line.Currency, err = property.NormalizeCurrency(currency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+// ofxTransactionStart splits an OFX statement into its STMTTRN aggregates. Closing tags are
+// optional in the SGML of OFX 1.x, so a transaction ends at the next one or at the end of its list.
+var ofxTransactionStart = regexp.MustCompile(`(?i)<STMTTRN>`)
+var ofxTransactionEnd = regexp.MustCompile(`(?i)</STMTTRN>|</BANKTRANLIST>`)
+
+// ofxDefaultCurrency matches the currency of the amounts of an OFX statement.
+var ofxDefaultCurrency = regexp.MustCompile(`(?i)<CURDEF>\s*([A-Za-z]{3})`)
+
+// ofxElement matches an element and its value, up to the next tag or the end of the line.
+var ofxElement = regexp.MustCompile(`(?i)<([A-Z0-9.]+)>([^<\r\n]*)`)
+
+// ofxDate matches the date and time of an OFX datetime, and its time zone offset in hours.
+var ofxDate = regexp.MustCompile(`^(\d{8})(\d{6})?(?:\.\d+)?(?:\[([+-]?\d+(?:\.\d+)?)(?::[^\]]*)?\])?$`)
+
+// parseOFX reads the transactions of an OFX 1.x (SGML) or 2.x (XML) bank statement.
+// The FITID of each transaction is its reference.
+func parseOFX(r io.Reader, options Options) ([]*property.ImportLine, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read statement: %w", err)
+	}
+	body := string(data)
+	if !strings.Contains(strings.ToUpper(body), "<OFX>") {
+		return nil, fmt.Errorf("not an OFX statement")
+	}
+
+	currency := options.Currency
+	if m := ofxDefaultCurrency.FindStringSubmatch(body); m != nil {
+		currency = strings.ToUpper(m[1])
+	}
+
+	var lines []*property.ImportLine
+	for i, transaction := range ofxTransactionStart.Split(body, -1)[1:] {
+		if end := ofxTransactionEnd.FindStringIndex(transaction); end != nil {
+			transaction = transaction[:end[0]]
+		}
+		line := &property.ImportLine{Row: i + 1, Currency: currency}
+		lines = append(lines, line)
+
+		elements := make(map[string]string)
+		for _, e := range ofxElement.FindAllStringSubmatch(transaction, -1) {
+			elements[strings.ToUpper(e[1])] = strings.TrimSpace(unescapeOFX(e[2]))
+		}
+
+		if line.Date, err = parseOFXDate(elements["DTPOSTED"], options.Location); err != nil {
+			line.Err = err
+			continue
+		}
+		if line.Amount, err = parseAmount(elements["TRNAMT"]); err != nil {
+			line.Err = err
+			continue
+		}
+		line.Reference = elements["FITID"]
+		line.Description = elements["NAME"]
+		if memo := elements["MEMO"]; memo != "" {
+			if line.Description == "" {
+				line.Description = memo
+			} else if memo != line.Description {
+				line.Description += " - " + memo
+			}
+		}
+		// the amount of a transaction with a CURRENCY aggregate is in its currency, not the statement's
+		if _, foreign := elements["CURRENCY"]; foreign && elements["CURSYM"] != "" {
+			line.Currency = strings.ToUpper(elements["CURSYM"])
+		}
+	}
+	return lines, nil
+}
+
+// parseOFXDate parses an OFX datetime such as "20240305", "20240305120000" or
+// "20240305120000.000[-5:EST]", in location when it has no time zone.
+func parseOFXDate(s string, location *time.Location) (time.Time, error) {
+	m := ofxDate.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	value, layout := m[1], "20060102"
+	if m[2] != "" {
+		value, layout = value+m[2], "20060102150405"
+	}
+	if m[3] != "" {
+		hours, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", s)
+		}
+		location = time.FixedZone("", int(hours*3600))
+	}
+	date, err := time.ParseInLocation(layout, value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return date, nil
+}
+
+func unescapeOFX(s string) string {
+	return strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'").Replace(s)
+}
@@ -0,0 +1,102 @@
+package statement
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+// qifDateFormats are the layouts QIF dates are tried against when no DateFormat is given,
+// Quicken writing years after 1999 with an apostrophe.
+var qifDateFormats = []string{"1/2/2006", "1/2'06", "1/2/06", time.DateOnly}
+
+// parseQIF reads the transactions of a QIF bank or cash account. Each transaction is a run of
+// lines, the first character of which names the field, up to a line holding "^".
+// The check number of a transaction is its reference, but not the ATM, DEP, EFT or XFER Quicken
+// writes in the same field, which many transactions share.
+func parseQIF(r io.Reader, options Options) ([]*property.ImportLine, error) {
+	formats := qifDateFormats
+	if options.DateFormat != "" {
+		formats = []string{options.DateFormat}
+	}
+
+	var lines []*property.ImportLine
+	var fields map[byte]string
+	// the header and option lines starting with "!" are not transactions
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "!") {
+			continue
+		}
+		if text[0] != '^' {
+			if fields == nil {
+				fields = make(map[byte]string)
+			}
+			// splits hold fields of their own, only the first of each is kept
+			if _, ok := fields[text[0]]; !ok {
+				fields[text[0]] = strings.TrimSpace(text[1:])
+			}
+			continue
+		}
+
+		lines = append(lines, qifLine(len(lines)+1, fields, formats, options))
+		fields = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read statement: %w", err)
+	}
+	// the last transaction may lack its "^"
+	if fields != nil {
+		lines = append(lines, qifLine(len(lines)+1, fields, formats, options))
+	}
+	return lines, nil
+}
+
+func qifLine(row int, fields map[byte]string, formats []string, options Options) *property.ImportLine {
+	line := &property.ImportLine{
+		Row:         row,
+		Currency:    options.Currency,
+		Description: fields['P'],
+	}
+	if isCheckNumber(fields['N']) {
+		line.Reference = fields['N']
+	}
+	if memo := fields['M']; memo != "" {
+		if line.Description == "" {
+			line.Description = memo
+		} else {
+			line.Description += " - " + memo
+		}
+	}
+
+	date := strings.ReplaceAll(fields['D'], " ", "")
+	var err error
+	for _, format := range formats {
+		if line.Date, err = time.ParseInLocation(format, date, options.Location); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		line.Err = fmt.Errorf("invalid date %q", fields['D'])
+		return line
+	}
+
+	amount, ok := fields['T']
+	if !ok {
+		amount = fields['U']
+	}
+	if line.Amount, err = parseAmount(amount); err != nil {
+		line.Err = err
+	}
+	return line
+}
+
+// isCheckNumber reports whether the QIF number field n holds a check number.
+func isCheckNumber(n string) bool {
+	return n != "" && strings.Trim(n, "0123456789") == ""
+}
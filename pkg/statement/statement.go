@@ -0,0 +1,76 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+type Format string
+
+const (
+	CSV Format = "csv"
+	OFX Format = "ofx"
+	QIF Format = "qif"
+)
+
+// Options describe how to read a statement.
+type Options struct {
+	Format Format
+	// CSV maps the columns of a CSV statement.
+	CSV CSVMapping
+	// DateFormat is the Go layout of the dates of CSV and QIF statements, "2006-01-02" and
+	// "01/02/2006" by default.
+	DateFormat string
+	// Location is the time zone of dates without one, UTC by default.
+	Location *time.Location
+	// Currency is the ISO-4217 code of lines without one, left empty for the property currency.
+	Currency string
+}
+
+// Parse reads the lines of a statement. A line that cannot be read is returned with its error,
+// and Parse only fails when the statement as a whole cannot be read.
+func Parse(r io.Reader, options Options) ([]*property.ImportLine, error) {
+	if options.Location == nil {
+		options.Location = time.UTC
+	}
+	if options.Currency != "" {
+		var err error
+		if options.Currency, err = property.NormalizeCurrency(options.Currency); err != nil {
+			return nil, err
+		}
+	}
+
+	switch options.Format {
+	case CSV:
+		return parseCSV(r, options)
+	case OFX:
+		return parseOFX(r, options)
+	case QIF:
+		return parseQIF(r, options)
+	}
+	return nil, fmt.Errorf("unknown statement format %q", options.Format)
+}
+
+// parseAmount parses amounts as banks write them, with thousands separators, a leading plus
+// sign or parentheses for negative amounts.
+func parseAmount(s string) (property.Money, error) {
+	str := strings.TrimSpace(s)
+	negative := strings.HasPrefix(str, "(") && strings.HasSuffix(str, ")")
+	if negative {
+		str = str[1 : len(str)-1]
+	}
+	str = strings.NewReplacer(",", "", " ", "", "\u00a0", "").Replace(str)
+
+	amount, err := property.ParseMoney(str)
+	if err != nil {
+		return property.Money{}, err
+	}
+	if negative {
+		return amount.Neg()
+	}
+	return amount, nil
+}
@@ -0,0 +1,181 @@
+package statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chn555/property-service/pkg/property"
+)
+
+type wantLine struct {
+	date        time.Time
+	amount      string
+	currency    string
+	description string
+	reference   string
+	err         bool
+}
+
+func checkLines(t *testing.T, got []*property.ImportLine, want []wantLine) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Parse() got %d lines, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		line := got[i]
+		if line.Row != i+1 {
+			t.Errorf("Parse() line %d row got = %d", i, line.Row)
+		}
+		if (line.Err != nil) != w.err {
+			t.Errorf("Parse() line %d error = %v, wantErr %v", i, line.Err, w.err)
+			continue
+		}
+		if w.err {
+			continue
+		}
+		amount, _ := property.ParseMoney(w.amount)
+		if !line.Date.Equal(w.date) || line.Amount != amount || line.Currency != w.currency ||
+			line.Description != w.description || line.Reference != w.reference {
+			t.Errorf("Parse() line %d got = %+v, want %+v", i, line, w)
+		}
+	}
+}
+
+func TestParse_CSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		options Options
+		want    []wantLine
+		wantErr bool
+	}{
+		{
+			name: "signed amounts",
+			input: "Date,Amount,Description,Id\n" +
+				"2024-03-05,\"1,500.00\",Rent March,A1\n" +
+				"2024-03-07,(120.50),Plumber,A2\n" +
+				"2024-03-32,10,Bad date,A3\n" +
+				"2024-03-08,ten,Bad amount,A4\n",
+			options: Options{Format: CSV, CSV: CSVMapping{Date: "date", Amount: "amount", Description: "description", Reference: "id"}},
+			want: []wantLine{
+				{date: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC), amount: "1500", description: "Rent March", reference: "A1"},
+				{date: time.Date(2024, time.March, 7, 0, 0, 0, 0, time.UTC), amount: "-120.5", description: "Plumber", reference: "A2"},
+				{err: true},
+				{err: true},
+			},
+		},
+		{
+			name:  "debit and credit columns",
+			input: "Booked;Debit;Credit;Text;Currency\n05/03/2024;;1500;Rent;eur\n07/03/2024;120.50;;Plumber;eur\n",
+			options: Options{Format: CSV, DateFormat: "02/01/2006", Currency: "USD",
+				CSV: CSVMapping{Date: "Booked", Debit: "Debit", Credit: "Credit", Description: "Text", Currency: "Currency", Delimiter: ';'}},
+			want: []wantLine{
+				{date: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC), amount: "1500", currency: "EUR", description: "Rent"},
+				{date: time.Date(2024, time.March, 7, 0, 0, 0, 0, time.UTC), amount: "-120.5", currency: "EUR", description: "Plumber"},
+			},
+		},
+		{
+			name:    "missing mapped column",
+			input:   "Date,Amount\n2024-03-05,10\n",
+			options: Options{Format: CSV, CSV: CSVMapping{Date: "Date", Amount: "Value"}},
+			wantErr: true,
+		},
+		{
+			name:    "no amount column mapped",
+			input:   "Date,Amount\n2024-03-05,10\n",
+			options: Options{Format: CSV, CSV: CSVMapping{Date: "Date"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input), tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				checkLines(t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_OFX(t *testing.T) {
+	sgml := `OFXHEADER:100
+DATA:OFXSGML
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>USD
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240305
+<TRNAMT>1500.00
+<FITID>2024030501
+<NAME>Rent March
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240307120000.000[-5:EST]
+<TRNAMT>-120.50
+<FITID>2024030701
+<NAME>Plumber &amp; Sons
+<MEMO>Leak
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>yesterday
+<TRNAMT>-1
+<FITID>2024030801
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>`
+	xml := `<?xml version="1.0"?><?OFX OFXHEADER="200"?>
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>EUR</CURDEF><BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20240310</DTPOSTED><TRNAMT>-99.99</TRNAMT><FITID>X1</FITID><NAME>Insurance</NAME>
+<CURRENCY><CURRATE>1.1</CURRATE><CURSYM>USD</CURSYM></CURRENCY></STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+
+	got, err := Parse(strings.NewReader(sgml), Options{Format: OFX})
+	if err != nil {
+		t.Fatalf("Parse() unexpected error = %v", err)
+	}
+	checkLines(t, got, []wantLine{
+		{date: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC), amount: "1500", currency: "USD", description: "Rent March", reference: "2024030501"},
+		{date: time.Date(2024, time.March, 7, 17, 0, 0, 0, time.UTC), amount: "-120.5", currency: "USD", description: "Plumber & Sons - Leak", reference: "2024030701"},
+		{err: true},
+	})
+
+	got, err = Parse(strings.NewReader(xml), Options{Format: OFX})
+	if err != nil {
+		t.Fatalf("Parse() unexpected error = %v", err)
+	}
+	checkLines(t, got, []wantLine{
+		{date: time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC), amount: "-99.99", currency: "USD", description: "Insurance", reference: "X1"},
+	})
+
+	if _, err := Parse(strings.NewReader("Date,Amount\n"), Options{Format: OFX}); err == nil {
+		t.Errorf("Parse() expected an error for a statement that is not OFX")
+	}
+}
+
+func TestParse_QIF(t *testing.T) {
+	input := "!Type:Bank\n" +
+		"D03/05/2024\nT1,500.00\nPRent March\n^\n" +
+		"D3/ 7'24\nT-120.50\nPPlumber\nMLeak\nN1001\n^\n" +
+		"D13/45/2024\nT-1\n^\n" +
+		"D03/08/2024\nT-40.00\nPCash\nNATM\n^\n" +
+		"D03/09/2024\nU-20.00\nPBank fee\n"
+
+	got, err := Parse(strings.NewReader(input), Options{Format: QIF, Currency: "usd"})
+	if err != nil {
+		t.Fatalf("Parse() unexpected error = %v", err)
+	}
+	checkLines(t, got, []wantLine{
+		{date: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC), amount: "1500", currency: "USD", description: "Rent March"},
+		{date: time.Date(2024, time.March, 7, 0, 0, 0, 0, time.UTC), amount: "-120.5", currency: "USD", description: "Plumber - Leak", reference: "1001"},
+		{err: true},
+		{date: time.Date(2024, time.March, 8, 0, 0, 0, 0, time.UTC), amount: "-40", currency: "USD", description: "Cash"},
+		{date: time.Date(2024, time.March, 9, 0, 0, 0, 0, time.UTC), amount: "-20", currency: "USD", description: "Bank fee"},
+	})
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 of the payload, keyed with the webhook secret,
+	// formatted as "sha256=" followed by its hex encoding.
+	SignatureHeader = "X-Signature-256"
+	signaturePrefix = "sha256="
+)
+
+// Config holds the configuration for posting to webhooks
+type Config struct {
+	// Timeout bounds each post to a webhook
+	Timeout time.Duration `validate:"required"`
+}
+
+// Sender is a property.WebhookSender posting JSON payloads over HTTP.
+// Any status other than 2xx is an error.
+type Sender struct {
+	client *http.Client
+}
+
+func NewSender(config Config) *Sender {
+	return &Sender{
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+func (s *Sender) Send(ctx context.Context, url string, secret string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(secret, payload))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("post: %w", err)
+	}
+	defer res.Body.Close()
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// Sign returns the value of SignatureHeader for payload.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature, the value of SignatureHeader, is that of payload, for
+// webhooks to check a payload was sent by the service.
+func Verify(secret string, payload []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, payload)), []byte(signature))
+}
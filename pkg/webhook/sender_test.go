@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSender_Send(t *testing.T) {
+	const secret = "0123456789abcdef"
+	payload := []byte(`{"delivery_id":"1","kind":"negative_balance"}`)
+
+	tests := []struct {
+		name     string
+		status   int
+		wantCode int
+		wantErr  bool
+	}{
+		{name: "accepted", status: http.StatusOK, wantCode: http.StatusOK},
+		{name: "accepted without content", status: http.StatusNoContent, wantCode: http.StatusNoContent},
+		{name: "rejected", status: http.StatusBadRequest, wantCode: http.StatusBadRequest, wantErr: true},
+		{name: "unavailable", status: http.StatusServiceUnavailable, wantCode: http.StatusServiceUnavailable, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verified bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				verified = string(body) == string(payload) && Verify(secret, body, r.Header.Get(SignatureHeader))
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			code, err := NewSender(Config{Timeout: time.Second}).Send(context.TODO(), server.URL, secret, payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if code != tt.wantCode {
+				t.Errorf("Send() got status %d, want %d", code, tt.wantCode)
+			}
+			if !verified {
+				t.Errorf("Send() payload not received with a valid signature")
+			}
+		})
+	}
+
+	if _, err := NewSender(Config{Timeout: time.Second}).Send(context.TODO(), "http://127.0.0.1:0", secret, payload); err == nil {
+		t.Errorf("Send() expected an error for an unreachable webhook")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	payload := []byte(`{"delivery_id":"1"}`)
+	signature := Sign("0123456789abcdef", payload)
+
+	if !Verify("0123456789abcdef", payload, signature) {
+		t.Errorf("Verify() rejected a valid signature")
+	}
+	if Verify("fedcba9876543210", payload, signature) {
+		t.Errorf("Verify() accepted a signature made with another secret")
+	}
+	if Verify("0123456789abcdef", []byte(`{"delivery_id":"2"}`), signature) {
+		t.Errorf("Verify() accepted a signature of another payload")
+	}
+}